@@ -0,0 +1,223 @@
+package i18n_gen
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+type (
+	// Report is the structured summary of a sync.
+	Report = RunReport
+
+	// Option sets a sync setting, options mirror flags of the sync command.
+	Option func()
+
+	// syncAbort carries an upload or download error out of Sync under the fail policy.
+	syncAbort struct {
+		err error
+	}
+)
+
+// syncCtx is the context of a Sync call, nil when running from the command line.
+var syncCtx context.Context
+
+// Sync runs the sync command programmatically with cfg in place of -config file,
+// settings not given by options have defaults of the sync command flags.
+// Cancellation of ctx stops the sync like -deadline does: requests in flight are canceled and state is saved.
+// Sync is not safe for concurrent use, local i/o errors stop the sync and are returned.
+// Sync routes the standard logger through masking of tokens and credentials.
+func Sync(ctx context.Context, cfg Config, opts ...Option) (rep Report, err error) {
+	syncCommand.flagSet().Parse(nil)
+	sanitizeLog()
+	defer func() { err = sanitizeError(err) }()
+	// there is nobody to confirm the plan
+	autoApprove = true
+	for _, opt := range opts {
+		opt()
+	}
+	if err := checkCommonFlags(); err != nil {
+		return Report{}, err
+	}
+	if err := cfg.validate(); err != nil {
+		return Report{}, err
+	}
+	config = cfg
+	syncCtx = ctx
+	defer func() { syncCtx = nil }()
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		abort, ok := r.(syncAbort)
+		if !ok {
+			panic(r)
+		}
+		if stateStore != nil {
+			stateStore.Close()
+		}
+		rep, err = report, abort.err
+	}()
+
+	if err := syncLocales(); err != nil {
+		return report, err
+	}
+	if len(report.Errors) > 0 {
+		return report, fmt.Errorf("Sync finished with %d errors", len(report.Errors))
+	}
+	return report, nil
+}
+
+// fail stops the run on an error of the fail policy.
+func fail(err error) {
+	if syncCtx != nil {
+		report.AddError(err)
+		panic(syncAbort{err})
+	}
+	log.Fatal(err)
+}
+
+// requestContext is the context of http requests of the run, they are canceled with the context of Sync.
+func requestContext() context.Context {
+	if syncCtx != nil {
+		return syncCtx
+	}
+	return context.Background()
+}
+
+func isCanceled() bool {
+	return syncCtx != nil && syncCtx.Err() != nil
+}
+
+func WithToken(token string) Option {
+	return func() { phraseappToken = token }
+}
+
+// WithPath sets the path to sources, -path.
+func WithPath(path string) Option {
+	return func() { basepath = path }
+}
+
+// WithProject adds phraseapp id of a project, -project_id.
+func WithProject(name, id string) Option {
+	return func() { phraseappProjects[name] = id }
+}
+
+// WithDefaultProject sets the project extracted keys are uploaded to, -project.
+func WithDefaultProject(name string) Option {
+	return func() { defaultProject = name }
+}
+
+func WithLocale(locale string) Option {
+	return func() { defaultLocale = locale }
+}
+
+func WithPerPage(n int) Option {
+	return func() { perPage = n }
+}
+
+func WithProd() Option {
+	return func() { prodDownload = true }
+}
+
+// WithErrorPolicy sets -on-error and -retries.
+func WithErrorPolicy(policy string, retries int) Option {
+	return func() { onError, errorRetries = policy, retries }
+}
+
+func WithDeadline(d time.Duration) Option {
+	return func() { syncTimeout = d }
+}
+
+// WithOnly limits the sync to directories relative to the path, -only.
+func WithOnly(dirs ...string) Option {
+	return func() { onlyDirs = strings.Join(dirs, ",") }
+}
+
+// WithState sets the run info store location, -state.
+func WithState(location string) Option {
+	return func() { stateLocation = location }
+}
+
+// WithStateKeyring reads the state encryption key from the OS keyring when I18N_GEN_STATE_KEY is not set, -state-keyring.
+func WithStateKeyring() Option {
+	return func() { useStateKeyring = true }
+}
+
+// WithQuarantine replaces failing translations with source text instead of failing the sync, -quarantine.
+func WithQuarantine() Option {
+	return func() { quarantineFailing = true }
+}
+
+// WithUnseenDays reports keys missing in sources for days, -unseen-days.
+func WithUnseenDays(days int) Option {
+	return func() { unseenDays = days }
+}
+
+// WithIgnoreSchedule downloads all locales regardless of schedule of the config, -ignore-schedule.
+func WithIgnoreSchedule() Option {
+	return func() { ignoreSchedule = true }
+}
+
+// WithChangelog appends per-locale changelogs of downloads to dir, -changelog.
+func WithChangelog(dir string) Option {
+	return func() { changelogDir = dir }
+}
+
+// WithStrictExtract fails the sync on extraction problems, -strict-extract.
+func WithStrictExtract() Option {
+	return func() { strictExtract = true }
+}
+
+// WithSplitUploads uploads keys of every service folder separately, -split-uploads.
+func WithSplitUploads() Option {
+	return func() { splitUploads = true }
+}
+
+// WithBootstrap downloads locales in batches of size with pauses, -bootstrap.
+func WithBootstrap(batch int, pause time.Duration) Option {
+	return func() { bootstrapMode, bootstrapBatch, bootstrapPause = true, batch, pause }
+}
+
+// WithHashIds uploads keys by hashed ids and writes their constants to the go file, -hash-ids.
+func WithHashIds(path, pkg string, length int) Option {
+	return func() { hashIdsPath, hashIdsPackage, hashIdsLength = path, pkg, length }
+}
+
+// WithMigrateMovedKeys adds translations of keys moved from other projects, -migrate-moved-keys.
+func WithMigrateMovedKeys() Option {
+	return func() { migrateMoved = true }
+}
+
+// WithSuggestTranslations offers translations of similar keys for new keys, -suggest-translations.
+func WithSuggestTranslations(mode string, similarity float64) Option {
+	return func() { suggestTranslations, suggestSimilarity = mode, similarity }
+}
+
+// WithBundles keeps versioned bundles of downloaded locales in a folder or s3://bucket/prefix, -bundles.
+func WithBundles(location string) Option {
+	return func() { bundleLocation = location }
+}
+
+// WithStartJitter waits a random duration up to jitter before syncing, -start-jitter.
+func WithStartJitter(jitter time.Duration) Option {
+	return func() { startJitter = jitter }
+}
+
+// WithCoordination shares a sync between runners through redis, -coordinate and -coordinate-max-age.
+// Runners which don't sync restore the bundle of the one which did, it needs WithBundles.
+func WithCoordination(url string, maxAge time.Duration) Option {
+	return func() { coordinateUrl, coordinateMaxAge = url, maxAge }
+}
+
+// WithUpdateTranslations sets whether uploads replace existing translations by project, * for others, -update-translations.
+func WithUpdateTranslations(projects map[string]bool) Option {
+	return func() { updateTranslationProjects = projectSwitches(projects) }
+}
+
+func WithVerbose() Option {
+	return func() { verbose = true }
+}
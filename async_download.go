@@ -0,0 +1,64 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	// ASYNC_POLL_DELAY is the pause between polls of an export without Retry-After, and the shortest one.
+	ASYNC_POLL_DELAY = 2 * time.Second
+	// ASYNC_POLL_MAX_DELAY caps Retry-After, so a far date doesn't stall the run.
+	ASYNC_POLL_MAX_DELAY = 30 * time.Second
+)
+
+// exportTimeout limits waiting for a locale export phraseapp builds asynchronously, answering 202 meanwhile.
+var exportTimeout time.Duration
+
+// pollExport does the request while phraseapp answers 202 Accepted, waiting as Retry-After asks.
+// newRequest is called for every poll, as request bodies are consumed.
+func pollExport(ctx PhraseappContexter, client *http.Client, newRequest func() (*http.Request, error), project, lang string) (*http.Response, error) {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err != nil || resp.StatusCode != http.StatusAccepted {
+			return resp, err
+		}
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+		delay := retryAfter(resp.Header.Get("Retry-After"), time.Now())
+		if ctx.Expired() || time.Since(start)+delay > exportTimeout {
+			return nil, fmt.Errorf("Export of locale %s of project %s is not ready after %s", lang, project, time.Since(start).Round(time.Second))
+		}
+		// printed right away, buffered locale logs would only show up once the download is done
+		log.Printf("Export of locale %s of project %s is being prepared, poll %d in %s\n", lang, project, attempt+1, delay)
+		time.Sleep(delay)
+		ctx.OnApiCall(project, 0, 0)
+	}
+}
+
+// retryAfter reads Retry-After given in seconds or as http date.
+func retryAfter(header string, now time.Time) time.Duration {
+	delay := ASYNC_POLL_DELAY
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		delay = time.Duration(seconds) * time.Second
+	} else if at, err := http.ParseTime(header); err == nil {
+		delay = at.Sub(now)
+	}
+	if delay < ASYNC_POLL_DELAY {
+		return ASYNC_POLL_DELAY
+	}
+	if delay > ASYNC_POLL_MAX_DELAY {
+		return ASYNC_POLL_MAX_DELAY
+	}
+	return delay
+}
@@ -0,0 +1,92 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/user"
+	"strings"
+	"time"
+)
+
+const (
+	AUDIT_UPLOAD        = "upload"
+	AUDIT_LOCALE_CREATE = "locale_create"
+	AUDIT_KEY_TAG       = "key_tag"
+	AUDIT_TRANSLATION   = "translation"
+)
+
+// AuditRecord describes a single mutation performed against phraseapp.
+type AuditRecord struct {
+	Time    time.Time `json:"time"`
+	User    string    `json:"user"`
+	Host    string    `json:"host"`
+	Action  string    `json:"action"`
+	Project string    `json:"project"`
+	Locale  string    `json:"locale,omitempty"`
+	Details string    `json:"details,omitempty"`
+}
+
+// auditTarget is a file to append json lines to or an http(s) endpoint to post records to.
+var auditTarget string
+
+func audit(action, projectName, localeName, details string) {
+	if auditTarget == "" {
+		return
+	}
+	record := AuditRecord{
+		Time:    time.Now().UTC(),
+		User:    auditUser(),
+		Action:  action,
+		Project: projectName,
+		Locale:  localeName,
+		Details: details,
+	}
+	record.Host, _ = os.Hostname()
+
+	encoded, err := json.Marshal(&record)
+	if err != nil {
+		log.Println("WARNING! Unable to encode audit record", err)
+		return
+	}
+	if strings.HasPrefix(auditTarget, "http://") || strings.HasPrefix(auditTarget, "https://") {
+		err = postAuditRecord(encoded)
+	} else {
+		err = appendAuditRecord(encoded)
+	}
+	if err != nil {
+		log.Println("WARNING! Unable to write audit record", action, projectName, localeName, err)
+	}
+}
+
+func appendAuditRecord(encoded []byte) error {
+	file, err := os.OpenFile(auditTarget, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(append(encoded, '\n'))
+	return err
+}
+
+func postAuditRecord(encoded []byte) error {
+	resp, err := http.Post(auditTarget, "application/json", bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Audit endpoint responded %s", resp.Status)
+	}
+	return nil
+}
+
+func auditUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
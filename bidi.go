@@ -0,0 +1,155 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	QA_RULE_BIDI = "bidi"
+
+	// PSEUDO_RTL_LOCALE is the locale name of the generated RTL pseudo-locale.
+	PSEUDO_RTL_LOCALE = "ar-XB"
+
+	RLO = '\u202e'
+	PDF = '\u202c'
+)
+
+var (
+	rtlLanguages = map[string]bool{"ar": true, "he": true, "iw": true, "fa": true, "ur": true, "ps": true, "yi": true}
+
+	// bidiOpeners are embeddings, overrides and isolates with their closing characters.
+	bidiOpeners = map[rune]rune{
+		'\u202a': PDF, '\u202b': PDF, '\u202d': PDF, RLO: PDF,
+		'\u2066': '\u2069', '\u2067': '\u2069', '\u2068': '\u2069',
+	}
+
+	pseudoRtl bool
+)
+
+func isRtlLocale(locale string) bool {
+	language, _ := splitLocale(locale)
+	return rtlLanguages[language]
+}
+
+// checkBidi reports unbalanced bidi control characters, placeholders of source missing in translations
+// of RTL locales and printf verbs out of source order.
+func checkBidi(locale, source, text string, placeholders *regexp.Regexp) string {
+	if !isRtlLocale(locale) {
+		return ""
+	}
+	if err := checkBidiControls(text); err != nil {
+		return err.Error()
+	}
+	for _, p := range placeholders.FindAllString(source, -1) {
+		if !strings.Contains(text, p) {
+			return fmt.Sprintf("placeholder %s is missing", p)
+		}
+	}
+	// named and indexed placeholders may be moved by translators, printf verbs are filled in order
+	expected := positionalVerbs(placeholders.FindAllString(source, -1))
+	actual := positionalVerbs(placeholders.FindAllString(text, -1))
+	for i := range expected {
+		if i < len(actual) && actual[i] != expected[i] {
+			return fmt.Sprintf("placeholder %s is reordered, %s is expected", actual[i], expected[i])
+		}
+	}
+	return ""
+}
+
+// positionalVerbs returns printf verbs without explicit argument index, like %s but not %[1]s.
+func positionalVerbs(placeholders []string) []string {
+	verbs := []string{}
+	for _, p := range placeholders {
+		if strings.HasPrefix(p, "%") && !strings.Contains(p, "[") {
+			verbs = append(verbs, p)
+		}
+	}
+	return verbs
+}
+
+// checkBidiControls checks that bidi embeddings, overrides and isolates are closed in order.
+func checkBidiControls(text string) error {
+	stack := []rune{}
+	for _, r := range text {
+		if closing, ok := bidiOpeners[r]; ok {
+			stack = append(stack, closing)
+			continue
+		}
+		if r != PDF && r != '\u2069' {
+			continue
+		}
+		if len(stack) == 0 || stack[len(stack)-1] != r {
+			return fmt.Errorf("unexpected bidi control %U", r)
+		}
+		stack = stack[:len(stack)-1]
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("%d bidi controls are not closed", len(stack))
+	}
+	return nil
+}
+
+// pseudoRtlText renders text right to left with bidi overrides, placeholders are kept intact.
+func pseudoRtlText(text string, placeholders *regexp.Regexp) string {
+	b := strings.Builder{}
+	last := 0
+	for _, loc := range placeholders.FindAllStringIndex(text, -1) {
+		writePseudoRtl(&b, text[last:loc[0]])
+		b.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	writePseudoRtl(&b, text[last:])
+	return b.String()
+}
+
+func writePseudoRtl(b *strings.Builder, s string) {
+	if s == "" {
+		return
+	}
+	b.WriteRune(RLO)
+	b.WriteString(s)
+	b.WriteRune(PDF)
+}
+
+// writePseudoRtlLocale generates PSEUDO_RTL_LOCALE of the project from its downloaded source locale.
+func writePseudoRtlLocale(projectName string) {
+	source := config.Qa.sourceLocale()
+	data, err := ioutil.ReadFile(getLocalizationFileName(projectName, source))
+	if err != nil {
+		log.Println("WARNING! Unable to generate RTL pseudo-locale without source locale", projectName, source, err)
+		return
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		log.Println("WARNING! Unable to parse source locale", projectName, source, err)
+		return
+	}
+	placeholders := projectPlaceholders(projectName, translations)
+	for _, t := range translations {
+		if t.IsPlural() {
+			for form, text := range t.Plural {
+				t.Plural[form] = pseudoRtlText(text, placeholders)
+			}
+		} else {
+			t.Text = pseudoRtlText(t.Text, placeholders)
+		}
+	}
+	encoded, err := json.MarshalIndent(translations, "", "  ")
+	if err != nil {
+		fail(fmt.Errorf("Unable to encode RTL pseudo-locale, %v", err))
+	}
+	path := getLocalizationFileName(projectName, PSEUDO_RTL_LOCALE)
+	if err := mkdirOutput(filepath.Dir(path)); err != nil {
+		fail(fmt.Errorf("Unable to create folder for project %s, %v", projectName, err))
+	}
+	if err := writeOutputFile(path, encoded); err != nil {
+		fail(fmt.Errorf("Unable to write RTL pseudo-locale %s, %v", path, err))
+	}
+	log.Println("RTL pseudo-locale was generated", projectName, PSEUDO_RTL_LOCALE)
+}
@@ -0,0 +1,43 @@
+package i18n_gen
+
+import (
+	"log"
+	"time"
+)
+
+var (
+	// bootstrapMode spreads downloads of a first run over time, so it doesn't trip rate limits,
+	// and lets an interrupted first run be resumed.
+	bootstrapMode  bool
+	bootstrapBatch int
+	bootstrapPause time.Duration
+	bootstrapCount int
+)
+
+// isBootstrapped reports whether the locale file was downloaded by a previous run and is unchanged since.
+func isBootstrapped(projectName, localeName string) bool {
+	sha := runInfo.CheckSumList.GetSha256(projectName, localeName)
+	return sha != INVALID_SHA256 && sha == getFileSha256(projectName, localeName)
+}
+
+// paceBootstrap saves progress and pauses before every batch of downloads but the first one.
+func paceBootstrap() {
+	if !bootstrapMode {
+		return
+	}
+	if bootstrapCount > 0 && bootstrapCount%bootstrapBatch == 0 {
+		saveRunInfo()
+		log.Printf("Bootstrap downloaded %d locales, progress is saved, pausing for %s\n", bootstrapCount, bootstrapPause)
+		pause := time.NewTimer(bootstrapPause)
+		defer pause.Stop()
+		var done <-chan struct{}
+		if syncCtx != nil {
+			done = syncCtx.Done()
+		}
+		select {
+		case <-pause.C:
+		case <-done:
+		}
+	}
+	bootstrapCount++
+}
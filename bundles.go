@@ -0,0 +1,283 @@
+package i18n_gen
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// BUNDLE_VERSION_FILE in the data folder holds the version of the bundle it belongs to.
+	BUNDLE_VERSION_FILE = "BUNDLE_VERSION"
+	BUNDLE_VERSION_SIZE = 12
+)
+
+type bundleStorage interface {
+	Put(version string, data []byte) error
+	Get(version string) ([]byte, error)
+}
+
+var (
+	// bundleLocation is a folder or s3://bucket/prefix keeping bundles of every sync, bundles are not kept if empty.
+	bundleLocation string
+	pinVersion     string
+)
+
+var downloadCommand = &command{
+	name:        "download",
+	description: "restore locales of a bundle version recorded by sync: download -pin <version>",
+	setFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&basepath, "path", "junolab.net", "path to micro-services")
+		fs.StringVar(&bundleLocation, "bundles", "", "folder or s3://bucket/prefix bundles are kept in")
+		fs.StringVar(&pinVersion, "pin", "", "bundle version to restore")
+		fs.BoolVar(&useStateKeyring, "state-keyring", false, "read bundle encryption key from OS keyring when "+STATE_KEY_ENV+" is not set")
+	},
+	run: runDownload,
+}
+
+func openBundleStorage(location string) (bundleStorage, error) {
+	u, err := url.Parse(location)
+	if err == nil && u.Scheme == "s3" {
+		return &s3BundleStorage{bucket: u.Host, prefix: strings.Trim(u.Path, "/")}, nil
+	}
+	return dirBundleStorage(location), nil
+}
+
+type dirBundleStorage string
+
+func (d dirBundleStorage) Put(version string, data []byte) error {
+	if err := mkdirOutput(string(d)); err != nil {
+		return err
+	}
+	return writeOutputFile(filepath.Join(string(d), version+".tar.gz"), data)
+}
+
+func (d dirBundleStorage) Get(version string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(string(d), version+".tar.gz"))
+}
+
+type s3BundleStorage struct {
+	bucket, prefix string
+}
+
+func (s *s3BundleStorage) object(version string) (*s3Store, error) {
+	return newS3Store(s.bucket, strings.TrimPrefix(s.prefix+"/"+version+".tar.gz", "/"))
+}
+
+func (s *s3BundleStorage) Put(version string, data []byte) error {
+	store, err := s.object(version)
+	if err != nil {
+		return err
+	}
+	return store.Save(data)
+}
+
+func (s *s3BundleStorage) Get(version string) ([]byte, error) {
+	store, err := s.object(version)
+	if err != nil {
+		return nil, err
+	}
+	data, err := store.Load()
+	if err == nil && data == nil {
+		return nil, fmt.Errorf("Bundle %s is not found", version)
+	}
+	return data, err
+}
+
+// bundleFolders are data folders, relative to -path, making up a bundle.
+// bundleFolders are live output folders, merged locales and regional files of project folders are bundled too.
+func bundleFolders() []string {
+	folders := []string{}
+	for _, f := range liveFolders() {
+		folders = append(folders, f[0])
+	}
+	return folders
+}
+
+// bundleVersion hashes paths and contents of all files of existing folders of base but the version file.
+func bundleVersion(base string, folders []string) (string, error) {
+	h := sha256.New()
+	for _, folder := range folders {
+		root := filepath.Join(base, folder)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() || info.Name() == BUNDLE_VERSION_FILE {
+				return err
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(h, "%s\n%d\n", filepath.ToSlash(rel), len(data))
+			h.Write(data)
+			return nil
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:BUNDLE_VERSION_SIZE], nil
+}
+
+// saveBundle records the version of downloaded data, a content hash of bundled folders, and keeps the bundle.
+func saveBundle() {
+	version, err := bundleVersion(basepath, bundleFolders())
+	if err != nil {
+		log.Println("WARNING! Unable to version bundle", err)
+		return
+	}
+	err = writeOutputFile(filepath.Join(getLocalizationFolderName(), BUNDLE_VERSION_FILE), []byte(version+"\n"))
+	if err != nil {
+		log.Println("WARNING! Unable to write bundle version", err)
+		return
+	}
+	report.Bundle = version
+
+	storage, err := openBundleStorage(bundleLocation)
+	if err != nil {
+		log.Println("WARNING! Unable to keep bundle", version, err)
+		return
+	}
+	data, err := archiveBundle(basepath, bundleFolders())
+	if err == nil {
+		data, err = sealState(data)
+	}
+	if err == nil {
+		err = storage.Put(version, data)
+	}
+	if err != nil {
+		log.Println("WARNING! Unable to keep bundle", version, err)
+		return
+	}
+	log.Println("Bundle", version, "was kept in", bundleLocation)
+}
+
+// archiveBundle packs existing folders of base into tar.gz.
+func archiveBundle(base string, folders []string) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+	for _, folder := range folders {
+		root := filepath.Join(base, folder)
+		if _, err := os.Stat(root); os.IsNotExist(err) {
+			continue
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			header := &tar.Header{Name: filepath.ToSlash(rel), Mode: int64(outputFileMode()), Size: int64(len(data)), ModTime: info.ModTime()}
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			_, err = tw.Write(data)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func runDownload(fs *flag.FlagSet) {
+	if pinVersion == "" {
+		log.Fatalln("Please, specify bundle version with -pin")
+	}
+	if bundleLocation == "" {
+		log.Fatalln("Please, specify -bundles location")
+	}
+	if err := initStateKey(); err != nil {
+		log.Fatalln(err)
+	}
+	storage, err := openBundleStorage(bundleLocation)
+	if err != nil {
+		log.Fatalln("Unable to open bundles", err)
+	}
+	if err := restoreBundle(storage, pinVersion); err != nil {
+		log.Fatalln(err)
+	}
+	log.Println("Bundle", pinVersion, "was restored to", basepath)
+}
+
+// restoreBundle replaces data folders of -path with the bundle version.
+func restoreBundle(storage bundleStorage, version string) error {
+	data, err := storage.Get(version)
+	if err != nil {
+		return fmt.Errorf("Unable to get bundle %s, %v", version, err)
+	}
+	if data, err = openState(data); err != nil {
+		return fmt.Errorf("Unable to open bundle %s, %v", version, err)
+	}
+	for _, folder := range bundleFolders() {
+		if err := os.RemoveAll(filepath.Join(basepath, folder)); err != nil {
+			return fmt.Errorf("Unable to clean %s, %v", folder, err)
+		}
+	}
+	if err := extractBundle(basepath, data); err != nil {
+		return fmt.Errorf("Unable to extract bundle %s, %v", version, err)
+	}
+	return nil
+}
+
+func extractBundle(base string, data []byte) error {
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		path := filepath.Join(base, filepath.FromSlash(header.Name))
+		if rel, err := filepath.Rel(base, path); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("Bundle entry %s is outside of the path", header.Name)
+		}
+		if err := mkdirOutput(filepath.Dir(path)); err != nil {
+			return err
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return err
+		}
+		if err := writeOutputFile(path, content); err != nil {
+			return err
+		}
+	}
+}
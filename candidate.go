@@ -0,0 +1,184 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	CANDIDATE_SUFFIX = "_candidate"
+	// CANDIDATE_STATUS_FILE records validation result of the candidate download.
+	CANDIDATE_STATUS_FILE = "CANDIDATE_STATUS.json"
+)
+
+type CandidateStatus struct {
+	Clean  bool     `json:"clean"`
+	Errors []string `json:"errors"`
+	Issues int      `json:"issues"`
+}
+
+var (
+	candidateMode    bool
+	promoteWhenClean bool
+	forcePromote     bool
+)
+
+var promoteCommand = &command{
+	name:        "promote",
+	description: "move locales downloaded by sync -candidate to the live folders",
+	setFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&basepath, "path", "junolab.net", "path to micro-services")
+		fs.BoolVar(&forcePromote, "force", false, "promote candidate which failed validation")
+		fs.StringVar(&bundleLocation, "bundles", "", "folder or s3://bucket/prefix to keep the promoted bundle in")
+		fs.BoolVar(&useStateKeyring, "state-keyring", false, "read bundle encryption key from OS keyring when "+STATE_KEY_ENV+" is not set")
+	},
+	run: runPromote,
+}
+
+// liveFolders are folders, relative to -path, services read, candidates have CANDIDATE_SUFFIX before MERGED_SUFFIX.
+func liveFolders() [][2]string {
+	folders := [][2]string{}
+	for _, base := range []string{LOCALIZED_DATA_FOLDER, PROD_DATA_FOLDER} {
+		folders = append(folders,
+			[2]string{base, base + CANDIDATE_SUFFIX},
+			[2]string{base + MERGED_SUFFIX, base + CANDIDATE_SUFFIX + MERGED_SUFFIX})
+	}
+	return folders
+}
+
+// liveFileName returns the live file of a candidate file in -candidate mode, path itself otherwise.
+func liveFileName(path string) string {
+	if !candidateMode {
+		return path
+	}
+	for _, f := range liveFolders() {
+		candidate := filepath.Join(basepath, f[1]) + string(filepath.Separator)
+		if strings.HasPrefix(path, candidate) {
+			return filepath.Join(basepath, f[0], path[len(candidate):])
+		}
+	}
+	return path
+}
+
+// prepareCandidate starts the candidate from live data, so a partial run keeps other projects.
+func prepareCandidate() {
+	for _, f := range liveFolders() {
+		candidate := filepath.Join(basepath, f[1])
+		if err := os.RemoveAll(candidate); err != nil {
+			fail(fmt.Errorf("Unable to clean candidate folder %s, %v", candidate, err))
+		}
+		if !isPartialSync() {
+			continue
+		}
+		if err := copyDir(filepath.Join(basepath, f[0]), candidate); err != nil && !os.IsNotExist(err) {
+			fail(fmt.Errorf("Unable to copy live folder to candidate %s, %v", candidate, err))
+		}
+	}
+}
+
+// finishCandidate records validation result of the run and promotes a clean candidate if asked to.
+func finishCandidate() {
+	status := &CandidateStatus{Clean: len(report.Errors) == 0, Errors: report.Errors, Issues: len(report.Issues)}
+	if err := writeJsonFile(filepath.Join(getLocalizationFolderName(), CANDIDATE_STATUS_FILE), status); err != nil {
+		log.Println("WARNING! Unable to write candidate status", err)
+	}
+	if !promoteWhenClean {
+		log.Println("Candidate locales are ready for review, run i18n_gen promote to make them live")
+		return
+	}
+	if !status.Clean {
+		log.Println("WARNING! Candidate locales are not promoted, the run has errors")
+		return
+	}
+	if err := promoteCandidate(); err != nil {
+		log.Fatalln("Unable to promote candidate", err)
+	}
+	if codegenPath != "" {
+		generateCode(defaultProject)
+	}
+	if bundleLocation != "" {
+		saveBundle()
+	}
+}
+
+func runPromote(fs *flag.FlagSet) {
+	if err := initStateKey(); err != nil {
+		log.Fatalln(err)
+	}
+	candidateMode = true
+	data, err := ioutil.ReadFile(filepath.Join(getLocalizationFolderName(), CANDIDATE_STATUS_FILE))
+	if err != nil {
+		log.Fatalln("There is no candidate to promote", err)
+	}
+	status := &CandidateStatus{}
+	if err := json.Unmarshal(data, status); err != nil {
+		log.Fatalln("Unable to parse candidate status", err)
+	}
+	if !status.Clean && !forcePromote {
+		for _, e := range status.Errors {
+			log.Println("  ", e)
+		}
+		log.Fatalln("Candidate failed validation, use -force to promote it anyway")
+	}
+	if err := promoteCandidate(); err != nil {
+		log.Fatalln("Unable to promote candidate", err)
+	}
+	if bundleLocation != "" {
+		saveBundle()
+	}
+}
+
+// promoteCandidate replaces live folders with candidate ones, live folders without a candidate are kept.
+func promoteCandidate() error {
+	candidateMode = true
+	os.Remove(filepath.Join(getLocalizationFolderName(), CANDIDATE_STATUS_FILE))
+	for _, f := range liveFolders() {
+		live, candidate := filepath.Join(basepath, f[0]), filepath.Join(basepath, f[1])
+		if _, err := os.Stat(candidate); os.IsNotExist(err) {
+			continue
+		}
+		old := live + ".old"
+		os.RemoveAll(old)
+		if err := os.Rename(live, old); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if err := os.Rename(candidate, live); err != nil {
+			os.Rename(old, live)
+			return err
+		}
+		os.RemoveAll(old)
+	}
+	candidateMode = false
+	log.Println("Candidate locales were promoted")
+	return nil
+}
+
+func copyDir(src, dst string) error {
+	if _, err := os.Stat(src); err != nil {
+		return err
+	}
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		if info.IsDir() {
+			return mkdirOutput(target)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return writeOutputFile(target, data)
+	})
+}
@@ -0,0 +1,112 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// changelogDir keeps per-locale changelogs of translations replaced by downloads, nothing is recorded if empty.
+	changelogDir string
+	// previousLocales are contents of live locale files by path before the run replaced them.
+	previousLocales map[string][]byte
+)
+
+// snapshotLocales reads locale files of dir before they are replaced by downloads.
+func snapshotLocales(dir string) {
+	previousLocales = map[string][]byte{}
+	if changelogDir == "" {
+		return
+	}
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || !strings.HasSuffix(path, ".json") {
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			previousLocales[path] = data
+		}
+		return err
+	})
+	if err != nil && !os.IsNotExist(err) {
+		log.Println("WARNING! Unable to read locales for changelog", err)
+	}
+}
+
+// recordChanges appends added, changed and removed translations of a replaced locale file to its changelog.
+func recordChanges(projectName, localeName string, translations []*Translation) {
+	data, ok := previousLocales[liveFileName(getLocalizationFileName(projectName, localeName))]
+	if !ok {
+		return
+	}
+	previous, err := ParseLocaleFile(data)
+	if err != nil {
+		return
+	}
+	old := map[string]string{}
+	for _, t := range previous {
+		old[t.ID] = translationValue(t)
+	}
+	lines := []string{}
+	added, changed, removed := 0, 0, 0
+	for _, t := range translations {
+		value := translationValue(t)
+		prev, ok := old[t.ID]
+		delete(old, t.ID)
+		switch {
+		case !ok:
+			added++
+			lines = append(lines, fmt.Sprintf("+ %q: %s", t.ID, value))
+		case prev != value:
+			changed++
+			lines = append(lines, fmt.Sprintf("~ %q: %s → %s", t.ID, prev, value))
+		}
+	}
+	for id, prev := range old {
+		// legacy ids are written along with renamed keys
+		if _, ok := keyMapping[id]; ok {
+			continue
+		}
+		removed++
+		lines = append(lines, fmt.Sprintf("- %q: %s", id, prev))
+	}
+	if len(lines) == 0 {
+		return
+	}
+	sort.SliceStable(lines, func(i, j int) bool { return lines[i][2:] < lines[j][2:] })
+	report.AddChange(fmt.Sprintf("%s:%s %d added, %d changed, %d removed", projectName, localeName, added, changed, removed))
+
+	entry := bytes.NewBuffer(nil)
+	fmt.Fprintf(entry, "## %s\n\n", time.Now().UTC().Format(time.RFC3339))
+	for _, line := range lines {
+		fmt.Fprintln(entry, line)
+	}
+	fmt.Fprintln(entry)
+	path := filepath.Join(changelogDir, projectName, runtimeLocale(localeName)+".md")
+	existing, _ := ioutil.ReadFile(path)
+	if err := mkdirOutput(filepath.Dir(path)); err != nil {
+		log.Println("WARNING! Unable to write changelog", path, err)
+		return
+	}
+	if err := writeOutputFile(path, append(existing, entry.Bytes()...)); err != nil {
+		log.Println("WARNING! Unable to write changelog", path, err)
+	}
+}
+
+// translationValue is json of the text or plural forms of a translation.
+func translationValue(t *Translation) string {
+	var value interface{} = t.Text
+	if t.IsPlural() {
+		value = t.Plural
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
@@ -0,0 +1,11 @@
+package main
+
+import (
+	"os"
+
+	"github.com/gojuno/i18n_gen"
+)
+
+func main() {
+	i18n_gen.Main(os.Args[1:])
+}
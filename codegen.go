@@ -0,0 +1,136 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const CODEGEN_PLURAL_FORM = "other"
+
+var (
+	codegenPath    string
+	codegenPackage string
+)
+
+func validateCodegen() error {
+	if codegenPath != "" && !token.IsIdentifier(codegenPackage) {
+		return fmt.Errorf("-codegen-package %q is not a valid package name", codegenPackage)
+	}
+	return nil
+}
+
+// generateCode writes downloaded locales of the project as go maps, so services may
+// look translations up without parsing go-i18n files at startup.
+// Plural translations are kept in Plurals, Translations holds their "other" form.
+func generateCode(projectName string) {
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", projectName, err)
+		return
+	}
+	locales := map[string][]*Translation{}
+	for _, path := range files {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Println("WARNING! Unable to read locale file", path, err)
+			continue
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			log.Println("WARNING! Unable to parse locale file", path, err)
+			continue
+		}
+		sort.Slice(translations, func(i, j int) bool { return translations[i].ID < translations[j].ID })
+		locales[strings.TrimSuffix(filepath.Base(path), ".json")] = translations
+	}
+
+	src, err := format.Source(localesCode(codegenPackage, projectName, locales))
+	if err != nil {
+		fail(fmt.Errorf("Unable to format generated code, %v", err))
+	}
+	if err := writeOutputFile(codegenPath, src); err != nil {
+		fail(fmt.Errorf("Unable to write generated code %s, %v", codegenPath, err))
+	}
+	log.Println("Go code of", len(locales), "locales was generated to", codegenPath)
+}
+
+func localesCode(pkg, projectName string, locales map[string][]*Translation) []byte {
+	names := make([]string, 0, len(locales))
+	for name := range locales {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintln(buf, "// Code generated by i18n_gen. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintf(buf, "// Translations of phraseapp project %s keyed by locale and id.\n", projectName)
+	fmt.Fprintln(buf, "var Translations = map[string]map[string]string{")
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s: {\n", strconv.Quote(name))
+		for _, t := range locales[name] {
+			text := t.Text
+			if t.IsPlural() {
+				text = t.Plural[CODEGEN_PLURAL_FORM]
+			}
+			fmt.Fprintf(buf, "%s: %s,\n", strconv.Quote(t.ID), strconv.Quote(text))
+		}
+		fmt.Fprintln(buf, "},")
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "// Plurals holds plural forms of plural translations keyed by locale, id and form.")
+	fmt.Fprintln(buf, "var Plurals = map[string]map[string]map[string]string{")
+	for _, name := range names {
+		fmt.Fprintf(buf, "%s: {\n", strconv.Quote(name))
+		for _, t := range locales[name] {
+			if !t.IsPlural() {
+				continue
+			}
+			forms := make([]string, 0, len(t.Plural))
+			for form := range t.Plural {
+				forms = append(forms, form)
+			}
+			sort.Strings(forms)
+			fmt.Fprintf(buf, "%s: {", strconv.Quote(t.ID))
+			for _, form := range forms {
+				fmt.Fprintf(buf, "%s: %s, ", strconv.Quote(form), strconv.Quote(t.Plural[form]))
+			}
+			fmt.Fprintln(buf, "},")
+		}
+		fmt.Fprintln(buf, "},")
+	}
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "// Lookup returns translation of id in locale, falling back to the id itself.")
+	fmt.Fprintln(buf, "func Lookup(locale, id string) string {")
+	fmt.Fprintln(buf, "if text, ok := Translations[locale][id]; ok {")
+	fmt.Fprintln(buf, "return text")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "return id")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "// VariantID returns id of the variant key of id declared by i18n:variants, e.g. formal.")
+	fmt.Fprintln(buf, "func VariantID(id, variant string) string {")
+	fmt.Fprintf(buf, "return id + %s + variant\n", strconv.Quote(VARIANT_SEPARATOR))
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf)
+	fmt.Fprintln(buf, "// LookupVariant returns translation of the variant of id in locale, falling back to Lookup of id")
+	fmt.Fprintln(buf, "// in locales which don't translate the variant.")
+	fmt.Fprintln(buf, "func LookupVariant(locale, id, variant string) string {")
+	fmt.Fprintln(buf, "if text, ok := Translations[locale][VariantID(id, variant)]; ok && text != \"\" {")
+	fmt.Fprintln(buf, "return text")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "return Lookup(locale, id)")
+	fmt.Fprintln(buf, "}")
+	return buf.Bytes()
+}
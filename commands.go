@@ -0,0 +1,79 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+type command struct {
+	name        string
+	description string
+	// setFlags registers command flags, may be nil.
+	setFlags func(fs *flag.FlagSet)
+	run      func(fs *flag.FlagSet)
+}
+
+// commands is filled in init to avoid initialization loop with commands listing other commands.
+var commands []*command
+
+func init() {
+	commands = []*command{
+		syncCommand,
+		downloadCommand,
+		promoteCommand,
+		versionCommand,
+		selfUpdateCommand,
+		completionCommand,
+		pullDescriptionsCommand,
+		costCommand,
+		extractVerifyCommand,
+		configCommand,
+		docsCommand,
+		exportComplianceCommand,
+		serveCommand,
+		selftestCommand,
+		cacheServerCommand,
+		remoteCommand,
+	}
+}
+
+func findCommand(name string) *command {
+	for _, c := range commands {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func (c *command) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet("i18n_gen "+c.name, flag.ExitOnError)
+	if c.setFlags != nil {
+		c.setFlags(fs)
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of i18n_gen %s: %s\n", c.name, c.description)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+func (c *command) execute(args []string) {
+	fs := c.flagSet()
+	fs.Parse(args)
+	c.run(fs)
+}
+
+// Main runs the command line interface, args exclude the program name.
+// Sync is executed when the first argument is not a command name.
+func Main(args []string) {
+	sanitizeLog()
+	if len(args) > 0 {
+		if c := findCommand(args[0]); c != nil {
+			c.execute(args[1:])
+			return
+		}
+	}
+	syncCommand.execute(args)
+}
@@ -0,0 +1,163 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+var completionCommand = &command{
+	name:        "completion",
+	description: "print shell completion script or man page: completion bash|zsh|fish|man",
+	run:         runCompletion,
+}
+
+func runCompletion(fs *flag.FlagSet) {
+	generators := map[string]func() string{
+		"bash": bashCompletion,
+		"zsh":  zshCompletion,
+		"fish": fishCompletion,
+		"man":  manPage,
+	}
+	generate, ok := generators[fs.Arg(0)]
+	if !ok {
+		fs.Usage()
+		os.Exit(2)
+	}
+	fmt.Print(generate())
+}
+
+type commandFlag struct {
+	name   string
+	usage  string
+	isBool bool
+}
+
+func commandFlags(c *command) []commandFlag {
+	flags := []commandFlag{}
+	c.flagSet().VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, commandFlag{f.Name, f.Usage, ok && b.IsBoolFlag()})
+	})
+	return flags
+}
+
+func flagNames(c *command) string {
+	names := []string{}
+	for _, f := range commandFlags(c) {
+		names = append(names, "-"+f.name)
+	}
+	return strings.Join(names, " ")
+}
+
+func bashCompletion() string {
+	buf := bytes.NewBuffer(nil)
+	names := []string{}
+	for _, c := range commands {
+		names = append(names, c.name)
+	}
+	fmt.Fprintln(buf, "# bash completion for i18n_gen")
+	fmt.Fprintln(buf, "_i18n_gen() {")
+	fmt.Fprintln(buf, "    local cur=\"${COMP_WORDS[COMP_CWORD]}\"")
+	fmt.Fprintln(buf, "    if [ \"$COMP_CWORD\" -eq 1 ]; then")
+	fmt.Fprintf(buf, "        COMPREPLY=($(compgen -W \"%s %s\" -- \"$cur\"))\n", strings.Join(names, " "), flagNames(syncCommand))
+	fmt.Fprintln(buf, "        return")
+	fmt.Fprintln(buf, "    fi")
+	fmt.Fprintln(buf, "    case \"${COMP_WORDS[1]}\" in")
+	for _, c := range commands {
+		fmt.Fprintf(buf, "        %s) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", c.name, flagNames(c))
+	}
+	fmt.Fprintf(buf, "        *) COMPREPLY=($(compgen -W \"%s\" -- \"$cur\")) ;;\n", flagNames(syncCommand))
+	fmt.Fprintln(buf, "    esac")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "complete -o default -F _i18n_gen i18n_gen")
+	return buf.String()
+}
+
+func zshCompletion() string {
+	escape := strings.NewReplacer("'", "'\\''", "[", "\\[", "]", "\\]", ":", "\\:")
+	arguments := func(c *command) string {
+		specs := []string{}
+		for _, f := range commandFlags(c) {
+			spec := "'-" + f.name + "[" + escape.Replace(f.usage) + "]"
+			if !f.isBool {
+				spec += ":" + f.name + ": "
+			}
+			specs = append(specs, spec+"'")
+		}
+		return strings.Join(specs, " ")
+	}
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintln(buf, "#compdef i18n_gen")
+	fmt.Fprintln(buf, "_i18n_gen() {")
+	fmt.Fprintln(buf, "    local -a commands")
+	fmt.Fprintln(buf, "    commands=(")
+	for _, c := range commands {
+		fmt.Fprintf(buf, "        '%s:%s'\n", c.name, escape.Replace(c.description))
+	}
+	fmt.Fprintln(buf, "    )")
+	fmt.Fprintln(buf, "    if (( CURRENT == 2 )); then")
+	fmt.Fprintln(buf, "        _describe 'command' commands")
+	fmt.Fprintln(buf, "        return")
+	fmt.Fprintln(buf, "    fi")
+	fmt.Fprintln(buf, "    case $words[2] in")
+	for _, c := range commands {
+		fmt.Fprintf(buf, "        %s) shift words; (( CURRENT-- )); _arguments %s ;;\n", c.name, arguments(c))
+	}
+	fmt.Fprintf(buf, "        *) _arguments %s ;;\n", arguments(syncCommand))
+	fmt.Fprintln(buf, "    esac")
+	fmt.Fprintln(buf, "}")
+	fmt.Fprintln(buf, "_i18n_gen \"$@\"")
+	return buf.String()
+}
+
+func fishCompletion() string {
+	escape := strings.NewReplacer("'", "\\'")
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintln(buf, "# fish completion for i18n_gen")
+	for _, c := range commands {
+		fmt.Fprintf(buf, "complete -c i18n_gen -f -n '__fish_use_subcommand' -a %s -d '%s'\n", c.name, escape.Replace(c.description))
+		for _, f := range commandFlags(c) {
+			condition := "__fish_seen_subcommand_from " + c.name
+			if c == syncCommand {
+				condition = "__fish_use_subcommand; or " + condition
+			}
+			requiresValue := " -r"
+			if f.isBool {
+				requiresValue = ""
+			}
+			fmt.Fprintf(buf, "complete -c i18n_gen -n '%s' -o %s%s -d '%s'\n", condition, f.name, requiresValue, escape.Replace(f.usage))
+		}
+	}
+	return buf.String()
+}
+
+func manPage() string {
+	escape := strings.NewReplacer("\\", "\\e", "-", "\\-")
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, ".TH I18N_GEN 1 \"%s\" \"i18n_gen %s\"\n", time.Now().Format("2006-01-02"), Version)
+	fmt.Fprintln(buf, ".SH NAME")
+	fmt.Fprintln(buf, "i18n_gen \\- extract localized strings from go sources and sync them with phraseapp")
+	fmt.Fprintln(buf, ".SH SYNOPSIS")
+	fmt.Fprintln(buf, ".B i18n_gen")
+	fmt.Fprintln(buf, "[\\fIcommand\\fR] [\\fIflags\\fR]")
+	fmt.Fprintln(buf, ".SH COMMANDS")
+	for _, c := range commands {
+		fmt.Fprintf(buf, ".SS %s\n", c.name)
+		fmt.Fprintln(buf, escape.Replace(c.description))
+		for _, f := range commandFlags(c) {
+			fmt.Fprintln(buf, ".TP")
+			if f.isBool {
+				fmt.Fprintf(buf, ".B \\-%s\n", escape.Replace(f.name))
+			} else {
+				fmt.Fprintf(buf, ".BI \\-%s \" value\"\n", escape.Replace(f.name))
+			}
+			fmt.Fprintln(buf, escape.Replace(f.usage))
+		}
+	}
+	return buf.String()
+}
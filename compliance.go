@@ -0,0 +1,230 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/x509"
+	"encoding/csv"
+	"encoding/pem"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ComplianceConfig selects compliance-sensitive keys, legal terms, consents and disclaimers, by tag or id prefix.
+type ComplianceConfig struct {
+	Tags     []string `json:"tags"`
+	Prefixes []string `json:"prefixes"`
+}
+
+var (
+	compliancePath       string
+	complianceLocales    string
+	complianceSigningKey string
+)
+
+// ComplianceRow is a translation of a compliance-sensitive key, Form is set for plural keys.
+type ComplianceRow struct {
+	Locale      string
+	Key         string
+	Form        string
+	Source      string
+	Translation string
+}
+
+var exportComplianceCommand = &command{
+	name:        "export-compliance",
+	description: "write signed csv of compliance-sensitive keys of -project in downloaded locales for legal review",
+	setFlags: func(fs *flag.FlagSet) {
+		setCommonFlags(fs)
+		fs.StringVar(&compliancePath, "out", "compliance.csv", "file to write the export to, the signature is written to <out>.sig")
+		fs.StringVar(&complianceLocales, "locales", "", "comma separated locales to export, all downloaded locales if empty")
+		fs.StringVar(&complianceSigningKey, "signing-key", "", "PEM file of ed25519 private key (PKCS #8) signing the export")
+	},
+	run: runExportCompliance,
+}
+
+func (c *ComplianceConfig) validate() error {
+	if c != nil && len(c.Tags) == 0 && len(c.Prefixes) == 0 {
+		return fmt.Errorf("Compliance keys need tags or prefixes")
+	}
+	return nil
+}
+
+// isSensitive reports whether the key is compliance-sensitive by its id or tags.
+func (c *ComplianceConfig) isSensitive(id string, tags []string) bool {
+	for _, prefix := range c.Prefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	for _, tag := range c.Tags {
+		if hasTag(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+func runExportCompliance(fs *flag.FlagSet) {
+	validateCommonFlags()
+	if config.Compliance == nil {
+		log.Fatalln("Please, specify compliance tags or prefixes in config")
+	}
+	if complianceSigningKey == "" {
+		log.Fatalln("Please, specify -signing-key, legal review needs signed exports")
+	}
+	key, err := readSigningKey(complianceSigningKey)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	tags := map[string][]string{}
+	if len(config.Compliance.Tags) > 0 {
+		worker := connect()
+		if tags, err = worker.KeyTags(&i18nGenContext{}, phraseappProjects[defaultProject], defaultProject); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	rows, err := complianceRows(defaultProject, tags)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if len(rows) == 0 {
+		log.Fatalln("No compliance-sensitive keys were found in downloaded locales of", defaultProject)
+	}
+	data, err := complianceCsv(defaultProject, dataVersion(), time.Now(), rows)
+	if err != nil {
+		log.Fatalln("Unable to write compliance export", err)
+	}
+	if err := writeOutputFile(compliancePath, data); err != nil {
+		log.Fatalln("Unable to write compliance export", compliancePath, err)
+	}
+	if err := writeOutputFile(compliancePath+".sig", ed25519.Sign(key, data)); err != nil {
+		log.Fatalln("Unable to write signature", compliancePath, err)
+	}
+	log.Println(len(rows), "compliance strings were exported to", compliancePath)
+}
+
+func readSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read signing key, %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("Signing key %s is not a PEM file", path)
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse signing key, %v", err)
+	}
+	key, ok := parsed.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("Signing key %s is not an ed25519 key", path)
+	}
+	return key, nil
+}
+
+// dataVersion returns version of downloaded locales, the same as the -bundles version, empty without a manifest.
+func dataVersion() string {
+	manifest, err := ioutil.ReadFile(filepath.Join(getLocalizationFolderName(), MANIFEST_FILE))
+	if err != nil {
+		log.Println("WARNING! Export is not versioned without manifest of downloaded locales", err)
+		return ""
+	}
+	return dataSha256(manifest)[:BUNDLE_VERSION_SIZE]
+}
+
+// complianceRows returns translations of sensitive keys in selected downloaded locales of the project,
+// sorted by locale and key. Source texts are taken from the source locale.
+func complianceRows(projectName string, tags map[string][]string) ([]*ComplianceRow, error) {
+	wanted := map[string]bool{}
+	for _, l := range strings.Split(complianceLocales, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			wanted[l] = true
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list locale files of %s, %v", projectName, err)
+	}
+	byLocale := map[string][]*Translation{}
+	for _, path := range files {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read locale file %s, %v", path, err)
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse locale file %s, %v", path, err)
+		}
+		byLocale[strings.TrimSuffix(filepath.Base(path), ".json")] = translations
+	}
+	for l := range wanted {
+		if _, ok := byLocale[l]; !ok {
+			return nil, fmt.Errorf("Locale %s of %s is not downloaded", l, projectName)
+		}
+	}
+	sources := map[string]*Translation{}
+	for _, t := range byLocale[runtimeLocale(config.Qa.sourceLocale())] {
+		sources[t.ID] = t
+	}
+
+	rows := []*ComplianceRow{}
+	for localeName, translations := range byLocale {
+		if len(wanted) > 0 && !wanted[localeName] {
+			continue
+		}
+		for _, t := range translations {
+			if !config.Compliance.isSensitive(t.ID, tags[t.ID]) {
+				continue
+			}
+			source := sources[t.ID]
+			if !t.IsPlural() {
+				row := &ComplianceRow{Locale: localeName, Key: t.ID, Translation: t.Text}
+				if source != nil {
+					row.Source = source.Text
+				}
+				rows = append(rows, row)
+				continue
+			}
+			for form, text := range t.Plural {
+				row := &ComplianceRow{Locale: localeName, Key: t.ID, Form: form, Translation: text}
+				if source != nil {
+					row.Source = source.Plural[form]
+				}
+				rows = append(rows, row)
+			}
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Locale != rows[j].Locale {
+			return rows[i].Locale < rows[j].Locale
+		}
+		if rows[i].Key != rows[j].Key {
+			return rows[i].Key < rows[j].Key
+		}
+		return rows[i].Form < rows[j].Form
+	})
+	return rows, nil
+}
+
+// complianceCsv writes version info as # comment lines, csv.Reader skips them with Comment set, followed by rows.
+func complianceCsv(projectName, version string, at time.Time, rows []*ComplianceRow) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "# project: %s\n", projectName)
+	fmt.Fprintf(buf, "# data version: %s\n", version)
+	fmt.Fprintf(buf, "# exported: %s by i18n_gen %s\n", at.UTC().Format(time.RFC3339), Version)
+	w := csv.NewWriter(buf)
+	w.Write([]string{"locale", "key", "form", "source", "translation"})
+	for _, r := range rows {
+		w.Write([]string{r.Locale, r.Key, r.Form, r.Source, r.Translation})
+	}
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
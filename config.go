@@ -0,0 +1,209 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+)
+
+const CONFIG_DATE_FORMAT = "2006-01-02"
+
+type (
+	// Config holds settings which don't fit command line flags, it is read from -config json file.
+	Config struct {
+		Freeze []*FreezeWindow `json:"freeze"`
+		Digest *DigestConfig   `json:"digest"`
+		Cost   *CostConfig     `json:"cost"`
+		// Seeds are go-i18n json files, relative to -path, with keys uploaded along with extracted ones.
+		Seeds []string `json:"seeds"`
+		// KeyMapping is a json file, relative to -path, renaming legacy ids of sources to new ids on upload,
+		// downloaded translations of new ids are written under legacy ids as well.
+		KeyMapping string `json:"key_mapping"`
+		// Download holds locale download parameters keyed by project name, "*" applies to all other projects.
+		Download map[string]*DownloadConfig `json:"download"`
+		// Upload holds upload parameters keyed by project name, "*" applies to all other projects.
+		Upload map[string]*UploadConfig `json:"upload"`
+		// Packages are import paths of packages whose NewI18nString calls are extracted, any package matches if empty.
+		Packages []string `json:"packages"`
+		// Directories maps service directories, relative to -path, to projects they use, it limits download of -only runs.
+		Directories map[string]string `json:"directories"`
+		// KeyRoutes maps extracted keys, "driver.*" prefixes or whole ids, to projects they are uploaded to instead of
+		// -project. The longest matching route wins.
+		KeyRoutes map[string]string `json:"key_routes"`
+		Qa        *QaConfig         `json:"qa"`
+		// StructTags are names of struct tags whose values are keys, in any go file.
+		StructTags []string `json:"struct_tags"`
+		// ConstPackages are folders of packages, relative to -path, whose exported string constants are keys.
+		ConstPackages []string `json:"const_packages"`
+		// Messages are types, import path and name, whose literals define keys: ID, Description, One and Other fields.
+		Messages []string `json:"messages"`
+		// LocaleAliases map phraseapp locale names to runtime codes files are named and normalized by.
+		LocaleAliases map[string]string `json:"locale_aliases"`
+		// Notes is a yaml file, relative to -path, of key notes synced with phraseapp comments.
+		Notes string `json:"notes"`
+		// Placeholders are comma separated placeholder styles by project name, "*" applies to other projects.
+		Placeholders map[string]string `json:"placeholders"`
+		Output       *OutputConfig     `json:"output"`
+		// Schedule sets download intervals of locales, locales matching no entry are downloaded by every run.
+		Schedule []*ScheduleEntry `json:"schedule"`
+		Provider *ProviderConfig  `json:"provider"`
+		Emails   *EmailsConfig    `json:"emails"`
+		// Compliance marks keys exported for legal review by export-compliance.
+		Compliance *ComplianceConfig `json:"compliance"`
+		// Regional are language files built from regional locales, keyed by file name, e.g. es from es-ES and es-MX.
+		Regional map[string]*RegionalConfig `json:"regional"`
+		Pii      *PiiConfig                 `json:"pii"`
+		// Sms constrains character sets and lengths of translations of keys delivered by SMS, by key tag.
+		Sms []*SmsConfig `json:"sms"`
+		// Typescript are frontend outputs of downloaded locales, keyed by project name.
+		Typescript map[string]*TypescriptConfig `json:"typescript"`
+		// UnescapeEntities enables upload of source strings with html entities unescaped by project name,
+		// "*" applies to other projects. Downloaded translations get entities of their source back.
+		UnescapeEntities map[string]bool `json:"unescape_entities"`
+		// Overrides is a folder, relative to -path, of <locale>/<project>.json files whose translations win over
+		// downloaded ones, e.g. legally mandated wording of a country.
+		Overrides string `json:"overrides"`
+	}
+
+	// DownloadConfig are phraseapp locale download parameters, file format is always go_i18n.
+	DownloadConfig struct {
+		// Tag limits download to keys tagged with it.
+		Tag                        string                 `json:"tag"`
+		FormatOptions              map[string]interface{} `json:"format_options"`
+		ConvertEmoji               bool                   `json:"convert_emoji"`
+		IncludeEmptyTranslations   bool                   `json:"include_empty_translations"`
+		SkipUnverifiedTranslations bool                   `json:"skip_unverified_translations"`
+		// Subsets are tags keyed by file name, keys of each tag are downloaded to a file of their own as well.
+		Subsets map[string]string `json:"subsets"`
+	}
+
+	// UploadConfig are phraseapp upload parameters of projects with different workflows,
+	// unset ones keep defaults of the phraseapp project.
+	UploadConfig struct {
+		// Autotranslate machine translates new keys, false forbids it where the project has it set up.
+		Autotranslate      *bool `json:"autotranslate"`
+		MarkReviewed       *bool `json:"mark_reviewed"`
+		SkipUnverification *bool `json:"skip_unverification"`
+		// SkipUploadTags keeps phraseapp from tagging uploaded keys with the upload tag.
+		SkipUploadTags *bool `json:"skip_upload_tags"`
+		// UpdateTranslations replaces translations of existing keys, for projects treating source text in code as
+		// authoritative. It is false by default and -update-translations overrides it.
+		UpdateTranslations *bool `json:"update_translations"`
+	}
+
+	// FreezeWindow blocks uploads of new keys between From and To (dates are inclusive).
+	// With Tag set new keys are uploaded tagged with it instead of being blocked.
+	FreezeWindow struct {
+		From     ConfigTime `json:"from"`
+		To       ConfigTime `json:"to"`
+		Projects []string   `json:"projects"`
+		Tag      string     `json:"tag"`
+		Message  string     `json:"message"`
+	}
+
+	// ConfigTime is either RFC3339 time or a date, dates are in local time zone.
+	ConfigTime struct {
+		time.Time
+		IsDate bool
+	}
+)
+
+var (
+	configPath string
+	config     Config
+)
+
+func (t *ConfigTime) UnmarshalJSON(data []byte) error {
+	s := ""
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	if parsed, err := time.ParseInLocation(CONFIG_DATE_FORMAT, s, time.Local); err == nil {
+		t.Time, t.IsDate = parsed, true
+		return nil
+	}
+	parsed, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return fmt.Errorf("Expected date %s or RFC3339 time, got %s", CONFIG_DATE_FORMAT, s)
+	}
+	t.Time, t.IsDate = parsed, false
+	return nil
+}
+
+func loadConfig(path string) (Config, error) {
+	cfg, err := readConfig(path)
+	if err != nil {
+		return cfg, err
+	}
+	return cfg, cfg.validate()
+}
+
+// readConfig parses the config without validating it.
+func readConfig(path string) (Config, error) {
+	cfg := Config{}
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("Unable to read config %s, %v", path, err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("Unable to parse config %s, %v", path, err)
+	}
+	return cfg, nil
+}
+
+func (cfg Config) validate() error {
+	if errs := cfg.problems(); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// problems returns all errors of the config, validate stops on the first one.
+func (cfg Config) problems() []error {
+	errs := []error{}
+	for _, w := range cfg.Freeze {
+		if w.To.Before(w.From.Time) {
+			errs = append(errs, fmt.Errorf("Freeze window ends before it starts, %s - %s", w.From, w.To))
+		}
+	}
+	for _, err := range []error{
+		validatePlaceholderStyles(cfg.Placeholders),
+		validateMessageTypes(cfg.Messages),
+		cfg.Output.validate(),
+		cfg.Provider.validate(),
+		cfg.Emails.validate(),
+		cfg.Compliance.validate(),
+		validateSchedule(cfg.Schedule),
+		validateSubsets(cfg.Download),
+		validateQaConfig(cfg.Qa),
+		validateSms(cfg.Sms),
+		cfg.Pii.validate(),
+		validateRegional(cfg.Regional, cfg.LocaleAliases),
+		validateTypescript(cfg.Typescript),
+	} {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// downloadConfig returns download parameters of the project, nil if none are configured.
+func (cfg Config) downloadConfig(project string) *DownloadConfig {
+	if d, ok := cfg.Download[project]; ok {
+		return d
+	}
+	return cfg.Download["*"]
+}
+
+// uploadConfig returns upload parameters of the project, nil if none are configured.
+func (cfg Config) uploadConfig(project string) *UploadConfig {
+	if u, ok := cfg.Upload[project]; ok {
+		return u
+	}
+	return cfg.Upload["*"]
+}
@@ -0,0 +1,99 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"time"
+)
+
+// configModTime is the modification time of -config when it was last loaded by a long running command.
+var configModTime time.Time
+
+// reloadConfig loads -config again if it changed since it was loaded, logging a diff of the effective config.
+// An invalid config is reported and the previous one is kept.
+func reloadConfig() {
+	if configPath == "" {
+		return
+	}
+	info, err := os.Stat(configPath)
+	if err != nil {
+		log.Println("WARNING! Unable to check config", configPath, err)
+		return
+	}
+	if info.ModTime().Equal(configModTime) {
+		return
+	}
+	configModTime = info.ModTime()
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Println("WARNING! Keeping previous config,", err)
+		return
+	}
+	diff := configDiff(config, cfg)
+	config = cfg
+	if len(diff) == 0 {
+		return
+	}
+	log.Println("Config", configPath, "reloaded:")
+	for _, line := range diff {
+		log.Println(line)
+	}
+}
+
+// configDiff returns settings of the old config changed or removed by the new one prefixed by -, and settings
+// the new one changed or added prefixed by +, as json paths with values.
+func configDiff(old, new Config) []string {
+	oldValues, newValues := configValues(old), configValues(new)
+	diff := []string{}
+	for path, value := range oldValues {
+		if newValues[path] != value {
+			diff = append(diff, "- "+path+" = "+value)
+		}
+	}
+	for path, value := range newValues {
+		if oldValues[path] != value {
+			diff = append(diff, "+ "+path+" = "+value)
+		}
+	}
+	// by path, removed values first
+	sort.Slice(diff, func(i, j int) bool {
+		if diff[i][2:] != diff[j][2:] {
+			return diff[i][2:] < diff[j][2:]
+		}
+		return diff[i][0] == '-'
+	})
+	return diff
+}
+
+// configValues flattens the config to json values by their paths, like download.Backend.tags.
+func configValues(cfg Config) map[string]string {
+	data, _ := json.Marshal(cfg)
+	var tree interface{}
+	json.Unmarshal(data, &tree)
+	values := map[string]string{}
+	flattenJson("", tree, values)
+	return values
+}
+
+func flattenJson(path string, node interface{}, values map[string]string) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for key, child := range n {
+			if path != "" {
+				key = path + "." + key
+			}
+			flattenJson(key, child, values)
+		}
+	case []interface{}:
+		for i, child := range n {
+			flattenJson(fmt.Sprintf("%s[%d]", path, i), child, values)
+		}
+	case nil:
+	default:
+		data, _ := json.Marshal(n)
+		values[path] = string(data)
+	}
+}
@@ -0,0 +1,178 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+)
+
+var (
+	// projectIdRegexp matches phraseapp project ids.
+	projectIdRegexp = regexp.MustCompile(`^[0-9a-f]{32}$`)
+	// localeCodeRegexp matches well-formed BCP 47 tags: language, script, region and variants.
+	localeCodeRegexp = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z]{4})?(-([a-zA-Z]{2}|[0-9]{3}))?(-([a-zA-Z0-9]{5,8}|[0-9][a-zA-Z0-9]{3}))*$`)
+)
+
+var configCommand = &command{
+	name:        "config",
+	description: "validate: check -config file and sync flags, listing all problems",
+	setFlags:    setSyncFlags,
+	run:         runConfig,
+}
+
+func runConfig(fs *flag.FlagSet) {
+	if fs.Arg(0) != "validate" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	// flags may follow the subcommand as well
+	fs.Parse(fs.Args()[1:])
+	problems := configProblems()
+	if len(problems) == 0 {
+		fmt.Println("Config and flags are valid")
+		return
+	}
+	for _, p := range problems {
+		fmt.Println("-", p)
+	}
+	fmt.Println(len(problems), "problems found")
+	os.Exit(1)
+}
+
+// configProblems checks config and flags of sync without stopping on the first problem.
+// The token is checked by fetching mapped projects only when everything else is valid.
+func configProblems() []error {
+	errs := []error{}
+	add := func(err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	cfg, err := readConfig(configPath)
+	add(err)
+	if err == nil {
+		errs = append(errs, cfg.problems()...)
+		config = cfg
+	}
+
+	if phraseappToken == "" {
+		add(fmt.Errorf("Please, specify phraseapp token"))
+	}
+	if perPage < 1 || perPage > MAX_PER_PAGE {
+		add(fmt.Errorf("Page size should be between 1 and %d", MAX_PER_PAGE))
+	}
+	if _, ok := phraseappProjects[defaultProject]; !ok {
+		add(fmt.Errorf("Please, specify phraseapp project id for default project"))
+	}
+	projects := make([]string, 0, len(phraseappProjects))
+	for project := range phraseappProjects {
+		projects = append(projects, project)
+	}
+	sort.Strings(projects)
+	for _, project := range projects {
+		if !projectIdRegexp.MatchString(phraseappProjects[project]) {
+			add(fmt.Errorf("Project id %s of %s should be 32 hex digits", phraseappProjects[project], project))
+		}
+	}
+
+	switch info, err := os.Stat(basepath); {
+	case basepath == "":
+		add(fmt.Errorf("Please, specify path to micro-services"))
+	case basepath == STDIN_PATH:
+		// the stream can't be checked without consuming it
+	case err != nil:
+		add(fmt.Errorf("Unable to find -path %s, %v", basepath, err))
+	case info.IsDir():
+		errs = append(errs, configPathProblems(config)...)
+	case !isSourceArchive(basepath):
+		add(fmt.Errorf("-path %s is not a directory or an archive", basepath))
+	}
+
+	errs = append(errs, localeCodeProblems(config)...)
+	add(validateErrorPolicy())
+	add(validateCodegen())
+	add(validateSuggestions())
+	add(validateHashIds())
+	add(validateKeyRoutes(config.KeyRoutes))
+	if codegenPath != "" && filepath.Ext(codegenPath) != ".go" {
+		add(fmt.Errorf("-codegen %s should be a go file", codegenPath))
+	}
+	errs = append(errs, flagConflicts()...)
+
+	if len(errs) > 0 {
+		return errs
+	}
+	worker, err := newWorker()
+	if err != nil {
+		return []error{err}
+	}
+	return worker.ValidateProjects(&i18nGenContext{})
+}
+
+// configPathProblems checks files and folders of the config exist under -path.
+func configPathProblems(cfg Config) []error {
+	errs := []error{}
+	paths := append(append([]string{}, cfg.Seeds...), cfg.ConstPackages...)
+	for _, path := range []string{cfg.KeyMapping, cfg.Notes} {
+		if path != "" {
+			paths = append(paths, path)
+		}
+	}
+	for dir := range cfg.Directories {
+		paths = append(paths, dir)
+	}
+	for _, dir := range syncDirs() {
+		paths = append(paths, dir)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if _, err := os.Stat(filepath.Join(basepath, path)); err != nil {
+			errs = append(errs, fmt.Errorf("Unable to find %s under -path, %v", path, err))
+		}
+	}
+	return errs
+}
+
+// localeCodeProblems checks locale codes of flags and the config are BCP 47 tags.
+func localeCodeProblems(cfg Config) []error {
+	codes := map[string]string{}
+	if defaultLocale != "" {
+		codes[defaultLocale] = "-locale"
+	}
+	if cfg.Qa != nil && cfg.Qa.SourceLocale != "" {
+		codes[cfg.Qa.SourceLocale] = "qa source_locale"
+	}
+	for name, alias := range cfg.LocaleAliases {
+		codes[alias] = "locale alias of " + name
+	}
+	errs := []error{}
+	for code, origin := range codes {
+		if !localeCodeRegexp.MatchString(code) {
+			errs = append(errs, fmt.Errorf("Locale %s of %s is not a BCP 47 code", code, origin))
+		}
+	}
+	sort.Slice(errs, func(i, j int) bool { return errs[i].Error() < errs[j].Error() })
+	return errs
+}
+
+// flagConflicts lists flags which are ignored or contradict each other.
+func flagConflicts() []error {
+	errs := []error{}
+	if promoteWhenClean && !candidateMode {
+		errs = append(errs, fmt.Errorf("-promote-when-clean requires -candidate"))
+	}
+	if stubSuffix != "" && !stubNewKeys {
+		errs = append(errs, fmt.Errorf("-stub-suffix requires -stub-new-keys"))
+	}
+	if bootstrapMode && bootstrapBatch < 1 {
+		errs = append(errs, fmt.Errorf("Bootstrap batch should be positive"))
+	}
+	if ignoreSchedule && len(config.Schedule) == 0 {
+		errs = append(errs, fmt.Errorf("-ignore-schedule is set but the config has no schedule"))
+	}
+	return errs
+}
@@ -0,0 +1,60 @@
+package i18n_gen
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// IGNORE_DIRECTIVE placed above a constant or a const block of config.ConstPackages keeps its values from being keys.
+const IGNORE_DIRECTIVE = "ignore"
+
+// inConstPackage reports whether the go file belongs to one of config.ConstPackages, test files excluded.
+func inConstPackage(path string) bool {
+	if len(config.ConstPackages) == 0 || !strings.HasSuffix(path, ".go") || strings.HasSuffix(path, "_test.go") {
+		return false
+	}
+	dir, err := filepath.Rel(basepath, filepath.Dir(path))
+	if err != nil {
+		return false
+	}
+	for _, p := range config.ConstPackages {
+		if filepath.Clean(p) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// extractConstants adds values of exported string constants: const MsgDriverArrived = "driver.arrived".
+func extractConstants(v *FuncVisitor, fset *token.FileSet, file *ast.File, directives fileDirectives) {
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		if _, ok := directives.find(fset, gen, IGNORE_DIRECTIVE); ok {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+			if _, ok := directives.find(fset, value, IGNORE_DIRECTIVE); ok {
+				continue
+			}
+			for i, name := range value.Names {
+				if !name.IsExported() || i >= len(value.Values) {
+					continue
+				}
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+				if id, err := strconv.Unquote(lit.Value); err == nil && id != "" {
+					v.AddLocation(id, fset.Position(lit.Pos()).String())
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// COORDINATE_POLL is the pause between checks of runners waiting for the one holding the lease.
+const COORDINATE_POLL = 5 * time.Second
+
+var (
+	// startJitter delays the start of a sync by a random duration up to it, spreading runners started together.
+	startJitter time.Duration
+	// coordinateUrl is redis://host:port/key runners agree on: the one holding key+":lease" syncs with phraseapp
+	// and publishes the version of its bundle in key+":bundle", others restore that bundle from -bundles.
+	coordinateUrl    string
+	coordinateMaxAge time.Duration
+	coordinateLease  time.Duration
+	// coordination is the connection of the runner holding the lease, nil otherwise.
+	coordination *redisStore
+)
+
+func validateCoordination() error {
+	if startJitter < 0 {
+		return fmt.Errorf("Start jitter should not be negative")
+	}
+	if coordinateUrl == "" {
+		return nil
+	}
+	u, err := url.Parse(coordinateUrl)
+	if err != nil || u.Scheme != "redis" {
+		return fmt.Errorf("Expected redis://host:port/key -coordinate location, got %s", coordinateUrl)
+	}
+	if bundleLocation == "" {
+		return fmt.Errorf("Coordinated runners publish and reuse bundles, please, specify -bundles")
+	}
+	if coordinateMaxAge <= 0 || coordinateLease <= 0 {
+		return fmt.Errorf("Coordination max age and lease should be positive")
+	}
+	return nil
+}
+
+// waitStartJitter sleeps for a random part of -start-jitter, a canceled Sync stops waiting.
+func waitStartJitter() {
+	if startJitter <= 0 {
+		return
+	}
+	// the global source is not seeded, runners would all wait the same
+	delay := time.Duration(rand.New(rand.NewSource(time.Now().UnixNano())).Int63n(int64(startJitter)))
+	log.Println("Starting in", delay.Round(time.Second))
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	var done <-chan struct{}
+	if syncCtx != nil {
+		done = syncCtx.Done()
+	}
+	select {
+	case <-timer.C:
+	case <-done:
+	}
+}
+
+// coordinateSync takes the lease of -coordinate or restores the bundle published by another runner, waiting for
+// the runner holding the lease to finish. It reports whether the bundle was restored and the sync is done.
+// A lease of a runner which died expires after -coordinate-lease.
+func coordinateSync() (bool, error) {
+	if coordinateUrl == "" {
+		return false, nil
+	}
+	u, _ := url.Parse(coordinateUrl)
+	s, err := dialRedis(u)
+	if err != nil {
+		return false, err
+	}
+	token, err := redisToken()
+	if err != nil {
+		s.conn.Close()
+		return false, err
+	}
+	s.token = token
+	ttl := strconv.FormatInt(int64(coordinateLease/time.Millisecond), 10)
+	waiting := false
+	for {
+		version, err := s.command("GET", s.key+":bundle")
+		if err != nil {
+			s.conn.Close()
+			return false, fmt.Errorf("Unable to read published bundle %s, %v", s.key, err)
+		}
+		if version != nil {
+			s.conn.Close()
+			return true, reusePublishedBundle(string(version))
+		}
+		reply, err := s.command("SET", s.key+":lease", s.token, "NX", "PX", ttl)
+		if err != nil {
+			s.conn.Close()
+			return false, fmt.Errorf("Unable to take sync lease %s, %v", s.key, err)
+		}
+		if reply != nil {
+			coordination = s
+			return false, nil
+		}
+		if !waiting {
+			log.Println("Another runner is syncing, waiting for its bundle")
+			waiting = true
+		}
+		if isCanceled() {
+			s.conn.Close()
+			return false, syncCtx.Err()
+		}
+		time.Sleep(COORDINATE_POLL)
+	}
+}
+
+func reusePublishedBundle(version string) error {
+	storage, err := openBundleStorage(bundleLocation)
+	if err != nil {
+		return fmt.Errorf("Unable to open bundles, %v", err)
+	}
+	if err := restoreBundle(storage, version); err != nil {
+		return err
+	}
+	report.Bundle = version
+	log.Println("Bundle", version, "published by another runner was restored to", basepath)
+	return nil
+}
+
+// publishCoordinatedBundle lets runners reuse the bundle of this sync for -coordinate-max-age.
+func publishCoordinatedBundle() {
+	if coordination == nil || report.Bundle == "" || len(report.Errors) > 0 {
+		return
+	}
+	ttl := strconv.FormatInt(int64(coordinateMaxAge/time.Millisecond), 10)
+	if _, err := coordination.command("SET", coordination.key+":bundle", report.Bundle, "PX", ttl); err != nil {
+		log.Println("WARNING! Unable to publish bundle", report.Bundle, err)
+	}
+}
+
+// releaseCoordination gives up the lease, runners still waiting take it unless a bundle was published.
+func releaseCoordination() {
+	if coordination == nil {
+		return
+	}
+	coordination.command("EVAL", REDIS_UNLOCK_SCRIPT, "1", coordination.key+":lease", coordination.token)
+	coordination.conn.Close()
+	coordination = nil
+}
@@ -0,0 +1,136 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// CostConfig holds translation rates per word, Rates are keyed by locale name.
+type CostConfig struct {
+	Currency    string             `json:"currency"`
+	DefaultRate float64            `json:"default_rate"`
+	Rates       map[string]float64 `json:"rates"`
+}
+
+type localeCost struct {
+	project      string
+	locale       string
+	untranslated int
+	words        int
+	rate         float64
+}
+
+var costCommand = &command{
+	name:        "cost",
+	description: "estimate cost of translating untranslated strings with per-locale word rates from config",
+	setFlags:    setCommonFlags,
+	run:         runCost,
+}
+
+func (c *CostConfig) Rate(localeName string) float64 {
+	if rate, ok := c.Rates[localeName]; ok {
+		return rate
+	}
+	return c.DefaultRate
+}
+
+func runCost(fs *flag.FlagSet) {
+	validateCommonFlags()
+	if config.Cost == nil {
+		log.Fatalln("Please, specify cost rates in config")
+	}
+	worker := connect()
+	localCtx := &i18nGenContext{}
+
+	costs := []*localeCost{}
+	for projectName, projectId := range phraseappProjects {
+		projectCosts, err := worker.projectCosts(localCtx, projectId, projectName)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		costs = append(costs, projectCosts...)
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		if costs[i].project != costs[j].project {
+			return costs[i].project < costs[j].project
+		}
+		return costs[i].locale < costs[j].locale
+	})
+	printCosts(costs, config.Cost.Currency)
+}
+
+// projectCosts counts words of source strings which are missing or untranslated in each non default locale.
+func (c *PhraseappWorkerContext) projectCosts(ctx PhraseappContexter, projectId, projectName string) ([]*localeCost, error) {
+	locales, err := c.getLocales(ctx, projectId, projectName)
+	if err != nil {
+		return nil, err
+	}
+	files := map[string]map[string]*Translation{}
+	source := map[string]*Translation{}
+	for _, locale := range locales {
+		data, _, err := c.downloadLocaleImpl(ctx, projectId, projectName, locale.ID, locale.Name, "", "")
+		if err != nil {
+			return nil, err
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse locale %s of project %s, %v", locale.Name, projectName, err)
+		}
+		files[locale.Name] = map[string]*Translation{}
+		for _, t := range translations {
+			files[locale.Name][t.ID] = t
+			if locale.Default {
+				source[t.ID] = t
+			}
+		}
+	}
+
+	costs := []*localeCost{}
+	for _, locale := range locales {
+		if locale.Default {
+			continue
+		}
+		lc := &localeCost{project: projectName, locale: locale.Name, rate: config.Cost.Rate(locale.Name)}
+		// keys of the source locale missing in the locale are to be translated as well
+		for id, s := range source {
+			if t, ok := files[locale.Name][id]; ok && !t.IsUntranslated() {
+				continue
+			}
+			lc.untranslated++
+			lc.words += sourceWords(id, s)
+		}
+		costs = append(costs, lc)
+	}
+	return costs, nil
+}
+
+// sourceWords counts words of all plural forms of the source string, or of the source text of id without one.
+func sourceWords(id string, source *Translation) int {
+	if source == nil || source.IsUntranslated() {
+		return len(strings.Fields(sourceText(id)))
+	}
+	words := 0
+	for _, text := range source.Texts() {
+		words += len(strings.Fields(text))
+	}
+	return words
+}
+
+func printCosts(costs []*localeCost, currency string) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Project\tLocale\tUntranslated\tWords\tRate\tCost\t")
+	totalWords, total := 0, 0.0
+	for _, c := range costs {
+		cost := float64(c.words) * c.rate
+		totalWords += c.words
+		total += cost
+		fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%.3f\t%.2f %s\t\n", c.project, c.locale, c.untranslated, c.words, c.rate, cost, currency)
+	}
+	fmt.Fprintf(w, "Total\t\t\t%d\t\t%.2f %s\t\n", totalWords, total, currency)
+	w.Flush()
+}
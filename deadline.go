@@ -0,0 +1,68 @@
+package i18n_gen
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+var (
+	// syncTimeout limits duration of a sync run, zero disables the deadline.
+	syncTimeout time.Duration
+	deadline    time.Time
+)
+
+func startDeadline(now time.Time) {
+	if syncTimeout > 0 {
+		deadline = now.Add(syncTimeout)
+	}
+}
+
+func (c *i18nGenContext) Expired() bool {
+	return isCanceled() || (!deadline.IsZero() && time.Now().After(deadline))
+}
+
+func (c *i18nGenContext) OnSkip(projectName, localeName string) {
+	logLocale(projectName, localeName, "WARNING! Deadline exceeded or sync canceled, locale is skipped", projectName, localeName)
+	report.AddSkipped(projectName, localeName)
+}
+
+// reportDeadline turns skipped locales into a run error, so the run fails after state is saved.
+func reportDeadline() {
+	if len(report.Skipped) > 0 {
+		report.AddError(fmt.Errorf("Deadline %s exceeded or sync canceled, %d locales were skipped", syncTimeout, len(report.Skipped)))
+	}
+}
+
+// deadlineTransport bounds each request by the time left until the deadline, so no request outlives the run.
+type deadlineTransport struct {
+	next http.RoundTripper
+}
+
+func (t *deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if deadline.IsZero() {
+		return t.next.RoundTrip(req)
+	}
+	ctx, cancel := context.WithDeadline(req.Context(), deadline)
+	resp, err := t.next.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	// the body is read after RoundTrip returns, the context is released with it
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
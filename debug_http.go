@@ -0,0 +1,79 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	debugHttp        bool
+	debugHttpDumpDir string
+)
+
+// debugTransport logs metadata of every request and optionally dumps requests and responses to files.
+type debugTransport struct {
+	next    http.RoundTripper
+	dumpDir string
+	counter int64
+}
+
+func newDebugTransport(dumpDir string) *debugTransport {
+	return &debugTransport{next: http.DefaultTransport, dumpDir: dumpDir}
+}
+
+func (t *debugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	n := atomic.AddInt64(&t.counter, 1)
+	if t.dumpDir != "" {
+		if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+			t.dump(n, "request", dump)
+		}
+	}
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	elapsed := time.Since(start)
+	if err != nil {
+		log.Printf("HTTP #%d %s %s failed after %v, %v\n", n, req.Method, sanitizedUrl(req.URL), elapsed, err)
+		return resp, err
+	}
+
+	log.Printf("HTTP #%d %s %s %s %v etag=%q rate-limit=%s/%s reset=%s\n", n, req.Method, sanitizedUrl(req.URL), resp.Status, elapsed,
+		resp.Header.Get("Etag"), resp.Header.Get("X-Rate-Limit-Remaining"), resp.Header.Get("X-Rate-Limit-Limit"),
+		resp.Header.Get("X-Rate-Limit-Reset"))
+	if t.dumpDir != "" {
+		if dump, err := httputil.DumpResponse(resp, true); err == nil {
+			t.dump(n, "response", dump)
+		}
+	}
+	return resp, nil
+}
+
+// sanitizedUrl masks user password and access_token query parameter.
+func sanitizedUrl(u *url.URL) string {
+	masked := *u
+	if masked.User != nil {
+		masked.User = url.User(masked.User.Username())
+	}
+	query := masked.Query()
+	if query.Get("access_token") != "" {
+		query.Set("access_token", "***")
+		masked.RawQuery = query.Encode()
+	}
+	return masked.String()
+}
+
+// dump writes request or response with credentials masked.
+func (t *debugTransport) dump(n int64, kind string, data []byte) {
+	data = []byte(sanitize(string(data)))
+	path := filepath.Join(t.dumpDir, fmt.Sprintf("%04d-%s.http", n, kind))
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		log.Println("WARNING! Unable to dump http", kind, err)
+	}
+}
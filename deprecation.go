@@ -0,0 +1,73 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"log"
+	"sort"
+	"time"
+)
+
+const (
+	// DEPRECATED_DIRECTIVE above NewI18nString call sets sunset date of the key: //i18n:deprecated 2017-12-31
+	DEPRECATED_DIRECTIVE = "deprecated"
+	DEPRECATED_TAG       = "deprecated"
+)
+
+// extractDeprecations records sunset dates of keys marked with the deprecated directive.
+func extractDeprecations(v *FuncVisitor, fset *token.FileSet, file *ast.File, directives fileDirectives) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		id, ok := i18nStringId(file, node)
+		if !ok {
+			return true
+		}
+		args, ok := directives.find(fset, node, DEPRECATED_DIRECTIVE)
+		if !ok {
+			return true
+		}
+		sunset, err := time.ParseInLocation(CONFIG_DATE_FORMAT, args, time.Local)
+		if err != nil {
+			v.AddDiagnostic(fset.Position(node.Pos()), fmt.Sprintf("invalid sunset date of %s, expected %s", id, CONFIG_DATE_FORMAT))
+			return true
+		}
+		v.AddDeprecated(id, sunset)
+		return true
+	})
+}
+
+// isDeprecated reports whether key is marked deprecated in sources scanned by this run.
+func isDeprecated(id string) bool {
+	if v == nil {
+		return false
+	}
+	_, ok := v.Deprecated()[id]
+	return ok
+}
+
+// reportExpiredKeys lists keys past their sunset date which are still used in code.
+func reportExpiredKeys(now time.Time) {
+	for id, sunset := range v.Deprecated() {
+		if now.After(sunset.AddDate(0, 0, 1)) {
+			report.AddExpiredKey(fmt.Sprintf("%s (sunset %s)", id, sunset.Format(CONFIG_DATE_FORMAT)))
+		}
+	}
+}
+
+// tagDeprecatedKeys tags deprecated keys of the project in phraseapp.
+func tagDeprecatedKeys(worker *PhraseappWorkerContext, projectName string) {
+	ids := []string{}
+	for id := range v.Deprecated() {
+		if keyProject(id) == projectName {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	err := worker.TagKeys(&i18nGenContext{}, phraseappProjects[projectName], projectName, ids, DEPRECATED_TAG)
+	if err != nil {
+		log.Println("WARNING! Unable to tag deprecated keys", projectName, err)
+	}
+}
@@ -0,0 +1,121 @@
+package i18n_gen
+
+import (
+	"flag"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const DESCRIPTION_COMMENT = "// i18n:"
+
+var pullDescriptionsCommand = &command{
+	name:        "pull-descriptions",
+	description: "write phraseapp key descriptions as " + DESCRIPTION_COMMENT + " comments above NewI18nString calls",
+	setFlags:    setCommonFlags,
+	run:         runPullDescriptions,
+}
+
+func runPullDescriptions(fs *flag.FlagSet) {
+	validateCommonFlags()
+	if isSourceArchive(basepath) {
+		log.Fatalln("Descriptions are written to sources, -path should be a folder")
+	}
+	worker := connect()
+
+	descriptions, err := worker.KeyDescriptions(&i18nGenContext{}, phraseappProjects[defaultProject], defaultProject)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	updated := 0
+	ignore := newSourceIgnore(basepath)
+	err = filepath.Walk(basepath, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			log.Print(err)
+			return nil
+		}
+		if ignore.skip(path, info) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !isLocalizationSource(path) {
+			return nil
+		}
+		changed, err := rewriteDescriptions(path, info.Mode(), descriptions)
+		if err != nil {
+			return err
+		}
+		if changed {
+			log.Println("Updated descriptions in", path)
+			updated++
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalln("Unable to update descriptions", err)
+	}
+	log.Printf("Descriptions of %d keys were pulled, %d files updated.\n", len(descriptions), updated)
+}
+
+// rewriteDescriptions finds NewI18nString calls with ast and puts description comment on the line
+// above each of them, replacing an existing description comment.
+func rewriteDescriptions(path string, mode os.FileMode, descriptions map[string]string) (bool, error) {
+	src, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, src, parser.ParseComments)
+	if err != nil {
+		return false, err
+	}
+
+	callLines := map[int]string{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if id, ok := i18nStringId(file, node); ok {
+			callLines[fset.Position(node.Pos()).Line-1] = id
+		}
+		return true
+	})
+	lineNumbers := []int{}
+	for line := range callLines {
+		lineNumbers = append(lineNumbers, line)
+	}
+	// going from the bottom keeps line numbers valid while inserting
+	sort.Sort(sort.Reverse(sort.IntSlice(lineNumbers)))
+
+	lines := strings.Split(string(src), "\n")
+	for _, line := range lineNumbers {
+		description := stripPlaceholderMetadata(descriptions[callLines[line]])
+		if description == "" {
+			continue
+		}
+		code := lines[line]
+		indent := code[:len(code)-len(strings.TrimLeft(code, " \t"))]
+		comment := indent + DESCRIPTION_COMMENT + " " + strings.Join(strings.Fields(description), " ")
+		if line > 0 && strings.HasPrefix(strings.TrimSpace(lines[line-1]), DESCRIPTION_COMMENT+" ") {
+			lines[line-1] = comment
+			continue
+		}
+		lines = append(lines[:line], append([]string{comment}, lines[line:]...)...)
+	}
+
+	formatted, err := format.Source([]byte(strings.Join(lines, "\n")))
+	if err != nil {
+		return false, err
+	}
+	if string(formatted) == string(src) {
+		return false, nil
+	}
+	return true, ioutil.WriteFile(path, formatted, mode)
+}
@@ -0,0 +1,66 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"log"
+	"sort"
+)
+
+// Diagnostic is a problem of extraction at a source position, Line is zero for file level problems.
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+}
+
+// strictExtract fails the run when extraction has diagnostics, they are warnings otherwise.
+var strictExtract bool
+
+func (d *Diagnostic) String() string {
+	if d.Line == 0 {
+		return d.File + ": " + d.Message
+	}
+	return fmt.Sprintf("%s:%d: %s", d.File, d.Line, d.Message)
+}
+
+func (v *FuncVisitor) AddDiagnostic(pos token.Position, message string) {
+	v.Lock()
+	defer v.Unlock()
+	v.diagnostics = append(v.diagnostics, &Diagnostic{File: pos.Filename, Line: pos.Line, Message: message})
+}
+
+// addParseDiagnostics adds every syntax error of a file, not only the first one.
+func addParseDiagnostics(v *FuncVisitor, path string, err error) {
+	list, ok := err.(scanner.ErrorList)
+	if !ok {
+		v.AddDiagnostic(token.Position{Filename: path}, err.Error())
+		return
+	}
+	for _, e := range list {
+		v.AddDiagnostic(e.Pos, e.Msg)
+	}
+}
+
+// reportDiagnostics prints diagnostics of the extraction sorted by position, with -strict-extract they fail the run.
+func reportDiagnostics(v *FuncVisitor) error {
+	if len(v.diagnostics) == 0 {
+		return nil
+	}
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		a, b := v.diagnostics[i], v.diagnostics[j]
+		if a.File != b.File {
+			return a.File < b.File
+		}
+		return a.Line < b.Line
+	})
+	log.Printf("WARNING! Extraction found %d problems:\n", len(v.diagnostics))
+	for _, d := range v.diagnostics {
+		log.Println("   ", d)
+	}
+	if strictExtract {
+		return fmt.Errorf("Extraction found %d problems, -strict-extract fails the run", len(v.diagnostics))
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/smtp"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+const SMTP_PASSWORD_ENV = "I18N_GEN_SMTP_PASSWORD"
+
+type (
+	// DigestConfig specifies where the digest of new keys is sent, both destinations may be used.
+	DigestConfig struct {
+		Webhook string      `json:"webhook"`
+		Smtp    *SmtpConfig `json:"smtp"`
+	}
+
+	// SmtpConfig password is taken from I18N_GEN_SMTP_PASSWORD.
+	SmtpConfig struct {
+		Addr     string   `json:"addr"`
+		Username string   `json:"username"`
+		From     string   `json:"from"`
+		To       []string `json:"to"`
+	}
+
+	// ProjectDigest lists keys added to a project since the previous run.
+	ProjectDigest struct {
+		Project string   `json:"project"`
+		Keys    []string `json:"keys"`
+		Words   int      `json:"words"`
+		// Refs are file:line positions of new keys harvested from struct tags.
+		Refs map[string][]string `json:"refs,omitempty"`
+	}
+)
+
+// recordExtractedKeys remembers keys extracted for the project and reports keys missing in the previous run,
+// previousKeys are keys of all projects recorded by the previous run. Nothing is reported on the first run,
+// when there is nothing to compare with.
+func recordExtractedKeys(projectName string, ids []string, previousKeys map[string][]string) {
+	previous, ok := previousKeys[projectName]
+	if runInfo.Keys == nil {
+		runInfo.Keys = map[string][]string{}
+	}
+	runInfo.Keys[projectName] = ids
+	known := map[string]bool{}
+	for _, id := range previous {
+		known[id] = true
+	}
+	detectMovedKeys(projectName, previous, ids, known, previousKeys)
+	if !ok {
+		return
+	}
+	digest := &ProjectDigest{Project: projectName, Keys: []string{}}
+	for _, id := range ids {
+		if !known[id] {
+			digest.Keys = append(digest.Keys, id)
+			digest.Words += len(strings.Fields(sourceText(id)))
+			if refs := v.Refs(id); len(refs) > 0 {
+				if digest.Refs == nil {
+					digest.Refs = map[string][]string{}
+				}
+				digest.Refs[id] = refs
+			}
+		}
+	}
+	if len(digest.Keys) > 0 {
+		report.AddDigest(digest)
+	}
+}
+
+func (d *ProjectDigest) String() string {
+	return fmt.Sprintf("%s: %d new keys, ~%d words", d.Project, len(d.Keys), d.Words)
+}
+
+func digestText(digests []*ProjectDigest) string {
+	sort.Slice(digests, func(i, j int) bool { return digests[i].Project < digests[j].Project })
+	buf := bytes.NewBuffer(nil)
+	for _, d := range digests {
+		fmt.Fprintln(buf, d)
+		for _, id := range d.Keys {
+			fmt.Fprintln(buf, "  ", id)
+		}
+		fmt.Fprintln(buf)
+	}
+	return buf.String()
+}
+
+// sendDigest delivers keys awaiting translation to configured destinations, failures are only logged.
+func sendDigest(cfg *DigestConfig, digests []*ProjectDigest) {
+	if cfg == nil || len(digests) == 0 {
+		return
+	}
+	if cfg.Webhook != "" {
+		if err := postDigest(cfg.Webhook, digests); err != nil {
+			log.Println("WARNING! Unable to post digest of new keys", err)
+		}
+	}
+	if cfg.Smtp != nil {
+		if err := mailDigest(cfg.Smtp, digests); err != nil {
+			log.Println("WARNING! Unable to mail digest of new keys", err)
+		}
+	}
+}
+
+func postDigest(url string, digests []*ProjectDigest) error {
+	encoded, err := json.Marshal(map[string]interface{}{
+		"time":     time.Now().UTC(),
+		"projects": digests,
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := http.Post(url, "application/json", bytes.NewReader(encoded))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("Digest webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+func mailDigest(cfg *SmtpConfig, digests []*ProjectDigest) error {
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host := strings.Split(cfg.Addr, ":")[0]
+		auth = smtp.PlainAuth("", cfg.Username, os.Getenv(SMTP_PASSWORD_ENV), host)
+	}
+	msg := bytes.NewBuffer(nil)
+	fmt.Fprintf(msg, "From: %s\r\n", cfg.From)
+	fmt.Fprintf(msg, "To: %s\r\n", strings.Join(cfg.To, ", "))
+	fmt.Fprintf(msg, "Subject: New strings awaiting translation %s\r\n", time.Now().Format(CONFIG_DATE_FORMAT))
+	fmt.Fprintf(msg, "Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	msg.WriteString(strings.Replace(digestText(digests), "\n", "\r\n", -1))
+	return smtp.SendMail(cfg.Addr, auth, cfg.From, cfg.To, msg.Bytes())
+}
@@ -0,0 +1,54 @@
+package i18n_gen
+
+import (
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// DIRECTIVE_PREFIX starts directives like "//i18n:table", unlike "// i18n: text" descriptions there is no space after colon.
+const DIRECTIVE_PREFIX = "i18n:"
+
+// fileDirectives maps line numbers to directives of the comment block right above the line.
+type fileDirectives map[int]map[string]string
+
+func parseDirectives(fset *token.FileSet, file *ast.File) fileDirectives {
+	d := fileDirectives{}
+	for _, group := range file.Comments {
+		line := fset.Position(group.End()).Line + 1
+		for _, c := range group.List {
+			name, args, ok := parseDirective(c.Text)
+			if !ok {
+				continue
+			}
+			if d[line] == nil {
+				d[line] = map[string]string{}
+			}
+			d[line][name] = args
+		}
+	}
+	return d
+}
+
+// parseDirective splits "//i18n:name args" comment into name and args.
+func parseDirective(comment string) (name, args string, ok bool) {
+	text := strings.TrimSpace(strings.TrimPrefix(comment, "//"))
+	if !strings.HasPrefix(text, DIRECTIVE_PREFIX) {
+		return "", "", false
+	}
+	text = text[len(DIRECTIVE_PREFIX):]
+	if text == "" || text[0] == ' ' {
+		return "", "", false
+	}
+	fields := strings.SplitN(text, " ", 2)
+	if len(fields) == 2 {
+		args = strings.TrimSpace(fields[1])
+	}
+	return fields[0], args, true
+}
+
+// find returns arguments of the directive placed above the node.
+func (d fileDirectives) find(fset *token.FileSet, node ast.Node, name string) (string, bool) {
+	args, ok := d[fset.Position(node.Pos()).Line][name]
+	return args, ok
+}
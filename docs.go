@@ -0,0 +1,228 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html/template"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	DOCS_FORMAT_MARKDOWN = "md"
+	DOCS_FORMAT_HTML     = "html"
+)
+
+var (
+	docsPath     string
+	docsFormat   string
+	docsExamples string
+)
+
+// KeyDoc describes a key of the catalog.
+type KeyDoc struct {
+	ID           string
+	Source       string
+	Description  string
+	Placeholders []string
+	Locations    []string
+	// Examples are translations by locale.
+	Examples []*KeyExample
+}
+
+type KeyExample struct {
+	Locale string
+	Text   string
+}
+
+var docsCommand = &command{
+	name:        "docs",
+	description: "write catalog of keys of -project with source text, description, placeholders, locations and translations",
+	setFlags: func(fs *flag.FlagSet) {
+		setCommonFlags(fs)
+		fs.StringVar(&docsPath, "out", "i18n_keys.md", "file to write the catalog to")
+		fs.StringVar(&docsFormat, "format", DOCS_FORMAT_MARKDOWN, "catalog format: "+DOCS_FORMAT_MARKDOWN+" or "+DOCS_FORMAT_HTML)
+		fs.StringVar(&docsExamples, "examples", "", "comma separated locales of translation examples, all downloaded locales if empty")
+	},
+	run: runDocs,
+}
+
+func runDocs(fs *flag.FlagSet) {
+	validateCommonFlags()
+	if docsFormat != DOCS_FORMAT_MARKDOWN && docsFormat != DOCS_FORMAT_HTML {
+		log.Fatalf("Unknown -format %s, expected %s or %s\n", docsFormat, DOCS_FORMAT_MARKDOWN, DOCS_FORMAT_HTML)
+	}
+	if err := loadKeyMapping(config.KeyMapping, basepath); err != nil {
+		log.Fatalln(err)
+	}
+	worker := connect()
+	descriptions, err := worker.KeyDescriptions(&i18nGenContext{}, phraseappProjects[defaultProject], defaultProject)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if err := extractSources(basepath); err != nil {
+		log.Fatalln(err)
+	}
+
+	docs := keyDocs(defaultProject, v, descriptions, docExamples(defaultProject))
+	var out []byte
+	if docsFormat == DOCS_FORMAT_HTML {
+		out, err = htmlDocs(defaultProject, docs)
+	} else {
+		out = markdownDocs(defaultProject, docs)
+	}
+	if err != nil {
+		log.Fatalln("Unable to render docs", err)
+	}
+	if err := writeOutputFile(docsPath, out); err != nil {
+		log.Fatalln("Unable to write docs", docsPath, err)
+	}
+	log.Println(len(docs), "keys were documented in", docsPath)
+}
+
+// docExamples returns downloaded translations of the project by locale and id.
+func docExamples(projectName string) map[string]map[string]string {
+	wanted := map[string]bool{}
+	for _, l := range strings.Split(docsExamples, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			wanted[l] = true
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", projectName, err)
+	}
+	examples := map[string]map[string]string{}
+	for _, path := range files {
+		localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+		if len(wanted) > 0 && !wanted[localeName] {
+			continue
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Println("WARNING! Unable to read locale file", path, err)
+			continue
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			log.Println("WARNING! Unable to parse locale file", path, err)
+			continue
+		}
+		examples[localeName] = map[string]string{}
+		for _, t := range translations {
+			text := t.Text
+			if t.IsPlural() {
+				text = t.Plural[CODEGEN_PLURAL_FORM]
+			}
+			if text != "" {
+				examples[localeName][t.ID] = text
+			}
+		}
+	}
+	return examples
+}
+
+func keyDocs(projectName string, v *FuncVisitor, descriptions map[string]string, examples map[string]map[string]string) []*KeyDoc {
+	ids := v.Ids()
+	sources := make([]*Translation, 0, len(ids))
+	for _, id := range ids {
+		sources = append(sources, &Translation{ID: sourceText(id)})
+	}
+	placeholders := projectPlaceholders(projectName, sources)
+	locales := make([]string, 0, len(examples))
+	for l := range examples {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+
+	docs := make([]*KeyDoc, 0, len(ids))
+	for _, id := range ids {
+		d := &KeyDoc{ID: id, Source: sourceText(id), Description: stripPlaceholderMetadata(descriptions[id])}
+		d.Placeholders = placeholders.FindAllString(d.Source, -1)
+		for _, location := range v.Locations(id) {
+			if rel, err := filepath.Rel(basepath, location); err == nil {
+				location = filepath.ToSlash(rel)
+			}
+			d.Locations = append(d.Locations, location)
+		}
+		sort.Strings(d.Locations)
+		for _, l := range locales {
+			if text, ok := examples[l][id]; ok {
+				d.Examples = append(d.Examples, &KeyExample{Locale: l, Text: text})
+			}
+		}
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+func markdownDocs(projectName string, docs []*KeyDoc) []byte {
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "# Keys of %s\n\n", projectName)
+	for _, d := range docs {
+		fmt.Fprintf(buf, "## %s\n\n", markdownEscape(d.ID))
+		if d.Source != d.ID {
+			fmt.Fprintf(buf, "Source: %s\n\n", markdownEscape(d.Source))
+		}
+		if d.Description != "" {
+			fmt.Fprintf(buf, "%s\n\n", markdownEscape(d.Description))
+		}
+		if len(d.Placeholders) > 0 {
+			fmt.Fprintf(buf, "Placeholders: `%s`\n\n", strings.Join(d.Placeholders, "`, `"))
+		}
+		if len(d.Locations) > 0 {
+			fmt.Fprintf(buf, "Used in: %s\n\n", strings.Join(d.Locations, ", "))
+		}
+		if len(d.Examples) > 0 {
+			fmt.Fprintln(buf, "| Locale | Translation |")
+			fmt.Fprintln(buf, "|---|---|")
+			for _, e := range d.Examples {
+				fmt.Fprintf(buf, "| %s | %s |\n", e.Locale, strings.Replace(markdownEscape(e.Text), "|", `\|`, -1))
+			}
+			fmt.Fprintln(buf)
+		}
+	}
+	return buf.Bytes()
+}
+
+// markdownEscape keeps text on a single line and its markup from being rendered.
+func markdownEscape(s string) string {
+	s = strings.Replace(s, "\n", " ", -1)
+	for _, c := range []string{`\`, "`", "*", "_", "#", "<", "["} {
+		s = strings.Replace(s, c, `\`+c, -1)
+	}
+	return s
+}
+
+var htmlDocsTemplate = template.Must(template.New("docs").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Keys of {{.Project}}</title></head>
+<body>
+<h1>Keys of {{.Project}}</h1>
+{{range .Keys}}<section id="{{.ID}}">
+<h2>{{.ID}}</h2>
+{{if ne .Source .ID}}<p>Source: {{.Source}}</p>
+{{end}}{{if .Description}}<p>{{.Description}}</p>
+{{end}}{{if .Placeholders}}<p>Placeholders: {{range $i, $p := .Placeholders}}{{if $i}}, {{end}}<code>{{$p}}</code>{{end}}</p>
+{{end}}{{if .Locations}}<p>Used in: {{range $i, $l := .Locations}}{{if $i}}, {{end}}{{$l}}{{end}}</p>
+{{end}}{{if .Examples}}<table>
+<tr><th>Locale</th><th>Translation</th></tr>
+{{range .Examples}}<tr><td>{{.Locale}}</td><td>{{.Text}}</td></tr>
+{{end}}</table>
+{{end}}</section>
+{{end}}</body>
+</html>
+`))
+
+func htmlDocs(projectName string, docs []*KeyDoc) ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+	err := htmlDocsTemplate.Execute(buf, struct {
+		Project string
+		Keys    []*KeyDoc
+	}{projectName, docs})
+	return buf.Bytes(), err
+}
@@ -0,0 +1,63 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// SUBSETS_FOLDER in a project folder keeps files of download subsets named <subset>.<locale>.json.
+const SUBSETS_FOLDER = "subsets"
+
+func validateSubsets(download map[string]*DownloadConfig) error {
+	for project, d := range download {
+		if d == nil {
+			continue
+		}
+		for name, tag := range d.Subsets {
+			if name == "" || strings.ContainsAny(name, `/\.`) {
+				return fmt.Errorf("Invalid name %q of download subset of project %s", name, project)
+			}
+			if tag == "" {
+				return fmt.Errorf("Download subset %s of project %s has no tag", name, project)
+			}
+		}
+	}
+	return nil
+}
+
+func getSubsetFileName(projectName, subset, localeName string) string {
+	return filepath.Join(getLocalizationFolderName(), projectName, SUBSETS_FOLDER, subset+"."+runtimeLocale(localeName)+".json")
+}
+
+func (c *i18nGenContext) DownloadSubsets(projectName string) map[string]string {
+	if d := config.downloadConfig(projectName); d != nil {
+		return d.Subsets
+	}
+	return nil
+}
+
+func (c *i18nGenContext) OnSubsetDownload(projectName, localeName, subset string, data []byte) {
+	data, _, err := sanitizePayload(data)
+	if err == nil {
+		data, err = normalizeEmbeddedLocale(data, localeName)
+	}
+	if err == nil {
+		// issues of subset keys are reported with the locale file, the subset is checked to quarantine them only
+		issues, quarantined := len(report.Issues), len(report.Quarantined)
+		_, data, err = c.processPayload(projectName, localeName, data, true)
+		report.Issues, report.Quarantined = report.Issues[:issues], report.Quarantined[:quarantined]
+	}
+	if err != nil {
+		c.ErrorHandler(fmt.Errorf("Unable to read subset %s of project %s %s, %v", subset, projectName, localeName, err))
+		return
+	}
+	path := getSubsetFileName(projectName, subset, localeName)
+	if err := mkdirOutput(filepath.Dir(path)); err != nil {
+		fail(fmt.Errorf("Unable to create subsets folder for project %s, %v", projectName, err))
+	}
+	if err := writeOutputFile(path, data); err != nil {
+		fail(fmt.Errorf("Unable to create subset file for project %s %s %s, %v", projectName, subset, localeName, err))
+	}
+	logLocale(projectName, localeName, "Downloaded subset", subset, projectName, localeName)
+}
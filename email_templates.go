@@ -0,0 +1,290 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	EMAIL_NAMESPACE = "email"
+	// FRONT_MATTER_DELIMITER opens and closes yaml front-matter at the start of a template.
+	FRONT_MATTER_DELIMITER = "---"
+)
+
+// EmailsConfig localizes email templates, MJML or HTML files with yaml front-matter.
+type EmailsConfig struct {
+	// Dir is the folder of source templates, relative to -path.
+	Dir string `json:"dir"`
+	// Out is the folder localized templates are written to, a folder per locale.
+	Out string `json:"out"`
+	// Fields are translated front-matter fields, subject and preheader by default.
+	Fields []string `json:"fields"`
+	// Namespace prefixes keys of templates, email by default.
+	Namespace string `json:"namespace"`
+}
+
+var (
+	emailTemplateExts = []string{".mjml", ".html", ".htm"}
+	// emailStringRegexp matches translated body strings, <i18n key="greeting">Hello, {{.Name}}!</i18n>.
+	emailStringRegexp = regexp.MustCompile(`(?s)<i18n\s+key="([\w.-]+)"\s*>(.*?)</i18n>`)
+	frontMatterField  = regexp.MustCompile(`^([\w-]+):`)
+)
+
+type emailTemplate struct {
+	// Name is the slash separated path of the template relative to the templates folder.
+	Name string
+	// Lines are lines of front-matter without delimiters, nil if the template has none.
+	Lines []string
+	Body  []byte
+	// Fields are line indexes of translated front-matter fields.
+	Fields map[string]int
+	// Strings are keys of the template with source texts.
+	Strings []*Translation
+}
+
+func (e *EmailsConfig) validate() error {
+	if e == nil {
+		return nil
+	}
+	if e.Dir == "" || e.Out == "" {
+		return fmt.Errorf("Email templates need both dir and out folders")
+	}
+	return nil
+}
+
+func (e *EmailsConfig) fields() []string {
+	if len(e.Fields) == 0 {
+		return []string{"subject", "preheader"}
+	}
+	return e.Fields
+}
+
+func (e *EmailsConfig) namespace() string {
+	if e.Namespace == "" {
+		return EMAIL_NAMESPACE
+	}
+	return e.Namespace
+}
+
+// emailKey returns key of the string of the template, e.g. email.orders.confirmed.subject
+// for subject of orders/confirmed.mjml.
+func emailKey(templateName, name string) string {
+	base := strings.TrimSuffix(templateName, path.Ext(templateName))
+	return config.Emails.namespace() + "." + strings.Replace(base, "/", ".", -1) + "." + name
+}
+
+// extractEmailTemplates adds strings of email templates to extracted keys like seeds,
+// ids defined elsewhere are reported and keep their definition.
+func extractEmailTemplates(v *FuncVisitor, basepath string) error {
+	templates, err := loadEmailTemplates(basepath)
+	if err != nil {
+		return err
+	}
+	for _, tmpl := range templates {
+		for _, t := range tmpl.Strings {
+			_, inCode := v.funcNames[t.ID]
+			if _, inSeeds := v.seeds[t.ID]; inCode || inSeeds {
+				log.Printf("WARNING! Key %s from email template %s is already defined\n", t.ID, tmpl.Name)
+				report.AddSeedCollision(t.ID + ": " + tmpl.Name)
+				continue
+			}
+			v.seeds[t.ID] = t
+		}
+	}
+	return nil
+}
+
+// loadEmailTemplates parses templates of config.Emails.Dir sorted by name.
+func loadEmailTemplates(basepath string) ([]*emailTemplate, error) {
+	names, err := emailTemplateNames(basepath, config.Emails.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to list email templates, %v", err)
+	}
+	templates := make([]*emailTemplate, 0, len(names))
+	for _, name := range names {
+		data, err := readSourceFile(basepath, path.Join(filepath.ToSlash(config.Emails.Dir), name))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to read email template %s, %v", name, err)
+		}
+		tmpl, err := parseEmailTemplate(name, data)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to parse email template %s, %v", name, err)
+		}
+		templates = append(templates, tmpl)
+	}
+	return templates, nil
+}
+
+// emailTemplateNames returns slash separated paths of templates relative to dir.
+func emailTemplateNames(basepath, dir string) ([]string, error) {
+	names := []string{}
+	if sourceFiles != nil {
+		prefix := path.Clean(filepath.ToSlash(dir)) + "/"
+		for name := range sourceFiles {
+			if strings.HasPrefix(name, prefix) && isEmailTemplate(name) {
+				names = append(names, strings.TrimPrefix(name, prefix))
+			}
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	root := dir
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(basepath, dir)
+	}
+	err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() || !isEmailTemplate(p) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(rel))
+		return nil
+	})
+	return names, err
+}
+
+func isEmailTemplate(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	for _, e := range emailTemplateExts {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// parseEmailTemplate finds translated front-matter fields, which should be single line strings, and body strings.
+func parseEmailTemplate(name string, data []byte) (*emailTemplate, error) {
+	tmpl := &emailTemplate{Name: name, Body: data, Fields: map[string]int{}}
+	text := strings.Replace(string(data), "\r\n", "\n", -1)
+	if strings.HasPrefix(text, FRONT_MATTER_DELIMITER+"\n") {
+		rest := text[len(FRONT_MATTER_DELIMITER)+1:]
+		// the closing delimiter may follow the opening one right away
+		end := strings.Index("\n"+rest, "\n"+FRONT_MATTER_DELIMITER+"\n")
+		if end < 0 {
+			return nil, fmt.Errorf("front-matter is not closed with %s", FRONT_MATTER_DELIMITER)
+		}
+		tmpl.Lines = []string{}
+		if end > 0 {
+			tmpl.Lines = strings.Split(rest[:end-1], "\n")
+		}
+		tmpl.Body = []byte(rest[end+len(FRONT_MATTER_DELIMITER)+1:])
+	}
+
+	translated := map[string]bool{}
+	for _, f := range config.Emails.fields() {
+		translated[f] = true
+	}
+	for i, line := range tmpl.Lines {
+		m := frontMatterField.FindStringSubmatch(line)
+		if m == nil || !translated[m[1]] {
+			continue
+		}
+		value := map[string]string{}
+		if err := yaml.Unmarshal([]byte(line), &value); err != nil || value[m[1]] == "" {
+			return nil, fmt.Errorf("front-matter field %s should be a single line string", m[1])
+		}
+		tmpl.Fields[m[1]] = i
+		tmpl.Strings = append(tmpl.Strings, &Translation{ID: emailKey(name, m[1]), Text: value[m[1]]})
+	}
+	for _, m := range emailStringRegexp.FindAllSubmatch(tmpl.Body, -1) {
+		key := string(m[1])
+		if _, ok := tmpl.Fields[key]; ok {
+			return nil, fmt.Errorf("body string %s has the name of a front-matter field", key)
+		}
+		tmpl.Strings = append(tmpl.Strings, &Translation{ID: emailKey(name, key), Text: strings.TrimSpace(string(m[2]))})
+	}
+	return tmpl, nil
+}
+
+// render returns the template with strings replaced by translations, source texts are kept for missing ones.
+func (tmpl *emailTemplate) render(translations map[string]string) []byte {
+	buf := bytes.NewBuffer(nil)
+	if tmpl.Lines != nil {
+		lines := append([]string{}, tmpl.Lines...)
+		for field, i := range tmpl.Fields {
+			if text, ok := translations[emailKey(tmpl.Name, field)]; ok {
+				lines[i] = field + ": " + strconv.Quote(text)
+			}
+		}
+		fmt.Fprintln(buf, FRONT_MATTER_DELIMITER)
+		for _, line := range lines {
+			fmt.Fprintln(buf, line)
+		}
+		fmt.Fprintln(buf, FRONT_MATTER_DELIMITER)
+	}
+	buf.Write(emailStringRegexp.ReplaceAllFunc(tmpl.Body, func(s []byte) []byte {
+		m := emailStringRegexp.FindSubmatch(s)
+		if text, ok := translations[emailKey(tmpl.Name, string(m[1]))]; ok {
+			return []byte(text)
+		}
+		return bytes.TrimSpace(m[2])
+	}))
+	return buf.Bytes()
+}
+
+// writeEmailTemplates writes templates localized with every downloaded locale of the project
+// to a folder of the locale in config.Emails.Out.
+func writeEmailTemplates(projectName string) {
+	templates, err := loadEmailTemplates(basepath)
+	if err != nil {
+		log.Println("WARNING!", err)
+		return
+	}
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", projectName, err)
+		return
+	}
+	for _, localePath := range files {
+		localeName := strings.TrimSuffix(filepath.Base(localePath), ".json")
+		if err := writeLocaleEmailTemplates(localeName, localePath, templates); err != nil {
+			log.Println("WARNING! Unable to localize email templates", projectName, localeName, err)
+		}
+	}
+}
+
+func writeLocaleEmailTemplates(localeName, localePath string, templates []*emailTemplate) error {
+	data, err := ioutil.ReadFile(localePath)
+	if err != nil {
+		return err
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		return err
+	}
+	texts := map[string]string{}
+	for _, t := range translations {
+		if !t.IsPlural() && t.Text != "" {
+			texts[t.ID] = t.Text
+		}
+	}
+	for _, tmpl := range templates {
+		out := filepath.Join(config.Emails.Out, localeName, filepath.FromSlash(tmpl.Name))
+		if err := mkdirOutput(filepath.Dir(out)); err != nil {
+			return err
+		}
+		if err := writeOutputFile(out, tmpl.render(texts)); err != nil {
+			return err
+		}
+	}
+	log.Printf("%d email templates were localized to %s\n", len(templates), filepath.Join(config.Emails.Out, localeName))
+	return nil
+}
@@ -0,0 +1,167 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// QA_RULE_ENTITIES checks entities of translations of projects with unescape_entities against their source.
+const QA_RULE_ENTITIES = "entities"
+
+var entityPattern = regexp.MustCompile(`&(?:#[0-9]+|#[xX][0-9a-fA-F]+|[A-Za-z][A-Za-z0-9]*);`)
+
+// isUnescapingEntities tells whether source strings of the project are uploaded with html entities unescaped,
+// so translators see &amp; as &, and downloaded translations get the entities of their source back.
+func isUnescapingEntities(projectName string) bool {
+	if unescape, ok := config.UnescapeEntities[projectName]; ok {
+		return unescape
+	}
+	return config.UnescapeEntities["*"]
+}
+
+// unescapeEntities decodes well-formed entities only, text like "R&D" or "&amp" stays as it is.
+func unescapeEntities(text string) string {
+	return entityPattern.ReplaceAllStringFunc(text, html.UnescapeString)
+}
+
+// unescapingSource returns upload source of the project, with entities of texts unescaped if it is enabled.
+func unescapingSource(projectName string, source func(id string) *Translation) func(id string) *Translation {
+	if !isUnescapingEntities(projectName) {
+		return source
+	}
+	return func(id string) *Translation {
+		t := source(id)
+		unescaped := &Translation{ID: t.ID, Text: unescapeEntities(t.Text)}
+		if t.IsPlural() {
+			unescaped.Plural = map[string]string{}
+			for form, text := range t.Plural {
+				unescaped.Plural[form] = unescapeEntities(text)
+			}
+		}
+		return unescaped
+	}
+}
+
+// sourceEntities returns entities of the source text of id keyed by the character they stand for,
+// keys of hash ids and mapped ids get source text from extracted sources, other keys are their source text.
+func sourceEntities(id string) map[string]string {
+	texts := []string{id}
+	if v != nil {
+		if t := v.translation(id); t != nil {
+			texts = t.Texts()
+		}
+	}
+	entities := map[string]string{}
+	for _, text := range texts {
+		for _, entity := range entityPattern.FindAllString(text, -1) {
+			if char := html.UnescapeString(entity); char != entity {
+				entities[char] = entity
+			}
+		}
+	}
+	return entities
+}
+
+// reescapeEntities replaces characters of downloaded translations with the entities their source has,
+// other spellings of these entities typed by translators, like &#38;, are normalized. Other entities are kept
+// for checkEntities. It reports whether any translation changed.
+func reescapeEntities(translations []*Translation) bool {
+	changed := false
+	for _, t := range translations {
+		entities := sourceEntities(t.ID)
+		if len(entities) == 0 {
+			continue
+		}
+		pairs := make([]string, 0, 2*len(entities))
+		for char, entity := range entities {
+			pairs = append(pairs, char, entity)
+		}
+		replacer := strings.NewReplacer(pairs...)
+		// text between entities is escaped, entities of the source are normalized and others kept
+		escape := func(text string) string {
+			escaped := &strings.Builder{}
+			last := 0
+			for _, loc := range entityPattern.FindAllStringIndex(text, -1) {
+				escaped.WriteString(replacer.Replace(text[last:loc[0]]))
+				entity := text[loc[0]:loc[1]]
+				if normalized, ok := entities[html.UnescapeString(entity)]; ok {
+					entity = normalized
+				}
+				escaped.WriteString(entity)
+				last = loc[1]
+			}
+			escaped.WriteString(replacer.Replace(text[last:]))
+			changed = changed || escaped.String() != text
+			return escaped.String()
+		}
+		t.Text = escape(t.Text)
+		for form, text := range t.Plural {
+			t.Plural[form] = escape(text)
+		}
+	}
+	return changed
+}
+
+// checkEntities reports translations whose entities differ from ones of their source, issues of error severity
+// are returned.
+func checkEntities(projectName, localeName string, translations []*Translation) []*Issue {
+	severity := config.Qa.severity(QA_RULE_ENTITIES)
+	if severity == QA_SEVERITY_OFF {
+		return nil
+	}
+	failed := []*Issue{}
+	for _, t := range translations {
+		expected := sourceEntities(t.ID)
+		for _, text := range t.Texts() {
+			if text == "" {
+				continue
+			}
+			message := entitiesMismatch(expected, text)
+			if message == "" {
+				continue
+			}
+			issue := &Issue{Project: projectName, Locale: localeName, Key: t.ID, Rule: QA_RULE_ENTITIES, Message: message}
+			if config.Qa.isSuppressed(issue) {
+				break
+			}
+			report.AddIssue(issue)
+			if severity == QA_SEVERITY_ERROR {
+				failed = append(failed, issue)
+			}
+			break
+		}
+	}
+	return failed
+}
+
+func entitiesMismatch(expected map[string]string, text string) string {
+	found := map[string]bool{}
+	unexpected := []string{}
+	for _, entity := range entityPattern.FindAllString(text, -1) {
+		if char := html.UnescapeString(entity); expected[char] == entity {
+			found[entity] = true
+		} else if char != entity {
+			unexpected = appendUnique(unexpected, entity)
+		}
+	}
+	missing := []string{}
+	for _, entity := range expected {
+		if !found[entity] {
+			missing = append(missing, entity)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	switch {
+	case len(missing) > 0 && len(unexpected) > 0:
+		return fmt.Sprintf("entities %s are missing, %s are unexpected", strings.Join(missing, " "), strings.Join(unexpected, " "))
+	case len(missing) > 0:
+		return fmt.Sprintf("entities %s are missing", strings.Join(missing, " "))
+	case len(unexpected) > 0:
+		return fmt.Sprintf("entities %s are unexpected", strings.Join(unexpected, " "))
+	}
+	return ""
+}
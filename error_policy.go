@@ -0,0 +1,42 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+)
+
+const (
+	ON_ERROR_FAIL     = "fail"
+	ON_ERROR_CONTINUE = "continue"
+	ON_ERROR_RETRY    = "retry"
+)
+
+var (
+	onError      string
+	errorRetries int
+)
+
+func validateErrorPolicy() error {
+	switch onError {
+	case ON_ERROR_FAIL, ON_ERROR_CONTINUE, ON_ERROR_RETRY:
+		return nil
+	}
+	return fmt.Errorf("Unknown -on-error policy %s, expected %s, %s or %s", onError, ON_ERROR_FAIL, ON_ERROR_CONTINUE, ON_ERROR_RETRY)
+}
+
+// ErrorHandler exits on the first error unless policy is to continue or retry,
+// then errors are collected for the summary and the exit code.
+func (c *i18nGenContext) ErrorHandler(err error) {
+	if onError != ON_ERROR_CONTINUE && onError != ON_ERROR_RETRY {
+		fail(err)
+	}
+	log.Println("ERROR!", err)
+	report.AddError(err)
+}
+
+func (c *i18nGenContext) Retries() int {
+	if onError == ON_ERROR_RETRY {
+		return errorRetries
+	}
+	return 0
+}
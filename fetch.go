@@ -0,0 +1,122 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// FetchTranslations returns translations of the locale of the project by key, plural translations in their
+// other form. The token and phraseapp id of the project are set by WithToken and WithProject.
+// Locale files are cached with their etag in the user cache dir, so unchanged locales aren't downloaded again.
+// FetchTranslations is not safe for concurrent use, neither with Sync.
+func FetchTranslations(ctx context.Context, project, locale string, opts ...Option) (map[string]string, error) {
+	syncCommand.flagSet().Parse(nil)
+	for _, opt := range opts {
+		opt()
+	}
+	projectId, ok := phraseappProjects[project]
+	if !ok {
+		return nil, fmt.Errorf("Please, specify phraseapp project id of %s", project)
+	}
+	if phraseappToken == "" {
+		return nil, fmt.Errorf("Please, specify phraseapp token")
+	}
+	config = Config{}
+	report = RunReport{}
+	if err := initStateKey(); err != nil {
+		return nil, err
+	}
+	syncCtx = ctx
+	defer func() { syncCtx = nil }()
+
+	worker, err := newWorker()
+	if err != nil {
+		return nil, err
+	}
+	localCtx := &i18nGenContext{}
+	locales, err := worker.getLocales(localCtx, projectId, project)
+	if err != nil {
+		return nil, err
+	}
+	localeId := ""
+	for _, l := range locales {
+		if l.Name == locale {
+			localeId = l.ID
+		}
+	}
+	if localeId == "" {
+		return nil, fmt.Errorf("Project %s has no locale %s", project, locale)
+	}
+
+	cacheFile := fetchCacheFileName(projectId, locale)
+	// a cache which can't be opened, like one encrypted with another key, is downloaded again
+	etag, cached := readFetchCache(cacheFile)
+	data, newEtag, err := worker.downloadLocaleImpl(localCtx, projectId, project, localeId, locale, etag, "")
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		data = cached
+	} else if err := writeFetchCache(cacheFile, data, newEtag); err != nil {
+		return nil, fmt.Errorf("Unable to cache locale %s of project %s, %v", locale, project, err)
+	}
+
+	data, _, err = sanitizePayload(data)
+	if err != nil {
+		return nil, fmt.Errorf("Locale %s of project %s is rejected, %v", locale, project, err)
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to unmarshal locale %s of project %s, %v", locale, project, err)
+	}
+	texts := make(map[string]string, len(translations))
+	for _, t := range translations {
+		if t.IsPlural() {
+			texts[t.ID] = t.Plural[CODEGEN_PLURAL_FORM]
+			continue
+		}
+		texts[t.ID] = t.Text
+	}
+	return texts, nil
+}
+
+func fetchCacheFileName(projectId, locale string) string {
+	return filepath.Join(filepath.Dir(getRunInfoFileName()), "fetch", projectId, locale+".json")
+}
+
+// readFetchCache returns the etag and the payload of the cache file, both are empty if it can't be read.
+func readFetchCache(path string) (string, []byte) {
+	data, err := ioutil.ReadFile(path)
+	if err == nil {
+		data, err = openState(data)
+	}
+	if err != nil {
+		return "", nil
+	}
+	i := bytes.IndexByte(data, '\n')
+	if i < 0 {
+		return "", nil
+	}
+	return string(data[:i]), data[i+1:]
+}
+
+// writeFetchCache keeps the etag in the first line of the payload, so it's sealed with the payload.
+func writeFetchCache(path string, data []byte, etag string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	sealed, err := sealState(append([]byte(etag+"\n"), data...))
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path, sealed, 0600); err != nil {
+		return err
+	}
+	// plain etag files were written next to the payload by earlier versions
+	os.Remove(path + ".etag")
+	return nil
+}
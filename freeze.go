@@ -0,0 +1,42 @@
+package i18n_gen
+
+import (
+	"time"
+)
+
+func (w *FreezeWindow) IsActive(now time.Time, projectName string) bool {
+	end := w.To.Time
+	if w.To.IsDate {
+		end = end.AddDate(0, 0, 1)
+	}
+	if now.Before(w.From.Time) || !now.Before(end) {
+		return false
+	}
+	if len(w.Projects) == 0 {
+		return true
+	}
+	for _, p := range w.Projects {
+		if p == projectName {
+			return true
+		}
+	}
+	return false
+}
+
+func (w *FreezeWindow) Describe() string {
+	s := "translation freeze " + w.From.Format(CONFIG_DATE_FORMAT) + " - " + w.To.Format(CONFIG_DATE_FORMAT)
+	if w.Message != "" {
+		s += " (" + w.Message + ")"
+	}
+	return s
+}
+
+// activeFreeze returns the first freeze window in effect for the project.
+func activeFreeze(now time.Time, projectName string) *FreezeWindow {
+	for _, w := range config.Freeze {
+		if w.IsActive(now, projectName) {
+			return w
+		}
+	}
+	return nil
+}
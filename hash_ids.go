@@ -0,0 +1,208 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// HASH_IDS_VAR is the map of source texts to hashed ids in the -hash-ids file.
+const HASH_IDS_VAR = "HashIds"
+
+var (
+	// hashIdsPath is a go file of constants of hashed ids, keys are uploaded by hashed ids when set.
+	hashIdsPath    string
+	hashIdsPackage string
+	hashIdsLength  int
+	// hashedTexts are source texts of hashed ids.
+	hashedTexts map[string]string
+)
+
+func validateHashIds() error {
+	if hashIdsPath == "" {
+		return nil
+	}
+	if hashIdsLength < 4 || hashIdsLength > sha256.Size*2 {
+		return fmt.Errorf("Hashed id length should be between 4 and %d", sha256.Size*2)
+	}
+	if !token.IsIdentifier(hashIdsPackage) {
+		return fmt.Errorf("-hash-ids-package %q is not a valid package name", hashIdsPackage)
+	}
+	return nil
+}
+
+// applyHashIds renames extracted keys to ids derived from sha256 of their source text and writes
+// the lookup table. Ids of the previous table are kept, so ids don't change when a colliding text
+// is added, colliding texts get longer prefixes of their hashes.
+func applyHashIds(v *FuncVisitor) error {
+	hashedTexts = map[string]string{}
+	if hashIdsPath == "" {
+		return nil
+	}
+	previous, err := readHashIds(hashIdsPath)
+	if err != nil {
+		return fmt.Errorf("Unable to read hashed ids %s, %v", hashIdsPath, err)
+	}
+	texts := map[string]string{}
+	for id := range v.funcNames {
+		texts[sourceText(id)] = id
+	}
+	ids := assignHashIds(texts, previous)
+	mapping := map[string]string{}
+	for text, id := range texts {
+		mapping[id] = ids[text]
+		hashedTexts[ids[text]] = text
+	}
+	renameKeys(v, mapping)
+	if err := writeHashIds(hashIdsPath, ids); err != nil {
+		return fmt.Errorf("Unable to write hashed ids %s, %v", hashIdsPath, err)
+	}
+	return nil
+}
+
+func assignHashIds(texts, previous map[string]string) map[string]string {
+	ids := map[string]string{}
+	taken := map[string]bool{}
+	sorted := make([]string, 0, len(texts))
+	for text := range texts {
+		sorted = append(sorted, text)
+	}
+	sort.Strings(sorted)
+	for _, text := range sorted {
+		sum := textHash(text)
+		if id, ok := previous[text]; ok && len(id) >= hashIdsLength && strings.HasPrefix(sum, id) && !taken[id] {
+			ids[text], taken[id] = id, true
+		}
+	}
+	for _, text := range sorted {
+		if _, ok := ids[text]; ok {
+			continue
+		}
+		sum := textHash(text)
+		for n := hashIdsLength; n <= len(sum); n++ {
+			if !taken[sum[:n]] {
+				ids[text], taken[sum[:n]] = sum[:n], true
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func textHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// readHashIds returns source texts and ids of the HashIds map of the file, nothing if it doesn't exist.
+func readHashIds(path string) (map[string]string, error) {
+	ids := map[string]string{}
+	file, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if os.IsNotExist(err) {
+		return ids, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		spec, ok := node.(*ast.ValueSpec)
+		if !ok || len(spec.Names) != 1 || spec.Names[0].Name != HASH_IDS_VAR || len(spec.Values) != 1 {
+			return true
+		}
+		lit, ok := spec.Values[0].(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				continue
+			}
+			text, textErr := unquoteLit(kv.Key)
+			id, idErr := unquoteLit(kv.Value)
+			if textErr == nil && idErr == nil {
+				ids[text] = id
+			}
+		}
+		return false
+	})
+	return ids, nil
+}
+
+func unquoteLit(expr ast.Expr) (string, error) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", fmt.Errorf("not a string literal")
+	}
+	return strconv.Unquote(lit.Value)
+}
+
+func writeHashIds(path string, ids map[string]string) error {
+	src, err := format.Source(hashIdsCode(hashIdsPackage, ids))
+	if err != nil {
+		return err
+	}
+	return writeOutputFile(path, src)
+}
+
+func hashIdsCode(pkg string, ids map[string]string) []byte {
+	texts := make([]string, 0, len(ids))
+	for text := range ids {
+		texts = append(texts, text)
+	}
+	sort.Strings(texts)
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintln(buf, "// Code generated by i18n_gen. DO NOT EDIT.")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "package %s\n\n", pkg)
+	fmt.Fprintln(buf, "// Hashed ids of source texts.")
+	fmt.Fprintln(buf, "const (")
+	names := map[string]bool{HASH_IDS_VAR: true}
+	for _, text := range texts {
+		name := constantName(text)
+		if names[name] {
+			name += "_" + ids[text]
+		}
+		names[name] = true
+		fmt.Fprintf(buf, "// %s\n", strconv.Quote(text))
+		fmt.Fprintf(buf, "%s = %s\n", name, strconv.Quote(ids[text]))
+	}
+	fmt.Fprintln(buf, ")")
+	fmt.Fprintln(buf)
+	fmt.Fprintf(buf, "// %s maps source texts to hashed ids.\n", HASH_IDS_VAR)
+	fmt.Fprintf(buf, "var %s = map[string]string{\n", HASH_IDS_VAR)
+	for _, text := range texts {
+		fmt.Fprintf(buf, "%s: %s,\n", strconv.Quote(text), strconv.Quote(ids[text]))
+	}
+	fmt.Fprintln(buf, "}")
+	return buf.Bytes()
+}
+
+// constantName makes an exported identifier of up to 6 first words of the text.
+func constantName(text string) string {
+	words := strings.FieldsFunc(text, func(r rune) bool { return !unicode.IsLetter(r) && !unicode.IsDigit(r) })
+	if len(words) > 6 {
+		words = words[:6]
+	}
+	name := ""
+	for _, w := range words {
+		runes := []rune(strings.ToLower(w))
+		runes[0] = unicode.ToUpper(runes[0])
+		name += string(runes)
+	}
+	if !token.IsExported(name) {
+		name = "Key" + name
+	}
+	return name
+}
@@ -1,15 +1,21 @@
-package main
+package i18n_gen
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"flag"
+	"fmt"
 	"hash/crc32"
 	"io/ioutil"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,14 +24,23 @@ import (
 
 const (
 	INVALID_CRC32         = 0
+	INVALID_SHA256        = ""
 	LOCALIZED_DATA_FOLDER = "localized_data"
+	PROD_DATA_FOLDER      = "localized_data_prod"
 	GLOBAL_RUN_DELAY      = 2e9 // nanoseconds
 	BACKEND               = "Backend"
+	FALLBACK_LOCALE       = "en-US"
+
+	// LOCALIZED_DIR_MODE and LOCALIZED_FILE_MODE are defaults of output of the config.
+	LOCALIZED_DIR_MODE  os.FileMode = 0777
+	LOCALIZED_FILE_MODE os.FileMode = 0644
 )
 
 type (
 	CheckSum struct {
-		DataCrc32   uint32 `json:"crc32"`
+		// DataCrc32 is read from run info of older versions only and migrated to DataSha256.
+		DataCrc32   uint32 `json:"crc32,omitempty"`
+		DataSha256  string `json:"sha256"`
 		ETag        string `json:"etag"`
 		ProjectName string `json:"project"`
 		LocaleName  string `json:"locale"`
@@ -36,11 +51,23 @@ type (
 	RunInfo struct {
 		CheckSumList CheckSumList `json:"lst"`
 		LastRunTime  int64        `json:"last_run_time"`
+		Usage        UsageHistory `json:"usage"`
+		// Keys are ids extracted in the previous run by project.
+		Keys map[string][]string `json:"keys"`
+		// Seen are first and last extraction times of keys by project.
+		Seen map[string]map[string]*KeySeen `json:"seen"`
+		// Downloaded are last download times of locales keyed by "project:locale".
+		Downloaded map[string]time.Time `json:"downloaded"`
+		// Uploaded are hashes of translations uploaded with updates by "project:locale" and key.
+		Uploaded map[string]map[string]string `json:"uploaded,omitempty"`
 	}
 
 	i18nGenContext struct{}
 
 	projectIds map[string]string
+
+	// projectSwitches are booleans by project name, "*" applies to other projects: Backend=true,*=false.
+	projectSwitches map[string]bool
 )
 
 func (i *projectIds) String() string {
@@ -57,68 +84,328 @@ func (i *projectIds) Set(value string) error {
 	return nil
 }
 
+func (s *projectSwitches) String() string {
+	cont := []string{}
+	for k, v := range *s {
+		cont = append(cont, fmt.Sprintf("%s=%t", k, v))
+	}
+	sort.Strings(cont)
+	return strings.Join(cont, ",")
+}
+
+func (s *projectSwitches) Set(value string) error {
+	if *s == nil {
+		*s = projectSwitches{}
+	}
+	for _, pair := range strings.Split(value, ",") {
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("expected project=true or project=false, got %s", pair)
+		}
+		on, err := strconv.ParseBool(kv[1])
+		if err != nil {
+			return fmt.Errorf("expected project=true or project=false, got %s", pair)
+		}
+		(*s)[kv[0]] = on
+	}
+	return nil
+}
+
+func (s projectSwitches) lookup(projectName string) (bool, bool) {
+	if on, ok := s[projectName]; ok {
+		return on, true
+	}
+	on, ok := s["*"]
+	return on, ok
+}
+
 var (
-	ctx               *PhraseappWorkerContext
-	runInfo           RunInfo
-	basepath          string
-	defaultProject    string
-	defaultLocale     string
-	phraseappProjects projectIds
+	// updateTranslationProjects are projects of -update-translations.
+	updateTranslationProjects projectSwitches
+	ctx                       *PhraseappWorkerContext
+	runInfo                   RunInfo
+	basepath                  string
+	phraseappToken            string
+	defaultProject            string
+	defaultLocale             string
+	verbose                   bool
+	createLocale              bool
+	useStateKeyring           bool
+	perPage                   int
+	prodDownload              bool
+	phraseappProjects         projectIds
 )
 
-func main() {
+var syncCommand = &command{
+	name:        "sync",
+	description: "upload strings extracted from sources and download all locales (default command)",
+	setFlags:    setSyncFlags,
+	run:         runSync,
+}
+
+// setCommonFlags registers flags of commands working with sources and phraseapp projects.
+func setCommonFlags(fs *flag.FlagSet) {
 	phraseappProjects = projectIds{}
-	junolabPath := flag.String("path", "junolab.net", "path to micro-services")
-	phraseappToken := flag.String("token", "", "token for phraseapp")
-	defaultProject = *flag.String("project", BACKEND, "default project name")
-	defaultLocale = *flag.String("locale", "en-US", "default locale name")
-	flag.Var(&phraseappProjects, "project_id", "pair of project name and prhaseapp id, Backend:phraseapp_project_id")
+	fs.StringVar(&configPath, "config", "", "path to json config file")
+	fs.StringVar(&basepath, "path", "junolab.net", "path to micro-services, sync also reads a .tar.gz, .tar or .zip archive of them or - for a tar stream on stdin")
+	fs.StringVar(&phraseappToken, "token", "", "token for phraseapp")
+	fs.StringVar(&defaultProject, "project", BACKEND, "default project name")
+	fs.IntVar(&perPage, "per-page", 25, "page size of phraseapp list requests, up to 100")
+	fs.BoolVar(&verbose, "verbose", false, "list every repeated warning instead of counted summaries")
+	fs.BoolVar(&debugHttp, "debug-http", false, "log method, url, status, timing, etag and rate limits of phraseapp requests")
+	fs.StringVar(&userAgentSuffix, "user-agent-suffix", "", "suffix appended to User-Agent of phraseapp requests, e.g. ci-runner-3")
+	fs.StringVar(&requestSource, "request-source", "", "value of "+REQUEST_SOURCE_HEADER+" header of phraseapp requests, e.g. CI job url")
+	fs.StringVar(&debugHttpDumpDir, "debug-http-dump", "", "folder to dump -debug-http requests and responses to, credentials are masked")
+	fs.StringVar(&cacheUrl, "cache-url", "", "shared cache server of locale payloads, e.g. http://i18n-cache:8081, see cache-server command")
+	fs.DurationVar(&exportTimeout, "export-timeout", 5*time.Minute, "time to wait for locale exports phraseapp prepares asynchronously")
+	fs.Var(&phraseappProjects, "project_id", "pair of project name and prhaseapp id, Backend:phraseapp_project_id")
+}
 
-	flag.Parse()
+func setSyncFlags(fs *flag.FlagSet) {
+	setCommonFlags(fs)
+	fs.StringVar(&defaultLocale, "locale", "", "locale to upload source strings to, default locale of the phraseapp project if empty")
+	fs.BoolVar(&prodDownload, "prod", false, "also write reviewed translations only to "+PROD_DATA_FOLDER)
+	fs.StringVar(&statusPath, "status", "", "file to write per-locale completeness json to")
+	fs.StringVar(&badgesDir, "badges", "", "folder to write shields.io endpoint badges of locale completeness to")
+	fs.BoolVar(&quarantineFailing, "quarantine", false, "replace malformed translations and ones failing qa rules of error severity with source text instead of failing the run")
+	fs.IntVar(&unseenDays, "unseen-days", 0, "report keys not extracted from sources for that many days, e.g. 180")
+	fs.BoolVar(&ignoreSchedule, "ignore-schedule", false, "download all locales regardless of schedule of the config")
+	fs.StringVar(&changelogDir, "changelog", "", "folder to append per-locale changelogs of translations changed by downloads to")
+	fs.BoolVar(&strictExtract, "strict-extract", false, "fail the run on parse errors and invalid markers found by extraction")
+	fs.BoolVar(&splitUploads, "split-uploads", false, "upload keys of every service folder of -path separately, tagged "+SERVICE_TAG_PREFIX+"<folder>")
+	fs.BoolVar(&bootstrapMode, "bootstrap", false, "first run on a new machine: download locales in batches with pauses saving progress, locales downloaded by previous bootstrap runs are kept")
+	fs.IntVar(&bootstrapBatch, "bootstrap-batch", 5, "number of locales downloaded between pauses of -bootstrap")
+	fs.DurationVar(&bootstrapPause, "bootstrap-pause", 30*time.Second, "pause between batches of -bootstrap")
+	fs.StringVar(&hashIdsPath, "hash-ids", "", "go file to generate with constants of ids derived from sha256 of source texts, keys are uploaded by these ids")
+	fs.StringVar(&hashIdsPackage, "hash-ids-package", "i18n", "package name of the -hash-ids file")
+	fs.IntVar(&hashIdsLength, "hash-ids-length", 8, "hex digits of -hash-ids ids, colliding ids are longer")
+	fs.BoolVar(&migrateMoved, "migrate-moved-keys", false, "add translations of keys moved from other projects to locales of -project missing them")
+	fs.BoolVar(&planOnly, "plan", false, "print the plan of the sync as json: sources to scan, uploads, downloads, validations and outputs, and exit without changes")
+	fs.BoolVar(&autoApprove, "auto-approve", false, "skip confirmation of the plan asked when stdin is a terminal")
+	fs.BoolVar(&createMissingLocales, "create-missing-locales", false, "create locales other synced projects have, they are reported otherwise")
+	fs.StringVar(&keyHitsPath, "key-hits", "", "usage report of keys, json object of key to hit count or csv of key,count rows, to rank untranslated keys by hits and report keys never displayed")
+	fs.BoolVar(&placeholderMetadata, "placeholder-metadata", false, "list placeholders of extracted source strings in descriptions of phraseapp keys")
+	fs.StringVar(&suggestTranslations, "suggest-translations", "", "offer translations of keys with similar source text for new keys: "+SUGGEST_REPORT+" lists them, "+SUGGEST_APPLY+" adds them as unverified translations")
+	fs.Float64Var(&suggestSimilarity, "suggest-similarity", 0.9, "minimal similarity of source texts of -suggest-translations, 1 for exact matches only")
+	fs.BoolVar(&stubNewKeys, "stub-new-keys", false, "add extracted keys missing in downloaded locales with source text as translation")
+	fs.StringVar(&stubSuffix, "stub-suffix", "", "suffix marking translations added by -stub-new-keys")
+	fs.StringVar(&onlyDirs, "only", "", "comma separated directories, relative to -path, to limit extraction and download to")
+	fs.BoolVar(&pseudoRtl, "pseudo-rtl", false, "generate "+PSEUDO_RTL_LOCALE+" pseudo-locale of -project rendering source strings right to left")
+	fs.BoolVar(&mergeProjects, "merge-projects", false, "also merge locales of all projects into one file per locale in "+LOCALIZED_DATA_FOLDER+MERGED_SUFFIX)
+	fs.StringVar(&codegenPath, "codegen", "", "go file to generate with translation maps of downloaded locales of -project")
+	fs.StringVar(&codegenPackage, "codegen-package", "i18n", "package name of the -codegen file")
+	updateTranslationProjects = nil
+	fs.Var(&updateTranslationProjects, "update-translations", "projects whose uploads replace existing translations, e.g. Backend=true,DriverApp=false, * for other projects")
+	fs.StringVar(&remoteEdits, "remote-edits", REMOTE_EDITS_ABORT, "policy of uploads updating translations edited in phraseapp since the last upload: abort or warn")
+	fs.StringVar(&onError, "on-error", ON_ERROR_FAIL, "error policy of upload and download: fail, continue or retry")
+	fs.IntVar(&errorRetries, "retries", 3, "number of retries of -on-error retry policy")
+	fs.BoolVar(&createLocale, "create-default-locale", false, "create default locale in projects without locales")
+	fs.Int64Var(&apiCallLimit, "api-limit", 0, "monthly phraseapp API call limit of the plan, warns when nearing it")
+	fs.StringVar(&auditTarget, "audit-log", "", "file or http(s) endpoint receiving json records of changes made in phraseapp")
+	fs.DurationVar(&syncTimeout, "deadline", 0, "abort the sync after the current locale once the duration passes, e.g. 5m, unlimited if zero")
+	fs.BoolVar(&candidateMode, "candidate", false, "download to "+LOCALIZED_DATA_FOLDER+CANDIDATE_SUFFIX+" to be promoted to the live folder by promote")
+	fs.BoolVar(&promoteWhenClean, "promote-when-clean", false, "promote -candidate download when the run has no errors")
+	fs.StringVar(&bundleLocation, "bundles", "", "folder or s3://bucket/prefix to keep versioned bundles of downloaded locales in for download -pin")
+	fs.DurationVar(&startJitter, "start-jitter", 0, "wait a random duration up to this before syncing, to spread runners started together")
+	fs.StringVar(&coordinateUrl, "coordinate", "", "redis://host:port/key of runners sharing a sync: one syncs with phraseapp, others restore its bundle from -bundles")
+	fs.DurationVar(&coordinateMaxAge, "coordinate-max-age", 15*time.Minute, "time a bundle published by -coordinate is reused")
+	fs.DurationVar(&coordinateLease, "coordinate-lease", 30*time.Minute, "time the -coordinate lease of a runner lasts if it dies before releasing it")
+	fs.StringVar(&stateLocation, "state", "", "run info store shared by runners: file path, s3://bucket/key or redis://[:password@]host:port/key, user cache dir if empty")
+	fs.BoolVar(&useStateKeyring, "state-keyring", false, "read run info encryption key from OS keyring when "+STATE_KEY_ENV+" is not set")
+}
 
-	if *phraseappToken == "" && *junolabPath == "" {
-		log.Fatalln("All params are empty.")
+func validateCommonFlags() {
+	if err := checkCommonFlags(); err != nil {
+		log.Fatalln(err)
 	}
 
-	if *phraseappToken == "" {
-		log.Fatalln("Please, specify phraseapp token")
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Fatalln(err)
 	}
+	config = cfg
+}
 
-	if *junolabPath == "" {
-		log.Fatalln("Please, specify path to micro-services")
-		return
+func checkCommonFlags() error {
+	if phraseappToken == "" && basepath == "" {
+		return fmt.Errorf("All params are empty.")
+	}
+
+	if phraseappToken == "" {
+		return fmt.Errorf("Please, specify phraseapp token")
+	}
+
+	if basepath == "" {
+		return fmt.Errorf("Please, specify path to micro-services")
+	}
+
+	if perPage < 1 || perPage > MAX_PER_PAGE {
+		return fmt.Errorf("Page size should be between 1 and %d", MAX_PER_PAGE)
 	}
 
 	if _, ok := phraseappProjects[defaultProject]; !ok {
-		log.Fatalln("Please, specify phraseapp project id for default project")
-		return
+		return fmt.Errorf("Please, specify phraseapp project id for default project")
 	}
+	return nil
+}
 
-	basepath = *junolabPath
+// connect checks connectivity and creates phraseapp worker.
+func connect() *PhraseappWorkerContext {
+	worker, err := newWorker()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return worker
+}
 
+func newWorker() (*PhraseappWorkerContext, error) {
 	if checkInternetConnectivity() == 0 {
-		log.Fatal("There is no internet connection.")
+		return nil, fmt.Errorf("There is no internet connection.")
 	}
 
-	cfg := createConfig(*phraseappToken)
+	cfg := createConfig(phraseappToken)
 
 	client, err := phraseapp.NewClient(cfg.Credentials)
 	if err != nil {
-		log.Fatalln("Unable to create client", err)
+		return nil, fmt.Errorf("Unable to create client, %v", err)
+	}
+	worker := NewPhraseappWorker(cfg, client)
+	var transport http.RoundTripper = http.DefaultTransport
+	if debugHttp {
+		transport = newDebugTransport(debugHttpDumpDir)
+	}
+	if userAgentSuffix != "" || requestSource != "" {
+		transport = &headerTransport{next: transport}
 	}
+	if branch := providerBranch(); branch != "" {
+		transport = &branchTransport{next: transport, branch: branch}
+	}
+	// no single request may outlive the whole run
+	transport = &deadlineTransport{next: transport}
+	client.Transport = transport
+	worker.Transport = transport
+	return worker, nil
+}
 
-	ctx = NewPhraseappWorker(cfg, client)
-	readRunInfo()
-	processLocales()
+func runSync(fs *flag.FlagSet) {
+	validateCommonFlags()
+	if err := syncLocales(); err != nil {
+		log.Fatalln(err)
+	}
+	report.Print()
+	if len(report.Errors) > 0 {
+		os.Exit(1)
+	}
+}
+
+// syncLocales runs a sync with settings of flag variables and config. Setup errors are returned,
+// errors of upload and download are handled according to -on-error and collected in report.
+func syncLocales() error {
+	report = RunReport{}
+	resetDetectedPlaceholders()
+	start := time.Now()
+	startDeadline(start)
+	defer func() { report.AddTiming(TIMING_RUN, "", time.Since(start)) }()
+	if err := validateErrorPolicy(); err != nil {
+		return err
+	}
+	if err := validateCodegen(); err != nil {
+		return err
+	}
+	if err := validateRemoteEdits(); err != nil {
+		return err
+	}
+	if err := validateCoordination(); err != nil {
+		return err
+	}
+	sourceFiles = nil
+	if isSourceArchive(basepath) {
+		archive := basepath
+		if err := loadSourceArchive(archive); err != nil {
+			return err
+		}
+		// locales are written to the working directory
+		basepath = "."
+		defer func() { basepath, sourceFiles = archive, nil }()
+	}
+	if err := validateHashIds(); err != nil {
+		return err
+	}
+	if err := validateSuggestions(); err != nil {
+		return err
+	}
+	if err := validatePartialSync(); err != nil {
+		return err
+	}
+	if err := validateKeyRoutes(config.KeyRoutes); err != nil {
+		return err
+	}
+	if err := loadKeyMapping(config.KeyMapping, basepath); err != nil {
+		return err
+	}
+	if err := loadKeyHits(keyHitsPath); err != nil {
+		return err
+	}
+	if err := loadOverrides(config.Overrides); err != nil {
+		return err
+	}
+	if bootstrapMode && bootstrapBatch < 1 {
+		return fmt.Errorf("Bootstrap batch should be positive")
+	}
+	bootstrapCount = 0
+
+	// bundles published by coordinated runners are encrypted as well
+	if err := initStateKey(); err != nil {
+		return err
+	}
+
+	waitStartJitter()
+	if reused, err := coordinateSync(); err != nil || reused {
+		return err
+	}
+	defer releaseCoordination()
+
+	var err error
+	ctx, err = newWorker()
+	if err != nil {
+		return err
+	}
+	if errs := ctx.ValidateProjects(&i18nGenContext{}); len(errs) > 0 {
+		for _, err := range errs {
+			log.Println(err)
+		}
+		return fmt.Errorf("Please, check -project_id mappings and the token")
+	}
+	if err := readRunInfo(); err != nil {
+		return err
+	}
+	if ok, err := confirmPlan(); err != nil || !ok {
+		stateStore.Close()
+		return err
+	}
+	if !processLocales() {
+		stateStore.Close()
+		return nil
+	}
 	writeRunInfo()
+	reportDeadline()
+	if candidateMode {
+		finishCandidate()
+	} else if bundleLocation != "" {
+		saveBundle()
+		publishCoordinatedBundle()
+	}
+	writeStatus(report.Locales)
+	sendDigest(config.Digest, report.NewKeys)
+	return nil
 }
 
 func createConfig(token string) *phraseapp.Config {
 	cfg := new(phraseapp.Config)
 	cfg.Credentials = new(phraseapp.Credentials)
 	cfg.Credentials.Token = token
+	cfg.Credentials.Host = providerHost()
 	cfg.DefaultFileFormat = "go_i18n"
-	perPage := 25
 	cfg.PerPage = &perPage
 	return cfg
 }
@@ -132,177 +419,608 @@ func (c *CheckSumList) GetETag(p, l string) string {
 	return ""
 }
 
-func (c *CheckSumList) GetCrc32(p, l string) uint32 {
+func (c *CheckSumList) GetSha256(p, l string) string {
 	for _, e := range *c {
 		if e.LocaleName == l && e.ProjectName == p {
-			return e.DataCrc32
+			return e.DataSha256
 		}
 	}
-	return INVALID_CRC32
+	return INVALID_SHA256
 }
 
-func (c *CheckSumList) Upsert(p, l, etag string, crc32 uint32) {
+func (c *CheckSumList) Upsert(p, l, etag string, sha string) {
 	for _, e := range *c {
 		if e.LocaleName == l && e.ProjectName == p {
-			e.DataCrc32 = crc32
+			e.DataSha256 = sha
+			e.DataCrc32 = INVALID_CRC32
 			e.ETag = etag
 			return
 		}
 	}
-	*c = append(*c, &CheckSum{crc32, etag, p, l})
+	*c = append(*c, &CheckSum{DataSha256: sha, ETag: etag, ProjectName: p, LocaleName: l})
+}
+
+// migrate replaces crc32 of run info written by older versions with sha256 of files
+// still matching the crc32, entries of changed or missing files lose their etag.
+func (c *CheckSumList) migrate() {
+	for _, e := range *c {
+		if e.DataCrc32 == INVALID_CRC32 {
+			continue
+		}
+		data, err := ioutil.ReadFile(getLocalizationFileName(e.ProjectName, e.LocaleName))
+		if err == nil && crc32.ChecksumIEEE(data) == e.DataCrc32 {
+			e.DataSha256 = dataSha256(data)
+		} else {
+			e.DataSha256, e.ETag = INVALID_SHA256, ""
+		}
+		e.DataCrc32 = INVALID_CRC32
+	}
+}
+
+func dataSha256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
 }
 
 func (c *i18nGenContext) Projects() map[string]string {
-	return phraseappProjects
+	return syncProjects()
+}
+
+func (c *i18nGenContext) OnUpload(projectName, localeName string, part *UploadPart) {
+	if projectName == defaultProject {
+		provenanceDue = true
+	}
+	if c.UpdateTranslationFlag(projectName) {
+		recordUploaded(projectName, localeName, part)
+	}
+	if part.Name != "" {
+		logLocalef(projectName, localeName, "Translations of %s for project %s for locale %s were uploaded successfully.\n", part.Name, projectName, localeName)
+		report.AddUpload(fmt.Sprintf("%s:%s %s %d keys", projectName, localeName, part.Name, part.Keys))
+		audit(AUDIT_UPLOAD, projectName, localeName, part.Name)
+		return
+	}
+	logLocalef(projectName, localeName, "Translations for project %s for locale %s was uploaded successfully.\n", projectName, localeName)
+	audit(AUDIT_UPLOAD, projectName, localeName, "")
 }
 
-func (c *i18nGenContext) OnUpload(projectName, localeName string) {
-	log.Printf("Translations for project %s for locale %s was uploaded successfully.\n", projectName, localeName)
+func (c *i18nGenContext) OnEmptyProject(projectName string) {
+	logLocalef(projectName, "", "WARNING! Project %s has no locales.\n", projectName)
+	report.AddEmptyProject(projectName)
 }
 
-func (c *i18nGenContext) UpdateTranslationFlag() bool {
+func (c *i18nGenContext) LocaleToCreate(projectName string) string {
+	if !createLocale {
+		return ""
+	}
+	if defaultLocale == "" {
+		return FALLBACK_LOCALE
+	}
+	return defaultLocale
+}
+
+func (c *i18nGenContext) OnLocaleCreate(projectName, localeName string) {
+	logLocalef(projectName, localeName, "Locale %s for project %s was created.\n", localeName, projectName)
+	report.AddCreatedLocale(projectName, localeName)
+	audit(AUDIT_LOCALE_CREATE, projectName, localeName, "")
+}
+
+func (c *i18nGenContext) OnKeyTag(projectName, key, tag string) {
+	logLocalef(projectName, "", "Key %s of project %s was tagged %s.\n", key, projectName, tag)
+	audit(AUDIT_KEY_TAG, projectName, "", key+" "+tag)
+}
+
+// UpdateTranslationFlag is false unless -update-translations or upload config of the project enable it,
+// the flag wins.
+func (c *i18nGenContext) UpdateTranslationFlag(projectName string) bool {
+	if update, ok := updateTranslationProjects.lookup(projectName); ok {
+		return update
+	}
+	if u := config.uploadConfig(projectName); u != nil && u.UpdateTranslations != nil {
+		return *u.UpdateTranslations
+	}
 	return false
 }
 
-func (c *i18nGenContext) GetLocalesForUpdate() map[string][]string {
-	jsonData := GetLocalizationJsonFromSources(basepath)
-	m := map[string][]string{}
-	m[defaultProject+":"+defaultLocale] = append(m["en-US"], jsonData)
-	return m
+func (c *i18nGenContext) UploadTags(projectName, localeName string, part *UploadPart) string {
+	tags := []string{}
+	if w := activeFreeze(time.Now(), projectName); w != nil && w.Tag != "" {
+		tags = append(tags, w.Tag)
+	}
+	if part.Name != "" {
+		tags = append(tags, SERVICE_TAG_PREFIX+part.Name)
+	}
+	return strings.Join(tags, ",")
 }
 
-func (c *i18nGenContext) ErrorHandler(err error) {
-	log.Fatal(err)
+func (c *i18nGenContext) GetLocalesForUpdate() map[string][]*UploadPart {
+	m := map[string][]*UploadPart{}
+	sourcePlaceholders = nil
+	provenanceDue = false
+	uploading := []string{}
+	for _, projectName := range routeProjects() {
+		if w := activeFreeze(time.Now(), projectName); w != nil {
+			if w.Tag == "" {
+				log.Printf("WARNING! New strings of project %s are not uploaded during %s.\n", projectName, w.Describe())
+				report.AddFrozenProject(projectName)
+				c.OnSkipped(projectName, defaultLocale, OPERATION_UPLOAD, SKIP_FROZEN)
+				continue
+			}
+			log.Printf("WARNING! New strings of project %s are uploaded with tag %s during %s.\n", projectName, w.Tag, w.Describe())
+		}
+		uploading = append(uploading, projectName)
+	}
+	if len(uploading) == 0 {
+		return m
+	}
+	if err := extractSources(basepath, syncDirs()...); err != nil {
+		fail(err)
+		return m
+	}
+	extractPlaceholders(defaultProject)
+	routed := routeIds(v.Ids())
+	previousKeys := make(map[string][]string, len(runInfo.Keys))
+	for projectName, ids := range runInfo.Keys {
+		previousKeys[projectName] = ids
+	}
+	for _, projectName := range uploading {
+		ids := routed[projectName]
+		// routed projects without keys of this run are left alone
+		if len(ids) == 0 && projectName != defaultProject {
+			continue
+		}
+		recordKeysSeen(projectName, ids, time.Now())
+		// keys of a partial run are not compared with keys of the whole tree
+		if !isPartialSync() {
+			recordExtractedKeys(projectName, ids, previousKeys)
+		}
+		if splitUploads {
+			m[projectName+":"+defaultLocale] = serviceParts(v, projectName)
+			continue
+		}
+		part, err := newUploadPart("", ids, unescapingSource(projectName, v.translation))
+		if err != nil {
+			fail(fmt.Errorf("Unable to write upload payload, %v", err))
+		}
+		m[projectName+":"+defaultLocale] = []*UploadPart{part}
+	}
+	return m
 }
 
 func (c *i18nGenContext) Etag(projectName, localeName string) string {
-	origCrc32 := runInfo.CheckSumList.GetCrc32(projectName, localeName)
-	existCrc32 := getFileCrc32(projectName, localeName)
+	origSha256 := runInfo.CheckSumList.GetSha256(projectName, localeName)
+	existSha256 := getFileSha256(projectName, localeName)
 
 	etag := ""
-	if origCrc32 != INVALID_CRC32 && origCrc32 == existCrc32 {
+	if origSha256 != INVALID_SHA256 && origSha256 == existSha256 {
 		etag = runInfo.CheckSumList.GetETag(projectName, localeName)
 	}
 	return etag
 }
 
+// processPayload parses the payload and applies overrides, entity and QA checks, quarantine and legacy ids to its
+// translations, data is encoded again when they changed.
+func (c *i18nGenContext) processPayload(projectName, localeName string, data []byte, subset bool) ([]*Translation, []byte, error) {
+	translations, parseErr := ParseLocaleFile(data)
+	rewrite := false
+	if parseErr != nil && quarantineFailing {
+		translations, parseErr = parseQuarantined(projectName, localeName, data)
+		rewrite = true
+	}
+	if parseErr != nil {
+		return nil, data, parseErr
+	}
+	if kept := dropProvenance(translations); len(kept) < len(translations) {
+		translations = kept
+		rewrite = true
+	}
+	if overridden, keys := applyOverrides(projectName, localeName, translations); len(keys) > 0 {
+		if subset {
+			// keys added by overrides are not tagged with the subset tag
+			overridden = overridden[:len(translations)]
+		}
+		translations = overridden
+		rewrite = true
+	}
+	start := time.Now()
+	failed := []*Issue{}
+	if isUnescapingEntities(projectName) {
+		rewrite = reescapeEntities(translations) || rewrite
+		failed = checkEntities(projectName, localeName, translations)
+	}
+	failed = append(failed, checkTranslations(config.Qa, projectName, localeName, translations)...)
+	if !subset {
+		c.OnTiming(projectName, localeName, TIMING_VALIDATION, time.Since(start))
+	}
+	if quarantineFailing {
+		rewrite = quarantineFailed(translations, failed) || rewrite
+	} else if !subset {
+		// failures of subset keys fail the run with the locale file
+		for _, issue := range failed {
+			report.AddError(fmt.Errorf("%s", issue))
+		}
+	}
+	written := translations
+	if legacy := legacyTranslations(translations); len(legacy) > 0 {
+		written = append(append([]*Translation{}, translations...), legacy...)
+		rewrite = true
+	}
+	if rewrite {
+		encoded, err := json.MarshalIndent(written, "", "  ")
+		if err != nil {
+			fail(fmt.Errorf("Unable to encode locale file %s %s, %v", projectName, localeName, err))
+		}
+		data = encoded
+	}
+	return translations, data, nil
+}
+
 func (c *i18nGenContext) OnDownload(projectName, localeName, newEtag string, data []byte) {
-	log.Println("Downloaded locale", projectName, localeName)
+	logLocale(projectName, localeName, "Downloaded locale", projectName, localeName)
 
-	err := os.MkdirAll(filepath.Join(getLocalizationFolderName(), projectName), 0777)
+	data, findings, err := sanitizePayload(data)
+	if err != nil {
+		logLocale(projectName, localeName, "WARNING! Locale file is rejected", projectName, localeName, err)
+		report.AddIssue(&Issue{Project: projectName, Locale: localeName, Rule: "encoding", Message: err.Error()})
+		return
+	}
+	for _, f := range findings {
+		report.AddIssue(&Issue{Project: projectName, Locale: localeName, Rule: "encoding", Message: f})
+	}
+	data, err = normalizeEmbeddedLocale(data, localeName)
 	if err != nil {
-		log.Fatalln("Unable to create folder for project", projectName, localeName, err)
+		c.ErrorHandler(fmt.Errorf("Unable to normalize locale of file for project %s %s, %v", projectName, localeName, err))
+		return
 	}
 
-	err = ioutil.WriteFile(getLocalizationFileName(projectName, localeName), data, 0644)
+	translations, data, parseErr := c.processPayload(projectName, localeName, data, false)
+
+	err = mkdirOutput(filepath.Join(getLocalizationFolderName(), projectName))
 	if err != nil {
-		log.Fatalln("Unable to create locale file for project", projectName, localeName, err)
+		fail(fmt.Errorf("Unable to create folder for project %s %s, %v", projectName, localeName, err))
 	}
 
-	decodedData := []interface{}{}
-	err = json.Unmarshal(data, &decodedData)
+	// the checksum is of the file as written
+	data = encodeOutput(getLocalizationFileName(projectName, localeName), data)
+	err = writeOutputFile(getLocalizationFileName(projectName, localeName), data)
 	if err != nil {
-		log.Fatalln("Unable to unmarshal locale file for project", projectName, localeName, err)
+		fail(fmt.Errorf("Unable to create locale file for project %s %s, %v", projectName, localeName, err))
+	}
+
+	if parseErr != nil {
+		c.ErrorHandler(fmt.Errorf("Unable to unmarshal locale file for project %s %s, %v", projectName, localeName, parseErr))
+		return
 	}
 
-	for _, m := range decodedData {
-		d := m.(map[string]interface{})
-		if d["id"] == d["translation"] {
-			log.Println("WARNING! There is untranslated string", d["id"], projectName, localeName)
+	if len(translations) == 0 {
+		report.AddEmptyLocale(projectName, localeName)
+	}
+	addLocaleStatus(projectName, localeName, translations)
+	recordChanges(projectName, localeName, translations)
+	untranslated := 0
+	for _, t := range translations {
+		if t.IsUntranslated() {
+			untranslated++
+			if verbose {
+				logLocale(projectName, localeName, "WARNING! There is untranslated string", t.ID, projectName, localeName)
+			}
+		}
+	}
+	if untranslated > 0 && !verbose {
+		logLocalef(projectName, localeName, "WARNING! There are %d untranslated strings in %s %s, -verbose lists them\n", untranslated, projectName, localeName)
+	}
+
+	if newEtag == "" {
+		// without ETag the file can't be downloaded conditionally, so it isn't cached
+		runInfo.CheckSumList.Upsert(projectName, localeName, "", INVALID_SHA256)
+	} else {
+		runInfo.CheckSumList.Upsert(projectName, localeName, newEtag, dataSha256(data))
+	}
+	recordDownloadTime(projectName, localeName, time.Now())
+}
+
+func (c *i18nGenContext) DownloadParams(project string) phraseapp.LocaleDownloadParams {
+	params := phraseapp.LocaleDownloadParams{}
+	d := config.downloadConfig(project)
+	if d == nil {
+		return params
+	}
+	if d.Tag != "" {
+		params.Tag = &d.Tag
+	}
+	if len(d.FormatOptions) > 0 {
+		params.FormatOptions = &d.FormatOptions
+	}
+	params.ConvertEmoji = d.ConvertEmoji
+	params.IncludeEmptyTranslations = d.IncludeEmptyTranslations
+	params.SkipUnverifiedTranslations = d.SkipUnverifiedTranslations
+	return params
+}
+
+func (c *i18nGenContext) UploadParams(project string) phraseapp.UploadParams {
+	u := config.uploadConfig(project)
+	if u == nil {
+		return phraseapp.UploadParams{}
+	}
+	return phraseapp.UploadParams{
+		Autotranslate:      u.Autotranslate,
+		MarkReviewed:       u.MarkReviewed,
+		SkipUnverification: u.SkipUnverification,
+		SkipUploadTags:     u.SkipUploadTags,
+	}
+}
+
+func (c *i18nGenContext) ProductionDownload() bool {
+	return prodDownload
+}
+
+// OnProductionDownload writes translations of reviewed keys only.
+func (c *i18nGenContext) OnProductionDownload(projectName, localeName string, data []byte, reviewed map[string]bool) {
+	if quarantineFailing {
+		// quarantined translations were replaced in the written locale file
+		if written, err := ioutil.ReadFile(getLocalizationFileName(projectName, localeName)); err == nil {
+			data = written
+		}
+	}
+	data, _, err := sanitizePayload(data)
+	if err != nil {
+		// already reported by OnDownload
+		return
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		// already reported by OnDownload
+		return
+	}
+	translations = dropProvenance(translations)
+	// re-escaping is idempotent, quarantined files are re-escaped already
+	if isUnescapingEntities(projectName) {
+		reescapeEntities(translations)
+	}
+	prod := []*Translation{}
+	for _, t := range translations {
+		if reviewed[t.ID] {
+			prod = append(prod, t)
 		}
 	}
+	logLocalef(projectName, localeName, "%d of %d translations are reviewed in %s %s\n", len(prod), len(translations), projectName, localeName)
+	prod = append(prod, legacyTranslations(prod)...)
 
-	runInfo.CheckSumList.Upsert(projectName, localeName, newEtag, crc32.ChecksumIEEE(data))
+	encoded, err := json.MarshalIndent(prod, "", "  ")
+	if err != nil {
+		fail(fmt.Errorf("Unable to encode production locale file %s %s, %v", projectName, localeName, err))
+	}
+	err = mkdirOutput(filepath.Join(getProdFolderName(), projectName))
+	if err != nil {
+		fail(fmt.Errorf("Unable to create production folder for project %s %s, %v", projectName, localeName, err))
+	}
+	err = writeOutputFile(getProdFileName(projectName, localeName), encoded)
+	if err != nil {
+		fail(fmt.Errorf("Unable to create production locale file for project %s %s, %v", projectName, localeName, err))
+	}
 }
 
 func checkInternetConnectivity() int {
 	conn, err := net.Dial("tcp", "google.com:80")
-	defer conn.Close()
 	if err != nil {
 		return 0
 	}
+	conn.Close()
 	return 1
 }
 
+// getRunInfoFileName keeps run info in the per-user cache directory, shared temp dirs
+// are readable by other users on unix and cleaned up unpredictably on windows.
 func getRunInfoFileName() string {
-	return filepath.Join(os.TempDir(), "i18n_gen_run_info.json")
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "i18n_gen_run_info.json")
+	}
+	dir = filepath.Join(dir, "i18n_gen")
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return filepath.Join(os.TempDir(), "i18n_gen_run_info.json")
+	}
+	return filepath.Join(dir, "run_info.json")
 }
 
+// getLocalizationFolderName returns the folder locales are downloaded to, the candidate one with -candidate.
 func getLocalizationFolderName() string {
+	if candidateMode {
+		return filepath.Join(basepath, LOCALIZED_DATA_FOLDER+CANDIDATE_SUFFIX)
+	}
 	return filepath.Join(basepath, LOCALIZED_DATA_FOLDER)
 }
 
+// getLocalizationFileName returns file of the phraseapp locale, named by its runtime code.
 func getLocalizationFileName(projectName, localeName string) string {
-	return filepath.Join(getLocalizationFolderName(), projectName, localeName+".json")
+	return filepath.Join(getLocalizationFolderName(), projectName, runtimeLocale(localeName)+".json")
 }
 
-func readRunInfo() {
-	file, e := os.Open(getRunInfoFileName())
-	if e != nil {
-		runInfo = RunInfo{}
-		return
+func getProdFolderName() string {
+	if candidateMode {
+		return filepath.Join(basepath, PROD_DATA_FOLDER+CANDIDATE_SUFFIX)
 	}
-	defer file.Close()
+	return filepath.Join(basepath, PROD_DATA_FOLDER)
+}
 
-	reader := bufio.NewReader(file)
-	buff, err := ioutil.ReadAll(reader)
+func getProdFileName(projectName, localeName string) string {
+	return filepath.Join(getProdFolderName(), projectName, runtimeLocale(localeName)+".json")
+}
+
+// readRunInfo opens the state store and reads run info, the store stays locked till writeRunInfo.
+func readRunInfo() error {
+	store, err := openStateStore(stateLocation)
+	if err != nil {
+		return fmt.Errorf("Unable to open state store, %v", err)
+	}
+	stateStore = store
+	runInfo = RunInfo{}
+
+	buff, err := stateStore.Load()
+	if err != nil {
+		stateStore.Close()
+		return fmt.Errorf("Unable to read check sum file, %v", err)
+	}
+	if buff == nil {
+		return nil
+	}
+	buff, err = openState(buff)
 	if err != nil {
-		log.Fatal("Unable to read check sum file", err)
+		log.Println("Unable to open run info, starting from scratch", err)
+		return nil
 	}
 	err = json.Unmarshal(buff, &runInfo)
 	if err != nil {
-		defer os.Remove(getRunInfoFileName())
+		log.Println("Unable to parse run info, starting from scratch", err)
+		runInfo = RunInfo{}
+		return nil
 	}
+	runInfo.CheckSumList.migrate()
+	return nil
 }
 
+// writeRunInfo saves run info and releases the state store.
 func writeRunInfo() {
-	file, e := os.Create(getRunInfoFileName())
-	if e != nil {
-		log.Println("Unable to write run info")
-		return
-	}
-	defer file.Close()
+	defer func() {
+		stateStore.Close()
+		stateStore = nil
+	}()
+	saveRunInfo()
+}
 
+// saveRunInfo stores run info keeping the store open.
+func saveRunInfo() {
 	encoded, err := json.Marshal(&runInfo)
 	if err != nil {
-		log.Fatal("Unable to encode check sum file", err)
+		fail(fmt.Errorf("Unable to encode check sum file, %v", err))
 	}
-	writer := bufio.NewWriter(file)
-	_, err = writer.Write(encoded)
+	encoded, err = sealState(encoded)
 	if err != nil {
-		log.Fatal(err)
+		fail(fmt.Errorf("Unable to encrypt check sum file, %v", err))
+	}
+	if err := stateStore.Save(encoded); err != nil {
+		log.Println("Unable to write run info", err)
 	}
-	writer.Flush()
 }
 
-func getFileCrc32(projectName, localeName string) uint32 {
+func getFileSha256(projectName, localeName string) string {
 	file, e := os.Open(getLocalizationFileName(projectName, localeName))
 	if e != nil {
-		return INVALID_CRC32
+		return INVALID_SHA256
 	}
 	defer file.Close()
 
 	reader := bufio.NewReader(file)
 	buff, err := ioutil.ReadAll(reader)
 	if err != nil {
-		log.Fatal(err)
+		fail(err)
 	}
 
-	return crc32.ChecksumIEEE(buff)
+	return dataSha256(buff)
 }
 
-func processLocales() {
+// processLocales returns false when the previous run was too recent and nothing was done.
+func processLocales() bool {
 	if time.Now().UnixNano()-runInfo.LastRunTime <= GLOBAL_RUN_DELAY {
-		os.Exit(0)
+		log.Println("Sync is skipped,", SKIP_MIN_INTERVAL)
+		report.AddSkippedWork(&SkippedWork{Project: "*", Operation: OPERATION_SYNC, Reason: SKIP_MIN_INTERVAL})
+		return false
 	}
 
-	removeContents(getLocalizationFolderName())
+	if candidateMode {
+		prepareCandidate()
+	}
+	// candidates are compared with live locales
+	snapshotLocales(filepath.Join(basepath, LOCALIZED_DATA_FOLDER))
+	restore, reportKept := keepLocalesNotDue()
+	clearProjectFolders(getLocalizationFolderName())
+	if prodDownload {
+		clearProjectFolders(getProdFolderName())
+	}
+	restore()
 	localCtx := &i18nGenContext{}
 
 	ctx.Upload(localCtx)
+	if v != nil {
+		for _, projectName := range routeProjects() {
+			tagDeprecatedKeys(ctx, projectName)
+			tagVariantKeys(ctx, projectName)
+			syncMessageDescriptions(ctx, projectName)
+			if projectName == defaultProject && provenanceDue {
+				recordProvenance(ctx, projectName)
+			}
+			if !isPartialSync() {
+				reportUnseenKeys(projectName, time.Now())
+			}
+		}
+		reportExpiredKeys(time.Now())
+	}
+	if config.Notes != "" {
+		syncNotes(ctx, defaultProject)
+	}
+	reportProjectsNotSynced()
 	ctx.Download(localCtx)
+	reportKept()
+	checkLocaleGaps(ctx)
+	if config.Overrides != "" {
+		applyOverrideFiles(getLocalizationFolderName())
+		if prodDownload {
+			applyOverrideFiles(getProdFolderName())
+		}
+	}
+	// stubs of new keys don't count as translations
+	if keyHits != nil {
+		reportKeyHits()
+	}
+	if len(config.Sms) > 0 {
+		checkSmsLimits(ctx)
+	}
+	// sources are not scanned when uploads are blocked by a freeze
+	if v != nil {
+		routed := routeIds(v.Ids())
+		for _, projectName := range routeProjects() {
+			if migrateMoved {
+				migrateMovedKeys(ctx, projectName)
+			}
+			if suggestTranslations != "" {
+				suggestNewKeyTranslations(ctx, projectName, routed[projectName])
+			}
+			if stubNewKeys {
+				stubMissingKeys(projectName, routed[projectName])
+			}
+		}
+	}
+	if len(config.Regional) > 0 {
+		writeRegionalFiles(getLocalizationFolderName())
+		if prodDownload {
+			writeRegionalFiles(getProdFolderName())
+		}
+	}
+	if config.Emails != nil {
+		writeEmailTemplates(defaultProject)
+	}
+	if pseudoRtl {
+		writePseudoRtlLocale(defaultProject)
+	}
+	// services may read generated code, so it is generated from candidate locales once they are promoted
+	if codegenPath != "" && !candidateMode {
+		generateCode(defaultProject)
+	}
+	if len(config.Typescript) > 0 && !candidateMode {
+		writeTypescriptOutputs()
+	}
+	if mergeProjects {
+		mergeProjectFolders(getLocalizationFolderName())
+		if prodDownload {
+			mergeProjectFolders(getProdFolderName())
+		}
+	}
+	writeManifest(getLocalizationFolderName())
+	if prodDownload {
+		writeManifest(getProdFolderName())
+	}
 
 	runInfo.LastRunTime = time.Now().UnixNano()
+	return true
 }
 
 func removeContents(dir string) error {
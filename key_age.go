@@ -0,0 +1,56 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// KeySeen tells when a key was extracted from sources for the first and the last time.
+type KeySeen struct {
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+// unseenDays reports keys not extracted from sources for that many days, nothing is reported if zero.
+var unseenDays int
+
+// recordKeysSeen updates timestamps of extracted keys of the project in run info.
+// Keys gone from sources keep their last seen time.
+func recordKeysSeen(projectName string, ids []string, now time.Time) {
+	if runInfo.Seen == nil {
+		runInfo.Seen = map[string]map[string]*KeySeen{}
+	}
+	seen, ok := runInfo.Seen[projectName]
+	if !ok {
+		seen = map[string]*KeySeen{}
+		runInfo.Seen[projectName] = seen
+	}
+	for _, id := range ids {
+		if s, ok := seen[id]; ok {
+			s.LastSeen = now
+		} else {
+			seen[id] = &KeySeen{FirstSeen: now, LastSeen: now}
+		}
+	}
+}
+
+// reportUnseenKeys lists keys of the project missing in sources for unseenDays.
+func reportUnseenKeys(projectName string, now time.Time) {
+	if unseenDays <= 0 {
+		return
+	}
+	threshold := now.AddDate(0, 0, -unseenDays)
+	ids := []string{}
+	for id, s := range runInfo.Seen[projectName] {
+		if s.LastSeen.Before(threshold) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		s := runInfo.Seen[projectName][id]
+		report.AddUnseenKey(fmt.Sprintf("%s: %s (first seen %s, last seen %s)", projectName, id,
+			s.FirstSeen.Format(CONFIG_DATE_FORMAT), s.LastSeen.Format(CONFIG_DATE_FORMAT)))
+	}
+}
@@ -0,0 +1,134 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// KEY_HITS_SAMPLES is the number of keys listed by key hits report sections without -verbose.
+const KEY_HITS_SAMPLES = 20
+
+var (
+	// keyHitsPath is a usage report of the metrics system: a json object of key to hit count or csv of key,count rows.
+	keyHitsPath string
+	keyHits     map[string]int64
+)
+
+type untranslatedHit struct {
+	project string
+	locale  string
+	key     string
+	hits    int64
+}
+
+func loadKeyHits(path string) error {
+	keyHits = nil
+	if path == "" {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("Unable to read key hits %s, %v", path, err)
+	}
+	hits := map[string]int64{}
+	if strings.HasSuffix(path, ".csv") {
+		rows, err := csv.NewReader(bytes.NewReader(data)).ReadAll()
+		if err != nil {
+			return fmt.Errorf("Unable to parse key hits %s, %v", path, err)
+		}
+		for i, row := range rows {
+			if len(row) < 2 {
+				return fmt.Errorf("Unable to parse key hits %s, row %d should be key,count", path, i+1)
+			}
+			count, err := strconv.ParseInt(strings.TrimSpace(row[1]), 10, 64)
+			if err != nil && i == 0 {
+				// header
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("Unable to parse key hits %s, row %d, %v", path, i+1, err)
+			}
+			hits[row[0]] += count
+		}
+	} else if err := json.Unmarshal(data, &hits); err != nil {
+		return fmt.Errorf("Unable to parse key hits %s, %v", path, err)
+	}
+	keyHits = hits
+	return nil
+}
+
+// reportKeyHits joins key hits with downloaded locales of synced projects: untranslated keys are reported by hits,
+// most displayed first, and keys without hits are reported for cleanup. Hits of untranslated keys are added to
+// locale statuses.
+func reportKeyHits() {
+	untranslated := []*untranslatedHit{}
+	for projectName := range syncProjects() {
+		files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+		if err != nil {
+			log.Println("WARNING! Unable to list locale files", projectName, err)
+			continue
+		}
+		keys := map[string]bool{}
+		for _, path := range files {
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				log.Println("WARNING! Unable to read locale file", path, err)
+				continue
+			}
+			translations, err := ParseLocaleFile(data)
+			if err != nil {
+				log.Println("WARNING! Unable to parse locale file", path, err)
+				continue
+			}
+			localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+			localeHits := int64(0)
+			for _, t := range translations {
+				if isDeprecated(t.ID) {
+					continue
+				}
+				keys[t.ID] = true
+				if hits := keyHits[t.ID]; hits > 0 && t.IsUntranslated() {
+					untranslated = append(untranslated, &untranslatedHit{projectName, localeName, t.ID, hits})
+					localeHits += hits
+				}
+			}
+			for _, s := range report.Locales {
+				if s.Project == projectName && s.Locale == localeName {
+					s.UntranslatedHits = localeHits
+				}
+			}
+		}
+		for key := range keys {
+			if keyHits[key] == 0 {
+				report.NeverDisplayed = append(report.NeverDisplayed, projectName+": "+key)
+			}
+		}
+	}
+	sort.Slice(untranslated, func(i, j int) bool {
+		a, b := untranslated[i], untranslated[j]
+		if a.hits != b.hits {
+			return a.hits > b.hits
+		}
+		return a.project+":"+a.locale+" "+a.key < b.project+":"+b.locale+" "+b.key
+	})
+	for _, u := range untranslated {
+		report.UntranslatedHits = append(report.UntranslatedHits, fmt.Sprintf("%s:%s %s (%d hits)", u.project, u.locale, u.key, u.hits))
+	}
+	sort.Strings(report.NeverDisplayed)
+}
+
+// keyHitsSamples lists KEY_HITS_SAMPLES lines and counts the rest, all with -verbose.
+func keyHitsSamples(lines []string) []string {
+	if verbose || len(lines) <= KEY_HITS_SAMPLES {
+		return lines
+	}
+	return append(append([]string{}, lines[:KEY_HITS_SAMPLES]...), fmt.Sprintf("%d more", len(lines)-KEY_HITS_SAMPLES))
+}
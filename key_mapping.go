@@ -0,0 +1,123 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+var (
+	// keyMapping renames legacy ids found in sources to new ids, legacyIds maps new ids back.
+	keyMapping map[string]string
+	legacyIds  map[string]string
+)
+
+// loadKeyMapping reads json object of old ids to new ids, path is relative to basepath.
+func loadKeyMapping(path, basepath string) error {
+	keyMapping, legacyIds = map[string]string{}, map[string]string{}
+	if path == "" {
+		return nil
+	}
+	data, err := readSourceFile(basepath, path)
+	if err != nil {
+		return fmt.Errorf("Unable to read key mapping %s, %v", path, err)
+	}
+	if err := json.Unmarshal(data, &keyMapping); err != nil {
+		return fmt.Errorf("Unable to parse key mapping %s, %v", path, err)
+	}
+	for old, id := range keyMapping {
+		if prev, ok := legacyIds[id]; ok {
+			return fmt.Errorf("Key mapping renames both %s and %s to %s", prev, old, id)
+		}
+		if _, ok := keyMapping[id]; ok {
+			return fmt.Errorf("Key mapping renames %s to %s which is renamed as well", old, id)
+		}
+		legacyIds[id] = old
+	}
+	return nil
+}
+
+// applyKeyMapping renames legacy ids extracted by v, sunset dates and positions move to new ids.
+func applyKeyMapping(v *FuncVisitor) {
+	renameKeys(v, keyMapping)
+}
+
+// renameKeys renames keys found by v by the mapping of old ids to new ids.
+func renameKeys(v *FuncVisitor, mapping map[string]string) {
+	for old, id := range mapping {
+		if _, ok := v.funcNames[old]; ok {
+			delete(v.funcNames, old)
+			v.funcNames[id] = struct{}{}
+		}
+		if sunset, ok := v.deprecated[old]; ok {
+			delete(v.deprecated, old)
+			v.deprecated[id] = sunset
+		}
+		if services, ok := v.services[old]; ok {
+			delete(v.services, old)
+			if v.services[id] == nil {
+				v.services[id] = map[string]bool{}
+			}
+			for service := range services {
+				v.services[id][service] = true
+			}
+		}
+		if refs, ok := v.refs[old]; ok {
+			delete(v.refs, old)
+			v.refs[id] = append(v.refs[id], refs...)
+		}
+		if locations, ok := v.locations[old]; ok {
+			delete(v.locations, old)
+			v.locations[id] = append(v.locations[id], locations...)
+		}
+		if t, ok := v.messages[old]; ok {
+			delete(v.messages, old)
+			v.messages[id] = &Translation{ID: id, Text: t.Text, Plural: t.Plural}
+		}
+		if description, ok := v.descriptions[old]; ok {
+			delete(v.descriptions, old)
+			v.descriptions[id] = description
+		}
+		if variants, ok := v.declaredVariants[old]; ok {
+			delete(v.declaredVariants, old)
+			for variant, positions := range variants {
+				v.addDeclaredVariant(id, variant, positions...)
+			}
+		}
+	}
+}
+
+// sourceText returns text uploaded for extracted id, renamed keys keep the legacy id as text.
+func sourceText(id string) string {
+	if text, ok := hashedTexts[id]; ok {
+		return text
+	}
+	if old, ok := legacyIds[id]; ok {
+		return old
+	}
+	return id
+}
+
+// legacyTranslations copies downloaded translations of renamed keys to their legacy ids,
+// so services still using legacy ids keep getting translations.
+func legacyTranslations(translations []*Translation) []*Translation {
+	present := map[string]bool{}
+	for _, t := range translations {
+		present[t.ID] = true
+	}
+	legacy := []*Translation{}
+	for _, t := range translations {
+		old, ok := legacyIds[t.ID]
+		if !ok || present[old] {
+			continue
+		}
+		copied := &Translation{ID: old, Text: t.Text}
+		if t.IsPlural() {
+			copied.Plural = map[string]string{}
+			for form, text := range t.Plural {
+				copied.Plural[form] = text
+			}
+		}
+		legacy = append(legacy, copied)
+	}
+	return legacy
+}
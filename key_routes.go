@@ -0,0 +1,61 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// validateKeyRoutes checks routes are ids or prefixes ending with * and their projects have -project_id.
+func validateKeyRoutes(routes map[string]string) error {
+	for pattern, project := range routes {
+		if pattern == "" || pattern == "*" || strings.Contains(strings.TrimSuffix(pattern, "*"), "*") {
+			return fmt.Errorf("Key route %s should be a key or a prefix followed by *", pattern)
+		}
+		if _, ok := phraseappProjects[project]; !ok {
+			return fmt.Errorf("Keys %s are routed to project %s without -project_id", pattern, project)
+		}
+	}
+	return nil
+}
+
+// keyProject returns the project an extracted key is uploaded to: the project of the longest route matching it,
+// a key route winning over a prefix of the same length, or -project.
+func keyProject(id string) string {
+	project, matched := defaultProject, -1
+	for pattern, p := range config.KeyRoutes {
+		prefix := strings.TrimSuffix(pattern, "*")
+		if pattern == id || (prefix != pattern && strings.HasPrefix(id, prefix)) {
+			if len(prefix) > matched || (len(prefix) == matched && pattern == id) {
+				project, matched = p, len(prefix)
+			}
+		}
+	}
+	return project
+}
+
+// routeProjects returns -project followed by sorted projects of key routes.
+func routeProjects() []string {
+	projects := []string{defaultProject}
+	routed := []string{}
+	for _, project := range config.KeyRoutes {
+		if project != defaultProject {
+			routed = appendUnique(routed, project)
+		}
+	}
+	sort.Strings(routed)
+	return append(projects, routed...)
+}
+
+// routeIds splits ids by project they are uploaded to, keeping their order.
+func routeIds(ids []string) map[string][]string {
+	routed := map[string][]string{}
+	for _, project := range routeProjects() {
+		routed[project] = []string{}
+	}
+	for _, id := range ids {
+		project := keyProject(id)
+		routed[project] = append(routed[project], id)
+	}
+	return routed
+}
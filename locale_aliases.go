@@ -0,0 +1,51 @@
+package i18n_gen
+
+import "encoding/json"
+
+// EMBEDDED_LOCALE_FIELDS are object fields of downloaded files holding the locale identifier.
+var EMBEDDED_LOCALE_FIELDS = map[string]bool{"locale": true, "locale_code": true, "language": true, "lang": true}
+
+// runtimeLocale returns the code services load the phraseapp locale under, config.LocaleAliases maps them.
+func runtimeLocale(localeName string) string {
+	if alias, ok := config.LocaleAliases[localeName]; ok {
+		return alias
+	}
+	return localeName
+}
+
+// normalizeEmbeddedLocale rewrites locale identifiers embedded in a downloaded file to the runtime code,
+// data is returned as is when there is nothing to rewrite.
+func normalizeEmbeddedLocale(data []byte, localeName string) ([]byte, error) {
+	alias := runtimeLocale(localeName)
+	if alias == localeName {
+		return data, nil
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if !rewriteLocaleFields(doc, localeName, alias) {
+		return data, nil
+	}
+	return json.MarshalIndent(doc, "", "  ")
+}
+
+func rewriteLocaleFields(node interface{}, from, to string) bool {
+	changed := false
+	switch n := node.(type) {
+	case map[string]interface{}:
+		for k, value := range n {
+			if s, ok := value.(string); ok && EMBEDDED_LOCALE_FIELDS[k] && s == from {
+				n[k] = to
+				changed = true
+				continue
+			}
+			changed = rewriteLocaleFields(value, from, to) || changed
+		}
+	case []interface{}:
+		for _, value := range n {
+			changed = rewriteLocaleFields(value, from, to) || changed
+		}
+	}
+	return changed
+}
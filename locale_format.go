@@ -0,0 +1,98 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// dollarPattern matches a dollar sign glued to an amount or a placeholder of it: $%d, $ 10, ${{.Amount}}.
+	dollarPattern = regexp.MustCompile(`\$\s*(%[-+ #0-9.]*[dfgsv]|\{\{[^}]*\}\}|\d)`)
+	// monthFirstPattern and dayFirstPattern match hardcoded date formats.
+	monthFirstPattern  = regexp.MustCompile(`(?i)\bMM[/.-]DD\b`)
+	dayFirstPattern    = regexp.MustCompile(`(?i)\bDD[/.-]MM\b`)
+	numericDatePattern = regexp.MustCompile(`\b\d{1,2}/\d{1,2}/\d{2,4}\b`)
+	// dotDecimalPattern matches numbers like 1,000.50 or 9.99.
+	dotDecimalPattern = regexp.MustCompile(`\b\d{1,3}(,\d{3})*\.\d{1,2}\b`)
+
+	// dollarRegions use dollar sign for local currency.
+	dollarRegions = map[string]bool{"US": true, "CA": true, "AU": true, "NZ": true, "SG": true, "HK": true, "MX": true, "AR": true, "CL": true, "CO": true}
+	// monthFirstRegions write dates month first.
+	monthFirstRegions = map[string]bool{"US": true, "PH": true, "FM": true}
+	// commaDecimalLanguages use comma as decimal separator.
+	commaDecimalLanguages = map[string]bool{
+		"de": true, "fr": true, "es": true, "it": true, "pt": true, "nl": true, "ru": true, "uk": true, "pl": true,
+		"cs": true, "sk": true, "tr": true, "sv": true, "da": true, "nb": true, "fi": true, "el": true, "ro": true,
+		"hu": true, "bg": true, "hr": true, "sr": true, "sl": true, "lt": true, "lv": true, "et": true, "id": true, "vi": true,
+	}
+	// likelyRegions are regions of locales without one, after CLDR likely subtags.
+	likelyRegions = map[string]string{
+		"en": "US", "de": "DE", "fr": "FR", "es": "ES", "it": "IT", "pt": "BR", "nl": "NL", "ru": "RU", "uk": "UA",
+		"pl": "PL", "cs": "CZ", "sk": "SK", "tr": "TR", "sv": "SE", "da": "DK", "nb": "NO", "fi": "FI", "el": "GR",
+		"ro": "RO", "hu": "HU", "bg": "BG", "hr": "HR", "sr": "RS", "sl": "SI", "lt": "LT", "lv": "LV", "et": "EE",
+		"id": "ID", "vi": "VN", "ja": "JP", "ko": "KR", "zh": "CN", "ar": "EG", "he": "IL", "hi": "IN", "th": "TH",
+		"ms": "MY", "fil": "PH", "ka": "GE", "hy": "AM", "kk": "KZ", "az": "AZ",
+	}
+)
+
+// splitLocale splits locale name like en-US or pt_BR into language and upper case region, region may be empty.
+func splitLocale(locale string) (language, region string) {
+	parts := strings.FieldsFunc(locale, func(r rune) bool { return r == '-' || r == '_' })
+	if len(parts) == 0 {
+		return "", ""
+	}
+	language = strings.ToLower(parts[0])
+	if len(parts) > 1 {
+		region = strings.ToUpper(parts[len(parts)-1])
+	}
+	return language, region
+}
+
+// localeRegion returns region of the locale or the likely region of its language, empty if it's unknown.
+func localeRegion(locale string) string {
+	language, region := splitLocale(locale)
+	if region == "" {
+		return likelyRegions[language]
+	}
+	return region
+}
+
+func checkCurrency(locale, source, text string, placeholders *regexp.Regexp) string {
+	region := localeRegion(locale)
+	if region == "" || dollarRegions[region] {
+		return ""
+	}
+	if m := dollarPattern.FindString(text); m != "" {
+		return fmt.Sprintf("dollar sign %q in %s, currency should be a placeholder", m, locale)
+	}
+	return ""
+}
+
+func checkDate(locale, source, text string, placeholders *regexp.Regexp) string {
+	if m := numericDatePattern.FindString(text); m != "" {
+		return fmt.Sprintf("hardcoded date %q, date should be a placeholder", m)
+	}
+	region := localeRegion(locale)
+	if region == "" {
+		return ""
+	}
+	if m := monthFirstPattern.FindString(text); m != "" && !monthFirstRegions[region] {
+		return fmt.Sprintf("month first date format %q in %s", m, locale)
+	}
+	if m := dayFirstPattern.FindString(text); m != "" && monthFirstRegions[region] {
+		return fmt.Sprintf("day first date format %q in %s", m, locale)
+	}
+	return ""
+}
+
+func checkNumber(locale, source, text string, placeholders *regexp.Regexp) string {
+	language, _ := splitLocale(locale)
+	if !commaDecimalLanguages[language] {
+		return ""
+	}
+	if m := dotDecimalPattern.FindString(text); m != "" {
+		return fmt.Sprintf("number %q uses dot decimal separator in %s, number should be a placeholder", m, locale)
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/phrase/phraseapp-go/phraseapp"
+)
+
+// createMissingLocales creates locales which other synced projects have, so runtime doesn't fall back to the default language.
+var createMissingLocales bool
+
+// checkLocaleGaps reports locales some synced projects have and others miss, locales are compared by runtime name.
+// With -create-missing-locales the missing ones are created, copying name and code of a project having them, and downloaded.
+func checkLocaleGaps(worker *PhraseappWorkerContext) {
+	projects := syncProjects()
+	if len(projects) < 2 {
+		return
+	}
+	ctx := &i18nGenContext{}
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	byProject := map[string]map[string]*phraseapp.Locale{}
+	all := map[string]*phraseapp.Locale{}
+	for _, projectName := range names {
+		locales, err := worker.getLocales(ctx, projects[projectName], projectName)
+		if err != nil {
+			log.Println("WARNING! Unable to check locale gaps", err)
+			return
+		}
+		byProject[projectName] = map[string]*phraseapp.Locale{}
+		for _, l := range locales {
+			byProject[projectName][runtimeLocale(l.Name)] = l
+			if _, ok := all[runtimeLocale(l.Name)]; !ok {
+				all[runtimeLocale(l.Name)] = l
+			}
+		}
+	}
+	for _, projectName := range names {
+		for _, missing := range missingLocales(byProject, projectName) {
+			if !createMissingLocales {
+				log.Printf("WARNING! Project %s has no locale %s, runtime falls back to the default one\n", projectName, missing)
+				report.AddLocaleGap(fmt.Sprintf("%s: %s (present in %s)", projectName, missing, strings.Join(projectsWithLocale(byProject, missing), ", ")))
+				continue
+			}
+			if err := worker.createMissingLocale(ctx, projects[projectName], projectName, all[missing]); err != nil {
+				ctx.ErrorHandler(err)
+			}
+		}
+	}
+}
+
+// missingLocales returns sorted runtime names of locales other projects have and the project misses.
+func missingLocales(byProject map[string]map[string]*phraseapp.Locale, projectName string) []string {
+	missing := []string{}
+	for other, locales := range byProject {
+		if other == projectName {
+			continue
+		}
+		for name := range locales {
+			if _, ok := byProject[projectName][name]; !ok {
+				missing = appendUnique(missing, name)
+			}
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+func projectsWithLocale(byProject map[string]map[string]*phraseapp.Locale, localeName string) []string {
+	projects := []string{}
+	for projectName, locales := range byProject {
+		if _, ok := locales[localeName]; ok {
+			projects = append(projects, projectName)
+		}
+	}
+	sort.Strings(projects)
+	return projects
+}
+
+// createMissingLocale creates a locale like the given one of another project and downloads it.
+func (c *PhraseappWorkerContext) createMissingLocale(ctx PhraseappContexter, projectId, project string, like *phraseapp.Locale) error {
+	isDefault := false
+	locale, err := c.Client.LocaleCreate(projectId, &phraseapp.LocaleParams{Name: &like.Name, Code: &like.Code, Default: &isDefault})
+	ctx.OnApiCall(project, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Unable to create locale %s for project %s, %v", like.Name, project, err)
+	}
+	ctx.OnLocaleCreate(project, locale.Name)
+	return retry(ctx, func() error {
+		return c.downloadLocale(ctx, projectId, project, locale.ID, locale.Name)
+	})
+}
+
+func (r *RunReport) AddLocaleGap(gap string) {
+	r.LocaleGaps = appendUnique(r.LocaleGaps, gap)
+}
@@ -1,35 +1,106 @@
-package main
+package i18n_gen
 
 import (
-	"encoding/json"
+	"bytes"
+	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 )
 
-func GetLocalizationJsonFromSources(path string) string {
+// GetLocalizationJsonFromSources extracts keys of dirs, relative to path, or of the whole path if no dirs given.
+func GetLocalizationJsonFromSources(path string, dirs ...string) string {
+	if err := extractSources(path, dirs...); err != nil {
+		log.Fatalln(err)
+	}
+	return v.MakeJson()
+}
+
+// extractSources extracts keys of dirs, relative to path, or of the whole path into v.
+func extractSources(path string, dirs ...string) error {
 	start := time.Now()
 	v = NewFuncVisit()
-	err := filepath.Walk(path, findLocalizedStrings)
-	if err != nil {
-		log.Fatal(err)
+	if sourceFiles != nil {
+		scanSourceArchive(dirs)
+	} else {
+		walkIgnore = newSourceIgnore(path)
+		for _, root := range sourceRoots(path, dirs) {
+			err := filepath.Walk(root, findLocalizedStrings)
+			if err != nil {
+				v.wg.Wait()
+				return fmt.Errorf("Unable to scan %s, %v", root, err)
+			}
+		}
 	}
 	v.wg.Wait()
-	jsonData := v.MakeJson()
+	if err := reportDiagnostics(v); err != nil {
+		return err
+	}
+	applyKeyMapping(v)
+	if err := applyHashIds(v); err != nil {
+		return err
+	}
+	addVariantKeys(v)
+	if err := mergeSeeds(v, config.Seeds, path); err != nil {
+		return err
+	}
+	if config.Emails != nil {
+		if err := extractEmailTemplates(v, path); err != nil {
+			return err
+		}
+	}
+	if config.Pii != nil {
+		quarantinePii(v)
+	}
+	report.AddTiming(TIMING_EXTRACTION, "", time.Since(start))
 	log.Println("Localized data was genereated for", time.Since(start))
-	return jsonData
+	return nil
+}
+
+// sourceRoots returns folders extraction walks, dirs relative to path or the whole path if no dirs given.
+func sourceRoots(path string, dirs []string) []string {
+	if len(dirs) == 0 {
+		return []string{path}
+	}
+	roots := []string{}
+	for _, dir := range dirs {
+		roots = append(roots, filepath.Join(path, dir))
+	}
+	return roots
 }
 
 type FuncVisitor struct {
 	sync.Mutex
-	wg        sync.WaitGroup
-	funcNames map[string]struct{}
+	wg         sync.WaitGroup
+	funcNames  map[string]struct{}
+	deprecated map[string]time.Time
+	// refs are file:line positions of keys harvested from struct tags
+	refs map[string][]string
+	// locations are file:line positions of NewI18nString calls and table values
+	locations map[string][]string
+	// seeds are keys defined in json files rather than code
+	seeds       map[string]*Translation
+	diagnostics []*Diagnostic
+	// services are top folders of -path keys were found in
+	services map[string]map[string]bool
+	// messages are source texts of keys defined by message literals, descriptions are their descriptions
+	messages     map[string]*Translation
+	descriptions map[string]string
+	// declaredVariants are positions of variants declared by ids of keys, by variant name. Variant keys are added
+	// by addVariantKeys once ids are mapped and hashed, variants are names of variants by id of variant keys.
+	declaredVariants map[string]map[string][]string
+	variants         map[string]string
 }
 
 var v *FuncVisitor
@@ -37,70 +108,403 @@ var v *FuncVisitor
 func NewFuncVisit() *FuncVisitor {
 	v := new(FuncVisitor)
 	v.funcNames = make(map[string]struct{})
+	v.deprecated = make(map[string]time.Time)
+	v.refs = make(map[string][]string)
+	v.locations = make(map[string][]string)
+	v.seeds = make(map[string]*Translation)
+	v.services = make(map[string]map[string]bool)
+	v.messages = make(map[string]*Translation)
+	v.descriptions = make(map[string]string)
+	v.declaredVariants = make(map[string]map[string][]string)
+	v.variants = make(map[string]string)
 	return v
 }
 
+// merge adds everything found by file visitor f in a file of the service folder.
+func (v *FuncVisitor) merge(f *FuncVisitor, service string) {
+	v.Lock()
+	defer v.Unlock()
+	for id := range f.funcNames {
+		v.funcNames[id] = struct{}{}
+		if v.services[id] == nil {
+			v.services[id] = map[string]bool{}
+		}
+		v.services[id][service] = true
+	}
+	for id, sunset := range f.deprecated {
+		v.deprecated[id] = sunset
+	}
+	for id, refs := range f.refs {
+		v.refs[id] = append(v.refs[id], refs...)
+	}
+	for id, locations := range f.locations {
+		v.locations[id] = append(v.locations[id], locations...)
+	}
+	for id, t := range f.messages {
+		v.messages[id] = t
+	}
+	for id, description := range f.descriptions {
+		v.descriptions[id] = description
+	}
+	for id, variants := range f.declaredVariants {
+		for variant, positions := range variants {
+			v.addDeclaredVariant(id, variant, positions...)
+		}
+	}
+	v.diagnostics = append(v.diagnostics, f.diagnostics...)
+}
+
 func (v *FuncVisitor) Add(id string) {
 	v.Lock()
 	defer v.Unlock()
 	v.funcNames[id] = struct{}{}
 }
 
-func (v *FuncVisitor) Visit(node ast.Node) (w ast.Visitor) {
-	if fCall, ok := node.(*ast.CallExpr); ok {
-		fs, ok := fCall.Fun.(*ast.SelectorExpr) //some package's function call
-		if ok {
-			switch fs.Sel.Name {
-			case "NewI18nString":
-				arg0 := fCall.Args[0]
-				switch expr := arg0.(type) {
-				case *ast.BasicLit:
-					if expr.Kind.String() != "STRING" {
-						log.Fatalf("In call NewI18nString(id) id should be string literal! Got:%#v", expr)
-					}
-					v.Add(expr.Value[1 : len(expr.Value)-1])
-				default:
-					log.Fatalf("In call NewI18nString(id) id should be string literal! Got:%#v", expr)
+// AddRef adds id found at pos.
+func (v *FuncVisitor) AddRef(id, pos string) {
+	v.Lock()
+	defer v.Unlock()
+	v.funcNames[id] = struct{}{}
+	v.refs[id] = append(v.refs[id], pos)
+}
+
+// AddLocation adds id found by a call or a table at pos.
+func (v *FuncVisitor) AddLocation(id, pos string) {
+	v.Lock()
+	defer v.Unlock()
+	v.funcNames[id] = struct{}{}
+	v.locations[id] = append(v.locations[id], pos)
+}
+
+// Locations returns positions of calls and tables id was found at, and of struct tags.
+func (v *FuncVisitor) Locations(id string) []string {
+	v.Lock()
+	defer v.Unlock()
+	return append(append([]string{}, v.locations[id]...), v.refs[id]...)
+}
+
+// Refs returns positions id was found at, only keys of struct tags have positions.
+func (v *FuncVisitor) Refs(id string) []string {
+	v.Lock()
+	defer v.Unlock()
+	return append([]string{}, v.refs[id]...)
+}
+
+func (v *FuncVisitor) AddDeprecated(id string, sunset time.Time) {
+	v.Lock()
+	defer v.Unlock()
+	v.deprecated[id] = sunset
+}
+
+// Deprecated returns sunset dates of deprecated keys.
+func (v *FuncVisitor) Deprecated() map[string]time.Time {
+	v.Lock()
+	defer v.Unlock()
+	deprecated := make(map[string]time.Time, len(v.deprecated))
+	for id, sunset := range v.deprecated {
+		deprecated[id] = sunset
+	}
+	return deprecated
+}
+
+// Ids returns sorted ids of all found strings.
+func (v *FuncVisitor) Ids() []string {
+	v.Lock()
+	defer v.Unlock()
+	ids := make([]string, 0, len(v.funcNames)+len(v.seeds))
+	for id := range v.funcNames {
+		ids = append(ids, id)
+	}
+	for id := range v.seeds {
+		if _, ok := v.funcNames[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// i18nStringId returns id of NewI18nString(id) call, ok is false for any other node
+// and for calls with id other than a string literal.
+func i18nStringId(file *ast.File, node ast.Node) (id string, ok bool) {
+	fCall, ok := i18nCall(file, node)
+	if !ok {
+		return "", false
+	}
+	expr, ok := fCall.Args[0].(*ast.BasicLit)
+	if !ok || expr.Kind != token.STRING {
+		return "", false
+	}
+	// raw strings and escapes are unquoted like the compiler does
+	id, err := strconv.Unquote(expr.Value)
+	return id, err == nil
+}
+
+// i18nCall returns node if it is NewI18nString call with arguments.
+// With config.Packages set only functions of these packages imported by the file are matched.
+func i18nCall(file *ast.File, node ast.Node) (*ast.CallExpr, bool) {
+	fCall, ok := node.(*ast.CallExpr)
+	if !ok || len(fCall.Args) == 0 {
+		return nil, false
+	}
+	switch fun := fCall.Fun.(type) {
+	case *ast.SelectorExpr: //some package's function call or method of a value of its type
+		if fun.Sel.Name != "NewI18nString" {
+			return nil, false
+		}
+		if len(config.Packages) > 0 && !importsI18nPackage(file, qualifierPackage(file, fun.X)) {
+			return nil, false
+		}
+	case *ast.Ident: // function of dot imported package
+		if fun.Name != "NewI18nString" || len(config.Packages) == 0 || !importsI18nPackage(file, ".") {
+			return nil, false
+		}
+	default:
+		return nil, false
+	}
+	return fCall, true
+}
+
+// qualifierPackage returns the package name of x in pkg.NewI18nString or the package of the type of receiver x,
+// "." for types of dot imports. Receivers are resolved by their declarations in the file, fields by struct types
+// of the file with the field name, without type checking; "" is returned when the type is unknown.
+func qualifierPackage(file *ast.File, x ast.Expr) string {
+	switch x := x.(type) {
+	case *ast.Ident:
+		// unresolved identifiers are package names
+		if x.Obj == nil {
+			return x.Name
+		}
+		return typePackage(declaredType(x.Name, x.Obj.Decl))
+	case *ast.SelectorExpr:
+		return typePackage(fieldType(file, x.Sel.Name))
+	}
+	return ""
+}
+
+// declaredType returns type expression of name in its declaration, or the expression it's initialized with.
+func declaredType(name string, decl interface{}) ast.Expr {
+	switch d := decl.(type) {
+	case *ast.Field:
+		return d.Type
+	case *ast.ValueSpec:
+		if d.Type != nil {
+			return d.Type
+		}
+		for i, n := range d.Names {
+			if n.Name == name && i < len(d.Values) {
+				return d.Values[i]
+			}
+		}
+	case *ast.AssignStmt:
+		for i, lhs := range d.Lhs {
+			if id, ok := lhs.(*ast.Ident); ok && id.Name == name && i < len(d.Rhs) {
+				return d.Rhs[i]
+			}
+		}
+		if len(d.Rhs) == 1 {
+			return d.Rhs[0]
+		}
+	}
+	return nil
+}
+
+func fieldType(file *ast.File, name string) ast.Expr {
+	var typ ast.Expr
+	ast.Inspect(file, func(node ast.Node) bool {
+		st, ok := node.(*ast.StructType)
+		if !ok || typ != nil {
+			return typ == nil
+		}
+		for _, f := range st.Fields.List {
+			for _, n := range f.Names {
+				if n.Name == name {
+					typ = f.Type
 				}
 			}
 		}
+		return typ == nil
+	})
+	return typ
+}
+
+// typePackage returns the package qualifying type expression, values built by pkg.New() are of a type of pkg.
+func typePackage(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return typePackage(e.X)
+	case *ast.StarExpr:
+		return typePackage(e.X)
+	case *ast.UnaryExpr:
+		return typePackage(e.X)
+	case *ast.CompositeLit:
+		return typePackage(e.Type)
+	case *ast.CallExpr:
+		if id, ok := e.Fun.(*ast.Ident); ok && id.Name == "new" && len(e.Args) == 1 {
+			return typePackage(e.Args[0])
+		}
+		return typePackage(e.Fun)
+	case *ast.SelectorExpr:
+		if id, ok := e.X.(*ast.Ident); ok && id.Obj == nil {
+			return id.Name
+		}
+	case *ast.Ident:
+		if e.Obj == nil {
+			return "."
+		}
 	}
-	return v
+	return ""
 }
 
-func (v *FuncVisitor) MakeJson() string {
-	storage := []map[string]string{}
-	for v, _ := range v.funcNames {
-		m := map[string]string{}
-		m["id"] = v
-		m["translation"] = v
-		storage = append(storage, m)
+var majorVersion = regexp.MustCompile(`^v[0-9]+$`)
+
+// defaultImportName is the package name of an unnamed import, the last element of its path
+// without major version suffixes of modules, /v2, and of gopkg.in paths, .v2.
+func defaultImportName(importPath string) string {
+	name := path.Base(importPath)
+	if majorVersion.MatchString(name) && path.Dir(importPath) != "." {
+		name = path.Base(path.Dir(importPath))
+	}
+	if strings.HasPrefix(importPath, "gopkg.in/") {
+		if i := strings.LastIndex(name, ".v"); i > 0 && majorVersion.MatchString(name[i+1:]) {
+			name = name[:i]
+		}
 	}
+	return name
+}
 
-	s, err := json.MarshalIndent(storage, "", "  ")
-	if err != nil {
+// importsI18nPackage reports whether the file imports one of config.Packages under the name.
+func importsI18nPackage(file *ast.File, name string) bool {
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		importName := defaultImportName(importPath)
+		if imp.Name != nil {
+			importName = imp.Name.Name
+		}
+		if importName != name {
+			continue
+		}
+		for _, p := range config.Packages {
+			if p == importPath {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isLocalizationSource(path string) bool {
+	return strings.HasSuffix(filepath.ToSlash(path), "api/i18n.go")
+}
+
+// MakeJson returns json of all keys, uploads stream them with writeKeys instead.
+func (v *FuncVisitor) MakeJson() string {
+	buf := bytes.NewBuffer(nil)
+	if err := writeKeys(buf, v.Ids(), v.translation); err != nil {
 		log.Fatal(err)
 	}
+	return buf.String()
+}
+
+// translation returns source of the key uploaded for id of Ids.
+func (v *FuncVisitor) translation(id string) *Translation {
+	if _, ok := v.funcNames[id]; ok {
+		return v.source(id)
+	}
+	return v.seeds[id]
+}
 
-	return string(s)
+// serviceIds returns sorted ids of keys by service folder they were found in, a key found in several
+// services is in each of them. Keys of seeds only are in the "" service.
+func (v *FuncVisitor) serviceIds() map[string][]string {
+	ids := map[string][]string{}
+	for _, id := range v.Ids() {
+		if _, ok := v.funcNames[id]; !ok {
+			ids[""] = append(ids[""], id)
+			continue
+		}
+		for service := range v.services[id] {
+			ids[service] = append(ids[service], id)
+		}
+	}
+	return ids
 }
 
 func findLocalizedStrings(path string, info os.FileInfo, err error) error {
 	if err != nil {
-		log.Print(err)
+		v.AddDiagnostic(token.Position{Filename: path}, err.Error())
 		return nil
 	}
-	if strings.HasSuffix(path, "api/i18n.go") {
-		go func() {
-			v.wg.Add(1)
-			defer v.wg.Done()
-			fset := token.NewFileSet()
-			file, err := parser.ParseFile(fset, path, nil, 0)
-			if err != nil {
-				log.Print(err)
-			}
-			ast.Walk(v, file)
-		}()
+	if walkIgnore != nil && walkIgnore.skip(path, info) {
+		if info.IsDir() {
+			return filepath.SkipDir
+		}
+		return nil
 	}
+	if isNestedModule(path, info) {
+		return filepath.SkipDir
+	}
+	if !isScannedFile(path, info) {
+		return nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		v.AddDiagnostic(token.Position{Filename: path}, err.Error())
+		return nil
+	}
+	scanAsync(path, data, isLocalizationSource(path))
 	return nil
 }
+
+// isScannedFile reports whether extraction parses the file: i18n sources, files of const packages and
+// go files with extraction markers.
+func isScannedFile(path string, info os.FileInfo) bool {
+	if info.IsDir() {
+		return false
+	}
+	return isLocalizationSource(path) || inConstPackage(path) || hasExtractionMarkers(path, info)
+}
+
+// scanAsync scans source of the file in background, v.wg waits for it.
+func scanAsync(path string, data []byte, isSource bool) {
+	all := v
+	all.wg.Add(1)
+	go func() {
+		defer all.wg.Done()
+		// the file is scanned by its own visitor to know the service of found keys
+		f := NewFuncVisit()
+		scanFile(f, path, data, isSource)
+		all.merge(f, serviceOf(path))
+	}()
+}
+
+func scanFile(v *FuncVisitor, path string, data []byte, isSource bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, data, parser.ParseComments)
+	if err != nil {
+		addParseDiagnostics(v, path, err)
+		return
+	}
+	directives := parseDirectives(fset, file)
+	if isSource {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if id, ok := i18nStringId(file, node); ok {
+				v.AddLocation(id, fset.Position(node.Pos()).String())
+			} else if call, ok := i18nCall(file, node); ok {
+				v.AddDiagnostic(fset.Position(call.Args[0].Pos()), "id of NewI18nString should be a string literal")
+			}
+			return true
+		})
+		extractDeprecations(v, fset, file, directives)
+		extractVariants(v, fset, file, directives)
+	}
+	if inConstPackage(path) {
+		extractConstants(v, fset, file, directives)
+	}
+	extractTables(v, fset, file, directives)
+	extractStructTags(v, fset, file)
+	extractMessages(v, fset, file)
+}
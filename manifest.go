@@ -0,0 +1,55 @@
+package i18n_gen
+
+import (
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MANIFEST_FILE lists locale files of a data folder with their sha256 for downstream integrity checks.
+const MANIFEST_FILE = "manifest.json"
+
+type ManifestEntry struct {
+	Project string `json:"project"`
+	Locale  string `json:"locale"`
+	// File is relative to the folder of the manifest.
+	File   string `json:"file"`
+	Sha256 string `json:"sha256"`
+	Size   int    `json:"size"`
+	// Overrides are keys whose translations come from the overrides folder of the config.
+	Overrides []string `json:"overrides,omitempty"`
+}
+
+// writeManifest describes locale files in dir as they are at the end of the run,
+// so stubbed and generated locales are covered as well.
+func writeManifest(dir string) {
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", dir, err)
+		return
+	}
+	sort.Strings(files)
+	entries := []*ManifestEntry{}
+	for _, path := range files {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Println("WARNING! Unable to read locale file", path, err)
+			continue
+		}
+		rel, _ := filepath.Rel(dir, path)
+		entries = append(entries, &ManifestEntry{
+			Project: filepath.Base(filepath.Dir(path)),
+			Locale:  strings.TrimSuffix(filepath.Base(path), ".json"),
+			File:    filepath.ToSlash(rel),
+			Sha256:  dataSha256(data),
+			Size:    len(data),
+			// overrides are in-repo changes of phraseapp content, consumers may tell them apart
+			Overrides: overriddenKeys[path],
+		})
+	}
+	if err := writeJsonFile(filepath.Join(dir, MANIFEST_FILE), entries); err != nil {
+		log.Println("WARNING! Unable to write manifest", dir, err)
+	}
+}
@@ -0,0 +1,122 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const MERGED_SUFFIX = "_merged"
+
+var mergeProjects bool
+
+// mergeProjectFolders deep-merges locale files of all projects in dir into dir+MERGED_SUFFIX,
+// one file per locale. The default project goes first, other projects in name order, and
+// the first translation of a key wins, differing translations are reported as collisions.
+func mergeProjectFolders(dir string) {
+	projects, err := ioutil.ReadDir(dir)
+	if err != nil {
+		log.Println("WARNING! Unable to list projects to merge", dir, err)
+		return
+	}
+	names := []string{}
+	for _, p := range projects {
+		if p.IsDir() {
+			names = append(names, p.Name())
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == defaultProject) != (names[j] == defaultProject) {
+			return names[i] == defaultProject
+		}
+		return names[i] < names[j]
+	})
+
+	merged := map[string]map[string]*Translation{}
+	origins := map[string]string{}
+	for _, projectName := range names {
+		files, err := filepath.Glob(filepath.Join(dir, projectName, "*.json"))
+		if err != nil {
+			log.Println("WARNING! Unable to list locale files", projectName, err)
+			continue
+		}
+		for _, path := range files {
+			localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+			data, err := ioutil.ReadFile(path)
+			if err != nil {
+				log.Println("WARNING! Unable to read locale file", path, err)
+				continue
+			}
+			translations, err := ParseLocaleFile(data)
+			if err != nil {
+				log.Println("WARNING! Unable to parse locale file", path, err)
+				continue
+			}
+			if merged[localeName] == nil {
+				merged[localeName] = map[string]*Translation{}
+			}
+			for _, t := range translations {
+				origin := localeName + ":" + t.ID
+				existing, ok := merged[localeName][t.ID]
+				if !ok {
+					merged[localeName][t.ID] = t
+					origins[origin] = projectName
+					continue
+				}
+				if err := mergeTranslation(existing, t); err != nil {
+					report.AddIssue(&Issue{Project: projectName, Locale: localeName, Key: t.ID, Rule: "merge-collision",
+						Message: fmt.Sprintf("%v, translation of %s is kept", err, origins[origin])})
+				}
+			}
+		}
+	}
+
+	mergedDir := dir + MERGED_SUFFIX
+	if err := removeContents(mergedDir); err != nil && !os.IsNotExist(err) {
+		fail(fmt.Errorf("Unable to clean merged locales %s, %v", mergedDir, err))
+	}
+	if err := mkdirOutput(mergedDir); err != nil {
+		fail(fmt.Errorf("Unable to create folder for merged locales %s, %v", mergedDir, err))
+	}
+	for localeName, byId := range merged {
+		translations := make([]*Translation, 0, len(byId))
+		for _, t := range byId {
+			translations = append(translations, t)
+		}
+		sort.Slice(translations, func(i, j int) bool { return translations[i].ID < translations[j].ID })
+		encoded, err := json.MarshalIndent(translations, "", "  ")
+		if err != nil {
+			fail(fmt.Errorf("Unable to encode merged locale %s, %v", localeName, err))
+		}
+		if err := writeOutputFile(filepath.Join(mergedDir, localeName+".json"), encoded); err != nil {
+			fail(fmt.Errorf("Unable to write merged locale %s, %v", localeName, err))
+		}
+	}
+	log.Println(len(merged), "locales of", len(names), "projects were merged to", mergedDir)
+}
+
+// mergeTranslation adds plural forms of t missing in existing, differing texts are a collision.
+func mergeTranslation(existing, t *Translation) error {
+	if existing.IsPlural() != t.IsPlural() {
+		return fmt.Errorf("plural and singular translations")
+	}
+	if !t.IsPlural() {
+		if existing.Text != t.Text {
+			return fmt.Errorf("translations differ, %q vs %q", existing.Text, t.Text)
+		}
+		return nil
+	}
+	for form, text := range t.Plural {
+		if prev, ok := existing.Plural[form]; !ok {
+			existing.Plural[form] = text
+		} else if prev != text {
+			return fmt.Errorf("%s forms differ, %q vs %q", form, prev, text)
+		}
+	}
+	return nil
+}
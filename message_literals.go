@@ -0,0 +1,210 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func validateMessageTypes(types []string) error {
+	for _, m := range types {
+		if i := strings.LastIndex(m, "."); i <= strings.LastIndex(m, "/") || i == len(m)-1 {
+			return fmt.Errorf("Message type %s should be an import path and a type name, e.g. github.com/nicksnyder/go-i18n/v2/i18n.Message", m)
+		}
+	}
+	return nil
+}
+
+// messageTypeName returns name of the configured message type, Message of github.com/nicksnyder/go-i18n/v2/i18n.Message.
+func messageTypeName(messageType string) string {
+	return messageType[strings.LastIndex(messageType, ".")+1:]
+}
+
+// isMessageType reports whether type expression of a composite literal is one of config.Messages,
+// pointers included, by the import path of its package in the file.
+func isMessageType(file *ast.File, expr ast.Expr) bool {
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	sel, ok := expr.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	pkg, ok := sel.X.(*ast.Ident)
+	if !ok {
+		return false
+	}
+	for _, imp := range file.Imports {
+		importPath, err := strconv.Unquote(imp.Path.Value)
+		if err != nil {
+			continue
+		}
+		importName := path.Base(importPath)
+		if imp.Name != nil {
+			importName = imp.Name.Name
+		}
+		if importName != pkg.Name {
+			continue
+		}
+		for _, m := range config.Messages {
+			if m == importPath+"."+sel.Sel.Name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// extractMessages adds keys defined by literals of config.Messages types: i18n.Message{ID: "...", Other: "..."},
+// elements of slices and maps of these types with elided types included.
+func extractMessages(v *FuncVisitor, fset *token.FileSet, file *ast.File) {
+	if len(config.Messages) == 0 {
+		return
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		lit, ok := node.(*ast.CompositeLit)
+		if !ok || lit.Type == nil {
+			return true
+		}
+		if isMessageType(file, lit.Type) {
+			addMessage(v, fset, lit)
+			return false
+		}
+		var elt ast.Expr
+		switch t := lit.Type.(type) {
+		case *ast.ArrayType:
+			elt = t.Elt
+		case *ast.MapType:
+			elt = t.Value
+		}
+		if elt == nil || !isMessageType(file, elt) {
+			return true
+		}
+		for _, e := range lit.Elts {
+			if kv, ok := e.(*ast.KeyValueExpr); ok {
+				e = kv.Value
+			}
+			if unary, ok := e.(*ast.UnaryExpr); ok && unary.Op == token.AND {
+				e = unary.X
+			}
+			if m, ok := e.(*ast.CompositeLit); ok && m.Type == nil {
+				addMessage(v, fset, m)
+			}
+		}
+		return true
+	})
+}
+
+// addMessage adds the key of the message literal, with One set its source text is plural.
+func addMessage(v *FuncVisitor, fset *token.FileSet, lit *ast.CompositeLit) {
+	fields := map[string]string{}
+	hasId := false
+	for _, e := range lit.Elts {
+		kv, ok := e.(*ast.KeyValueExpr)
+		if !ok {
+			v.AddDiagnostic(fset.Position(lit.Pos()), "message literal should name its fields")
+			return
+		}
+		name, ok := kv.Key.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		switch name.Name {
+		case "ID":
+			hasId = true
+		case "Description", "One", "Other":
+		default:
+			continue
+		}
+		value, ok := kv.Value.(*ast.BasicLit)
+		if !ok || value.Kind != token.STRING {
+			v.AddDiagnostic(fset.Position(kv.Value.Pos()), fmt.Sprintf("%s of message should be a string literal", name.Name))
+			continue
+		}
+		text, err := strconv.Unquote(value.Value)
+		if err != nil {
+			v.AddDiagnostic(fset.Position(value.Pos()), err.Error())
+			continue
+		}
+		fields[name.Name] = text
+	}
+	id := fields["ID"]
+	if id == "" {
+		// IDs other than string literals are reported already
+		if !hasId {
+			v.AddDiagnostic(fset.Position(lit.Pos()), "message literal without ID")
+		}
+		return
+	}
+	t := &Translation{ID: id, Text: fields["Other"]}
+	if fields["One"] != "" {
+		t.Text, t.Plural = "", map[string]string{"one": fields["One"], "other": fields["Other"]}
+	}
+	if t.Text == "" && t.Plural == nil {
+		t.Text = id
+	}
+	v.AddLocation(id, fset.Position(lit.Pos()).String())
+	v.AddMessage(t, fields["Description"])
+}
+
+// AddMessage adds source text and description of a key defined by a message literal.
+func (v *FuncVisitor) AddMessage(t *Translation, description string) {
+	v.Lock()
+	defer v.Unlock()
+	v.messages[t.ID] = t
+	if description != "" {
+		v.descriptions[t.ID] = description
+	}
+}
+
+// source returns text uploaded for the extracted id, message literals define their own.
+func (v *FuncVisitor) source(id string) *Translation {
+	if t, ok := v.messages[id]; ok {
+		return t
+	}
+	return &Translation{ID: id, Text: sourceText(id)}
+}
+
+// syncMessageDescriptions sets descriptions of message literals, and placeholders of -placeholder-metadata,
+// as descriptions of phraseapp keys unless the key already has the same description.
+func syncMessageDescriptions(worker *PhraseappWorkerContext, projectName string) {
+	if len(v.descriptions) == 0 && !placeholderMetadata {
+		return
+	}
+	localCtx := &i18nGenContext{}
+	projectId := phraseappProjects[projectName]
+	current, err := worker.KeyDescriptions(localCtx, projectId, projectName)
+	if err != nil {
+		log.Println("WARNING! Unable to sync message descriptions", err)
+		return
+	}
+	wanted := keyDescriptions(projectName, current)
+	ids := make([]string, 0, len(wanted))
+	for id, description := range wanted {
+		if current[id] != description {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	sort.Strings(ids)
+	keyIds, err := worker.KeyIds(localCtx, projectId, projectName)
+	if err != nil {
+		log.Println("WARNING! Unable to sync message descriptions", err)
+		return
+	}
+	for _, id := range ids {
+		if keyIds[id] == "" {
+			continue
+		}
+		if err := worker.SetKeyDescription(localCtx, projectId, projectName, keyIds[id], wanted[id]); err != nil {
+			log.Println("WARNING!", err)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// MODULE_CONFIG_FILE at the module root is the config of extract-verify unless -config is given.
+	MODULE_CONFIG_FILE = "i18n_gen.json"
+	MODULE_KEYS_FILE   = "i18n_keys.json"
+)
+
+var (
+	// moduleRoot limits extraction to a single go module, nested modules of other services are skipped.
+	moduleRoot string
+	keysFile   string
+	checkKeys  bool
+)
+
+var extractVerifyCommand = &command{
+	name:        "extract-verify",
+	description: "extract keys of the go module of the working directory to its keys file, for //go:generate i18n_gen extract-verify",
+	setFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&configPath, "config", "", "path to json config file, "+MODULE_CONFIG_FILE+" of the module root if it exists")
+		fs.StringVar(&keysFile, "out", MODULE_KEYS_FILE, "keys file relative to the module root")
+		fs.BoolVar(&strictExtract, "strict-extract", false, "fail on parse errors and invalid markers found by extraction")
+		fs.BoolVar(&checkKeys, "check", false, "fail instead of writing the keys file when it is out of date")
+	},
+	run: runExtractVerify,
+}
+
+// findModuleRoot returns the closest folder with go.mod containing dir.
+func findModuleRoot(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, "go.mod")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("Unable to find go.mod of the module")
+		}
+		dir = parent
+	}
+}
+
+func isNestedModule(path string, info os.FileInfo) bool {
+	if moduleRoot == "" || !info.IsDir() || path == moduleRoot {
+		return false
+	}
+	_, err := os.Stat(filepath.Join(path, "go.mod"))
+	return err == nil
+}
+
+func runExtractVerify(fs *flag.FlagSet) {
+	wd, err := os.Getwd()
+	if err != nil {
+		log.Fatalln("Unable to get working directory", err)
+	}
+	root, err := findModuleRoot(wd)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	moduleRoot, basepath = root, root
+	if configPath == "" {
+		if _, err := os.Stat(filepath.Join(root, MODULE_CONFIG_FILE)); err == nil {
+			configPath = filepath.Join(root, MODULE_CONFIG_FILE)
+		}
+	}
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config = cfg
+	if err := loadKeyMapping(config.KeyMapping, root); err != nil {
+		log.Fatalln(err)
+	}
+
+	jsonData := GetLocalizationJsonFromSources(root)
+	path := filepath.Join(root, keysFile)
+	existing, err := ioutil.ReadFile(path)
+	if err == nil && string(existing) == jsonData {
+		log.Println("Keys file", path, "is up to date")
+		return
+	}
+	if checkKeys {
+		log.Fatalf("Keys file %s is out of date, please, run go generate\n", path)
+	}
+	if err := writeOutputFile(path, []byte(jsonData)); err != nil {
+		log.Fatalln("Unable to write keys file", path, err)
+	}
+	log.Println("Keys file", path, "was updated")
+}
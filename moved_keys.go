@@ -0,0 +1,205 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// migrateMoved copies translations of keys moved from other projects with -migrate-moved-keys.
+var migrateMoved bool
+
+// movedKey is a key extracted for the first time in To and extracted for From in its previous run.
+type movedKey struct {
+	ID   string
+	From string
+	To   string
+}
+
+// movedKeys are keys moved to the project of this run, translations are migrated once locales are downloaded.
+var movedKeys []*movedKey
+
+// detectMovedKeys reports keys which appeared in the project and are known in another project, when service
+// code moves between projects, and keys which disappeared from the project and are known in another project.
+// Projects are compared with keys of the previous run, not with ones other projects recorded by this run.
+func detectMovedKeys(projectName string, previous, ids []string, known map[string]bool, previousKeys map[string][]string) {
+	others := make([]string, 0, len(previousKeys))
+	for name := range previousKeys {
+		if name != projectName {
+			others = append(others, name)
+		}
+	}
+	if len(others) == 0 {
+		return
+	}
+	sort.Strings(others)
+	byProject := map[string]map[string]bool{}
+	for _, name := range others {
+		byProject[name] = map[string]bool{}
+		for _, id := range previousKeys[name] {
+			byProject[name][id] = true
+		}
+	}
+	current := map[string]bool{}
+	for _, id := range ids {
+		current[id] = true
+		if known[id] {
+			continue
+		}
+		for _, name := range others {
+			if byProject[name][id] {
+				movedKeys = append(movedKeys, &movedKey{ID: id, From: name, To: projectName})
+				report.AddMovedKey(fmt.Sprintf("%s: %s -> %s", id, name, projectName))
+				break
+			}
+		}
+	}
+	for _, id := range previous {
+		if current[id] {
+			continue
+		}
+		for _, name := range others {
+			if byProject[name][id] {
+				report.AddMovedKey(fmt.Sprintf("%s: %s -> %s", id, projectName, name))
+				break
+			}
+		}
+	}
+}
+
+func (r *RunReport) AddMovedKey(moved string) {
+	r.MovedKeys = appendUnique(r.MovedKeys, moved)
+}
+
+// migrateMovedKeys adds translations of keys moved to the project from downloaded locales of projects
+// they were moved from, for locales missing them. Existing translations of the project are kept.
+func migrateMovedKeys(worker *PhraseappWorkerContext, projectName string) {
+	byLocale := map[string][]*Translation{}
+	for _, m := range movedKeys {
+		if m.To != projectName {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), m.From, "*.json"))
+		if err != nil {
+			log.Println("WARNING! Unable to list locale files", m.From, err)
+			continue
+		}
+		for _, path := range files {
+			localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+			if t := movedTranslation(path, m.ID); t != nil {
+				byLocale[localeName] = append(byLocale[localeName], t)
+			}
+		}
+	}
+	if len(byLocale) == 0 {
+		return
+	}
+	keyIds, localeIds, err := translationTargets(worker, projectName)
+	if err != nil {
+		log.Println("WARNING! Unable to migrate moved keys", err)
+		return
+	}
+	locales := make([]string, 0, len(byLocale))
+	for l := range byLocale {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	for _, localeName := range locales {
+		path := filepath.Join(getLocalizationFolderName(), projectName, localeName+".json")
+		if err := migrateLocaleFile(worker, projectName, localeName, path, byLocale[localeName], keyIds, localeIds); err != nil {
+			log.Println("WARNING! Unable to migrate moved keys", projectName, localeName, err)
+		}
+	}
+}
+
+// movedTranslation returns translation of id in the locale file, nil if it is missing or untranslated.
+func movedTranslation(path, id string) *Translation {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println("WARNING! Unable to read locale file", path, err)
+		return nil
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		log.Println("WARNING! Unable to parse locale file", path, err)
+		return nil
+	}
+	for _, t := range translations {
+		if t.ID == id && !t.IsUntranslated() {
+			return t
+		}
+	}
+	return nil
+}
+
+func migrateLocaleFile(worker *PhraseappWorkerContext, projectName, localeName, path string,
+	moved []*Translation, keyIds, localeIds map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		return err
+	}
+	byId := map[string]*Translation{}
+	for _, t := range translations {
+		byId[t.ID] = t
+	}
+	migrated := 0
+	for _, m := range moved {
+		if t, ok := byId[m.ID]; ok && !t.IsUntranslated() {
+			continue
+		}
+		keyId, localeId := keyIds[m.ID], localeIds[localeName]
+		if keyId == "" || localeId == "" {
+			log.Println("WARNING! Unable to migrate moved key, key or locale is unknown", projectName, localeName, m.ID)
+			continue
+		}
+		if err := addMovedTranslation(worker, projectName, keyId, localeId, m); err != nil {
+			log.Println("WARNING!", err)
+			continue
+		}
+		audit(AUDIT_TRANSLATION, projectName, localeName, m.ID)
+		if t, ok := byId[m.ID]; ok {
+			t.Text, t.Plural = m.Text, m.Plural
+		} else {
+			translations = append(translations, m)
+			byId[m.ID] = m
+		}
+		migrated++
+	}
+	if migrated == 0 {
+		return nil
+	}
+	logLocalef(projectName, localeName, "%d translations of moved keys were migrated to %s %s\n", migrated, projectName, localeName)
+	encoded, err := json.MarshalIndent(translations, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutputFile(path, encoded)
+}
+
+// addMovedTranslation creates the translation in the project, every form of plural keys separately.
+// Migrated translations are added verified, services used them before the move.
+func addMovedTranslation(worker *PhraseappWorkerContext, projectName, keyId, localeId string, t *Translation) error {
+	projectId := phraseappProjects[projectName]
+	if !t.IsPlural() {
+		return worker.AddTranslation(&i18nGenContext{}, projectId, projectName, keyId, localeId, "", t.Text, false)
+	}
+	forms := make([]string, 0, len(t.Plural))
+	for form := range t.Plural {
+		forms = append(forms, form)
+	}
+	sort.Strings(forms)
+	for _, form := range forms {
+		if err := worker.AddTranslation(&i18nGenContext{}, projectId, projectName, keyId, localeId, form, t.Plural[form], false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/phrase/phraseapp-go/phraseapp"
+	"gopkg.in/yaml.v2"
+)
+
+// NOTE_PREFIX marks comments pushed from the notes file, other comments are translator questions.
+const NOTE_PREFIX = "[i18n_gen note] "
+
+type (
+	// KeyNotes are the developer note of a key and translator questions pulled from phraseapp comments.
+	KeyNotes struct {
+		Note      string      `yaml:"note,omitempty"`
+		Questions []*Question `yaml:"questions,omitempty"`
+	}
+
+	Question struct {
+		ID      string    `yaml:"id"`
+		From    string    `yaml:"from"`
+		At      time.Time `yaml:"at"`
+		Message string    `yaml:"message"`
+	}
+)
+
+// syncNotes pushes notes of config.Notes file to phraseapp as key comments and pulls
+// other comments of the listed keys back into the file as questions.
+func syncNotes(worker *PhraseappWorkerContext, projectName string) {
+	path := config.Notes
+	notes := map[string]*KeyNotes{}
+	data, err := readSourceFile(basepath, path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(basepath, path)
+	}
+	if os.IsNotExist(err) {
+		log.Println("WARNING! Notes file doesn't exist", path)
+		return
+	}
+	if err != nil {
+		log.Println("WARNING! Unable to read notes", path, err)
+		return
+	}
+	if err := yaml.Unmarshal(data, &notes); err != nil {
+		log.Println("WARNING! Unable to parse notes", path, err)
+		return
+	}
+
+	localCtx := &i18nGenContext{}
+	projectId := phraseappProjects[projectName]
+	keyIds, err := worker.KeyIds(localCtx, projectId, projectName)
+	if err != nil {
+		log.Println("WARNING! Unable to sync notes", err)
+		return
+	}
+	names := make([]string, 0, len(notes))
+	for name := range notes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		n := notes[name]
+		if n == nil {
+			n = &KeyNotes{}
+			notes[name] = n
+		}
+		keyId, ok := keyIds[name]
+		if !ok {
+			log.Println("WARNING! Note refers to unknown key", projectName, name)
+			continue
+		}
+		comments, err := worker.KeyComments(localCtx, projectId, projectName, keyId)
+		if err != nil {
+			log.Println("WARNING! Unable to sync notes", err)
+			continue
+		}
+		noteComments := []*phraseapp.Comment{}
+		n.Questions = nil
+		for _, comment := range comments {
+			if strings.HasPrefix(comment.Message, NOTE_PREFIX) {
+				noteComments = append(noteComments, comment)
+				continue
+			}
+			n.Questions = append(n.Questions, newQuestion(comment))
+		}
+		pushNote(worker, localCtx, projectId, projectName, keyId, n.Note, noteComments)
+	}
+
+	encoded, err := yaml.Marshal(notes)
+	if err != nil {
+		fail(fmt.Errorf("Unable to encode notes, %v", err))
+	}
+	if err := writeOutputFile(path, encoded); err != nil {
+		log.Println("WARNING! Unable to write notes", path, err)
+	}
+}
+
+// pushNote keeps the note in a single comment of the key: an edited note updates the previous note comment,
+// other note comments, all of them for a removed note, are deleted.
+func pushNote(worker *PhraseappWorkerContext, ctx PhraseappContexter, projectId, projectName, keyId, note string, noteComments []*phraseapp.Comment) {
+	var kept *phraseapp.Comment
+	for _, comment := range noteComments {
+		if note != "" && comment.Message == NOTE_PREFIX+note {
+			kept = comment
+			break
+		}
+	}
+	if kept == nil && note != "" {
+		var err error
+		if len(noteComments) > 0 {
+			kept = noteComments[0]
+			err = worker.UpdateComment(ctx, projectId, projectName, keyId, kept.ID, NOTE_PREFIX+note)
+		} else {
+			err = worker.AddComment(ctx, projectId, projectName, keyId, NOTE_PREFIX+note)
+		}
+		if err != nil {
+			log.Println("WARNING! Unable to push note", err)
+			return
+		}
+	}
+	for _, comment := range noteComments {
+		if comment == kept {
+			continue
+		}
+		if err := worker.DeleteComment(ctx, projectId, projectName, keyId, comment.ID); err != nil {
+			log.Println("WARNING! Unable to delete previous note", err)
+		}
+	}
+}
+
+func newQuestion(comment *phraseapp.Comment) *Question {
+	q := &Question{ID: comment.ID, Message: comment.Message}
+	if comment.User != nil {
+		q.From = comment.User.Name
+	}
+	if comment.CreatedAt != nil {
+		q.At = *comment.CreatedAt
+	}
+	return q
+}
@@ -0,0 +1,72 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// localeLogLine is a message about a locale of a project, empty locale stands for the whole project.
+type localeLogLine struct {
+	project string
+	locale  string
+	line    string
+}
+
+var localeLogMu sync.Mutex
+
+// logLocale logs like log.Println, messages are printed grouped by project and locale at the end of the run
+// so logs of runs compare line by line, -verbose streams them as well.
+func logLocale(projectName, localeName string, v ...interface{}) {
+	addLocaleLog(projectName, localeName, strings.TrimSuffix(fmt.Sprintln(v...), "\n"))
+}
+
+// logLocalef logs like log.Printf, see logLocale.
+func logLocalef(projectName, localeName, format string, v ...interface{}) {
+	addLocaleLog(projectName, localeName, strings.TrimSuffix(fmt.Sprintf(format, v...), "\n"))
+}
+
+func addLocaleLog(projectName, localeName, line string) {
+	if verbose {
+		log.Println(line)
+	}
+	localeLogMu.Lock()
+	defer localeLogMu.Unlock()
+	report.localeLog = append(report.localeLog, &localeLogLine{projectName, localeName, line})
+}
+
+// localeLogLines returns messages of logLocale ordered by project and locale, messages of a locale keep their order.
+func (r *RunReport) localeLogLines() []string {
+	localeLogMu.Lock()
+	sorted := append([]*localeLogLine{}, r.localeLog...)
+	localeLogMu.Unlock()
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].project != sorted[j].project {
+			return sorted[i].project < sorted[j].project
+		}
+		return sorted[i].locale < sorted[j].locale
+	})
+	lines := []string{}
+	group := ""
+	for i, l := range sorted {
+		if name := strings.TrimSpace(l.project + " " + l.locale); i == 0 || name != group {
+			group = name
+			lines = append(lines, "  "+name+":")
+		}
+		lines = append(lines, "    "+l.line)
+	}
+	return lines
+}
+
+func (r *RunReport) printLocaleLog() {
+	lines := r.localeLogLines()
+	if len(lines) == 0 {
+		return
+	}
+	log.Println("Log by project and locale:")
+	for _, line := range lines {
+		log.Println(line)
+	}
+}
@@ -0,0 +1,111 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"path"
+	"path/filepath"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+)
+
+// OutputEncoding re-escapes strings of json files written to paths matching Path, a glob relative to -path
+// like localized_data/Web/*.json. Strings are raw UTF-8 unless Ascii is set, EscapeHtml escapes <, > and &
+// as \u003c, \u003e and \u0026, entities like &amp; are kept as they are.
+type OutputEncoding struct {
+	Path       string `json:"path"`
+	Ascii      bool   `json:"ascii"`
+	EscapeHtml bool   `json:"escape_html"`
+}
+
+func validateOutputEncodings(encodings []*OutputEncoding) error {
+	for _, e := range encodings {
+		if e.Path == "" {
+			return fmt.Errorf("Output encoding needs a path")
+		}
+		if _, err := path.Match(e.Path, ""); err != nil {
+			return fmt.Errorf("Invalid output encoding path %s, %v", e.Path, err)
+		}
+	}
+	return nil
+}
+
+// outputEncoding returns the first encoding matching the written file, nil if it is written as is.
+func outputEncoding(p string) *OutputEncoding {
+	if config.Output == nil || !strings.HasSuffix(p, ".json") {
+		return nil
+	}
+	if rel, err := filepath.Rel(basepath, p); err == nil && !strings.HasPrefix(rel, "..") {
+		p = rel
+	}
+	p = filepath.ToSlash(p)
+	for _, e := range config.Output.Encodings {
+		if ok, _ := path.Match(e.Path, p); ok {
+			return e
+		}
+	}
+	return nil
+}
+
+// encodeOutput re-escapes strings of json data written to path, formatting is kept. Encoding is idempotent,
+// so downloads hash encoded data before writing it. Malformed json is written as is.
+func encodeOutput(path string, data []byte) []byte {
+	e := outputEncoding(path)
+	if e == nil || !json.Valid(data) {
+		return data
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	for i := 0; i < len(data); {
+		if data[i] != '"' {
+			out.WriteByte(data[i])
+			i++
+			continue
+		}
+		end := i + 1
+		for data[end] != '"' {
+			if data[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		var s string
+		if err := json.Unmarshal(data[i:end+1], &s); err != nil {
+			return data
+		}
+		e.writeString(out, s)
+		i = end + 1
+	}
+	return out.Bytes()
+}
+
+func (e *OutputEncoding) writeString(out *bytes.Buffer, s string) {
+	out.WriteByte('"')
+	for _, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(r)
+		case r == '\n':
+			out.WriteString(`\n`)
+		case r == '\r':
+			out.WriteString(`\r`)
+		case r == '\t':
+			out.WriteString(`\t`)
+		case r < 0x20 || r == '\u2028' || r == '\u2029':
+			fmt.Fprintf(out, `\u%04x`, r)
+		case e.EscapeHtml && (r == '<' || r == '>' || r == '&'):
+			fmt.Fprintf(out, `\u%04x`, r)
+		case e.Ascii && r > 0x7e:
+			if r1, r2 := utf16.EncodeRune(r); r1 != unicode.ReplacementChar {
+				fmt.Fprintf(out, `\u%04x\u%04x`, r1, r2)
+			} else {
+				fmt.Fprintf(out, `\u%04x`, r)
+			}
+		default:
+			out.WriteRune(r)
+		}
+	}
+	out.WriteByte('"')
+}
@@ -0,0 +1,110 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// OutputConfig sets modes and owner of written folders and files, modes are octal strings like "0750".
+// The umask applies to modes unless IgnoreUmask is set.
+type OutputConfig struct {
+	DirMode     string `json:"dir_mode"`
+	FileMode    string `json:"file_mode"`
+	IgnoreUmask bool   `json:"ignore_umask"`
+	// Owner is numeric uid:gid, -1 keeps the id unchanged.
+	Owner     string            `json:"owner"`
+	Encodings []*OutputEncoding `json:"encodings"`
+}
+
+func (o *OutputConfig) validate() error {
+	if o == nil {
+		return nil
+	}
+	if _, err := parseMode(o.DirMode, LOCALIZED_DIR_MODE); err != nil {
+		return fmt.Errorf("Invalid output dir_mode %s, %v", o.DirMode, err)
+	}
+	if _, err := parseMode(o.FileMode, LOCALIZED_FILE_MODE); err != nil {
+		return fmt.Errorf("Invalid output file_mode %s, %v", o.FileMode, err)
+	}
+	if _, _, err := parseOwner(o.Owner); err != nil {
+		return fmt.Errorf("Invalid output owner %s, expected uid:gid", o.Owner)
+	}
+	return validateOutputEncodings(o.Encodings)
+}
+
+func parseMode(s string, def os.FileMode) (os.FileMode, error) {
+	if s == "" {
+		return def, nil
+	}
+	mode, err := strconv.ParseUint(s, 8, 32)
+	if err != nil || mode&^uint64(os.ModePerm) != 0 {
+		return 0, fmt.Errorf("expected octal permissions")
+	}
+	return os.FileMode(mode), nil
+}
+
+func parseOwner(s string) (int, int, error) {
+	if s == "" {
+		return -1, -1, nil
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("expected uid:gid")
+	}
+	uid, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err := strconv.Atoi(parts[1])
+	return uid, gid, err
+}
+
+func outputDirMode() os.FileMode {
+	if config.Output == nil {
+		return LOCALIZED_DIR_MODE
+	}
+	mode, _ := parseMode(config.Output.DirMode, LOCALIZED_DIR_MODE)
+	return mode
+}
+
+func outputFileMode() os.FileMode {
+	if config.Output == nil {
+		return LOCALIZED_FILE_MODE
+	}
+	mode, _ := parseMode(config.Output.FileMode, LOCALIZED_FILE_MODE)
+	return mode
+}
+
+// mkdirOutput creates folder path, parents included, the mode and owner are applied to the folder itself.
+func mkdirOutput(path string) error {
+	if err := os.MkdirAll(path, outputDirMode()); err != nil {
+		return err
+	}
+	return applyOutputConfig(path, outputDirMode())
+}
+
+func writeOutputFile(path string, data []byte) error {
+	if err := ioutil.WriteFile(path, encodeOutput(path, data), outputFileMode()); err != nil {
+		return err
+	}
+	return applyOutputConfig(path, outputFileMode())
+}
+
+func applyOutputConfig(path string, mode os.FileMode) error {
+	if config.Output == nil {
+		return nil
+	}
+	if config.Output.IgnoreUmask {
+		if err := os.Chmod(path, mode); err != nil {
+			return err
+		}
+	}
+	uid, gid, _ := parseOwner(config.Output.Owner)
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return os.Chown(path, uid, gid)
+}
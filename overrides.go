@@ -0,0 +1,137 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	// overrides are translations of config.Overrides by runtime locale and project, they win over downloads.
+	overrides map[string]map[string][]*Translation
+	// overriddenKeys are keys replaced by overrides by locale file path, listed by the manifest.
+	overriddenKeys map[string][]string
+)
+
+// loadOverrides reads <locale>/<project>.json go-i18n files of the overrides folder, relative to -path.
+func loadOverrides(dir string) error {
+	overrides, overriddenKeys = map[string]map[string][]*Translation{}, map[string][]string{}
+	if dir == "" {
+		return nil
+	}
+	names, err := overrideFiles(dir)
+	if err != nil {
+		return fmt.Errorf("Unable to list overrides %s, %v", dir, err)
+	}
+	for _, name := range names {
+		data, err := readSourceFile(basepath, name)
+		if err != nil {
+			return fmt.Errorf("Unable to read overrides %s, %v", name, err)
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			return fmt.Errorf("Unable to parse overrides %s, %v", name, err)
+		}
+		localeName := filepath.Base(filepath.Dir(name))
+		projectName := strings.TrimSuffix(filepath.Base(name), ".json")
+		if overrides[localeName] == nil {
+			overrides[localeName] = map[string][]*Translation{}
+		}
+		overrides[localeName][projectName] = translations
+	}
+	return nil
+}
+
+// overrideFiles returns sorted names of override files relative to -path.
+func overrideFiles(dir string) ([]string, error) {
+	names := []string{}
+	if sourceFiles != nil {
+		prefix := path.Clean(filepath.ToSlash(dir)) + "/"
+		for name := range sourceFiles {
+			if strings.HasPrefix(name, prefix) && path.Ext(name) == ".json" && strings.Count(name[len(prefix):], "/") == 1 {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		return names, nil
+	}
+	files, err := filepath.Glob(filepath.Join(basepath, dir, "*", "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		rel, err := filepath.Rel(basepath, f)
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, rel)
+	}
+	return names, nil
+}
+
+// applyOverrides replaces translations of the locale with overrides, missing keys are added.
+// It returns the translations and overridden keys, nil if there are none.
+func applyOverrides(projectName, localeName string, translations []*Translation) ([]*Translation, []string) {
+	byId := map[string]*Translation{}
+	for _, t := range translations {
+		byId[t.ID] = t
+	}
+	keys := []string{}
+	for _, o := range overrides[runtimeLocale(localeName)][projectName] {
+		if t, ok := byId[o.ID]; ok {
+			t.Text, t.Plural = o.Text, o.Plural
+		} else {
+			translations = append(translations, &Translation{ID: o.ID, Text: o.Text, Plural: o.Plural})
+		}
+		keys = append(keys, o.ID)
+	}
+	if len(keys) == 0 {
+		return translations, nil
+	}
+	sort.Strings(keys)
+	return translations, keys
+}
+
+// applyOverrideFiles merges overrides into locale files of folder, files not due for download and files
+// filtered by review included. Changed files are written, overridden keys are kept for the manifest.
+func applyOverrideFiles(folder string) {
+	for localeName, projects := range overrides {
+		for projectName := range projects {
+			p := filepath.Join(folder, projectName, localeName+".json")
+			data, err := ioutil.ReadFile(p)
+			if os.IsNotExist(err) {
+				log.Println("WARNING! Overrides have no locale file to apply to", projectName, localeName)
+				continue
+			}
+			if err != nil {
+				log.Println("WARNING! Unable to apply overrides", projectName, localeName, err)
+				continue
+			}
+			translations, err := ParseLocaleFile(data)
+			if err != nil {
+				log.Println("WARNING! Unable to apply overrides", projectName, localeName, err)
+				continue
+			}
+			before, _ := json.MarshalIndent(translations, "", "  ")
+			translations, keys := applyOverrides(projectName, localeName, translations)
+			overriddenKeys[p] = keys
+			after, err := json.MarshalIndent(translations, "", "  ")
+			if err != nil {
+				fail(fmt.Errorf("Unable to encode locale file %s %s, %v", projectName, localeName, err))
+			}
+			// files overridden on download already are left as written, so their checksums still match
+			if string(before) == string(after) {
+				continue
+			}
+			if err := writeOutputFile(p, after); err != nil {
+				fail(fmt.Errorf("Unable to write locale file %s %s, %v", projectName, localeName, err))
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	FIRST_PAGE       = 1 // phraseapp pages are 1-based
+	MAX_PER_PAGE     = 100
+	PAGE_RETRIES     = 3
+	PAGE_RETRY_DELAY = time.Second
+)
+
+// pageRetryDelay grows with attempts of a failed page.
+var pageRetryDelay = PAGE_RETRY_DELAY
+
+// paginate calls fetch for consecutive pages until it returns less than perPage items.
+// Failed pages are retried with growing delay before giving up.
+func paginate(perPage int, fetch func(page, perPage int) (int, error)) error {
+	if perPage < 1 || perPage > MAX_PER_PAGE {
+		return fmt.Errorf("Page size should be between 1 and %d, got %d", MAX_PER_PAGE, perPage)
+	}
+	for page := FIRST_PAGE; ; page++ {
+		n, err := fetchPage(page, perPage, fetch)
+		if err != nil {
+			return err
+		}
+		if n < perPage {
+			return nil
+		}
+	}
+}
+
+func fetchPage(page, perPage int, fetch func(page, perPage int) (int, error)) (int, error) {
+	var err error
+	for attempt := 0; attempt <= PAGE_RETRIES; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * pageRetryDelay)
+		}
+		var n int
+		n, err = fetch(page, perPage)
+		if err == nil {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("Unable to get page %d after %d retries, %v", page, PAGE_RETRIES, err)
+}
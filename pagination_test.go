@@ -0,0 +1,64 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPaginate(t *testing.T) {
+	pageRetryDelay = 0
+	defer func() { pageRetryDelay = PAGE_RETRY_DELAY }()
+
+	tests := []struct {
+		name    string
+		perPage int
+		items   int
+		// failures are failed attempts by page
+		failures map[int]int
+		pages    []int
+		err      string
+	}{
+		{name: "empty", perPage: 10, items: 0, pages: []int{1}},
+		{name: "short last page", perPage: 10, items: 25, pages: []int{1, 2, 3}},
+		{name: "full last page", perPage: 10, items: 20, pages: []int{1, 2, 3}},
+		{name: "retried page", perPage: 10, items: 15, failures: map[int]int{2: PAGE_RETRIES}, pages: []int{1, 2, 2, 2, 2}},
+		{name: "retries exhausted", perPage: 10, items: 15, failures: map[int]int{2: PAGE_RETRIES + 1}, pages: []int{1, 2, 2, 2, 2},
+			err: "Unable to get page 2 after 3 retries"},
+		{name: "page size too small", perPage: 0, err: "Page size should be between"},
+		{name: "page size too big", perPage: MAX_PER_PAGE + 1, err: "Page size should be between"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pages := []int{}
+			err := paginate(tt.perPage, func(page, perPage int) (int, error) {
+				pages = append(pages, page)
+				if tt.failures[page] > 0 {
+					tt.failures[page]--
+					return 0, fmt.Errorf("page %d failed", page)
+				}
+				n := tt.items - (page-FIRST_PAGE)*perPage
+				if n > perPage {
+					n = perPage
+				}
+				if n < 0 {
+					n = 0
+				}
+				return n, nil
+			})
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			if tt.pages == nil {
+				tt.pages = []int{}
+			}
+			if !reflect.DeepEqual(pages, tt.pages) {
+				t.Errorf("expected pages %v, got %v", tt.pages, pages)
+			}
+		})
+	}
+}
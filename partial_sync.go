@@ -0,0 +1,87 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// onlyDirs are comma separated directories, relative to -path, limiting a sync run.
+var onlyDirs string
+
+// syncDirs returns directories to extract keys from relative to -path, nil for a full run.
+func syncDirs() []string {
+	if onlyDirs == "" {
+		return nil
+	}
+	dirs := []string{}
+	for _, dir := range strings.Split(onlyDirs, ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			dirs = append(dirs, filepath.Clean(dir))
+		}
+	}
+	return dirs
+}
+
+func isPartialSync() bool {
+	return len(syncDirs()) > 0
+}
+
+// syncProjects returns projects synced by the run. Partial run syncs projects keys are uploaded to, the default
+// project and ones of config.KeyRoutes, and projects config.Directories maps the directories of the run to.
+func syncProjects() projectIds {
+	if !isPartialSync() {
+		return phraseappProjects
+	}
+	projects := projectIds{}
+	for _, project := range routeProjects() {
+		projects[project] = phraseappProjects[project]
+	}
+	for _, dir := range syncDirs() {
+		for mapped, project := range config.Directories {
+			if dir == filepath.Clean(mapped) || strings.HasPrefix(dir, filepath.Clean(mapped)+string(filepath.Separator)) {
+				projects[project] = phraseappProjects[project]
+			}
+		}
+	}
+	return projects
+}
+
+func validatePartialSync() error {
+	for dir, project := range config.Directories {
+		if _, ok := phraseappProjects[project]; !ok {
+			return fmt.Errorf("Directory %s is mapped to project %s without -project_id", dir, project)
+		}
+	}
+	for _, dir := range syncDirs() {
+		if sourceFiles != nil {
+			if !hasSourceDir(dir) {
+				return fmt.Errorf("Unable to sync %s, there are no sources in it", dir)
+			}
+			continue
+		}
+		info, err := os.Stat(filepath.Join(basepath, dir))
+		if err != nil {
+			return fmt.Errorf("Unable to sync %s, %v", dir, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("Unable to sync %s, it is not a directory", dir)
+		}
+	}
+	return nil
+}
+
+// clearProjectFolders removes downloaded locales of synced projects only,
+// so a partial run keeps locales of other projects.
+func clearProjectFolders(dir string) error {
+	if !isPartialSync() {
+		return removeContents(dir)
+	}
+	for project := range syncProjects() {
+		if err := os.RemoveAll(filepath.Join(dir, project)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var utf8Bom = []byte{0xEF, 0xBB, 0xBF}
+
+// sanitizePayload strips BOM and normalizes downloaded file to NFC, go-i18n fails to parse
+// files with BOM and NFD strings don't match NFC ids. Invalid UTF-8 can't be fixed and is rejected.
+func sanitizePayload(data []byte) (fixed []byte, findings []string, err error) {
+	if !utf8.Valid(data) {
+		return nil, nil, fmt.Errorf("File is not valid UTF-8 at byte %d", invalidUtf8Offset(data))
+	}
+	if bytes.HasPrefix(data, utf8Bom) {
+		data = data[len(utf8Bom):]
+		findings = append(findings, "BOM was stripped")
+	}
+	if !norm.NFC.IsNormal(data) {
+		data = norm.NFC.Bytes(data)
+		findings = append(findings, "strings were normalized to NFC")
+	}
+	return data, findings, nil
+}
+
+func invalidUtf8Offset(data []byte) int {
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		if r == utf8.RuneError && size <= 1 {
+			return i
+		}
+		i += size
+	}
+	return -1
+}
@@ -1,18 +1,24 @@
-package main
+package i18n_gen
 
 import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"mime/multipart"
+	"log"
 	"net/http"
-	"strconv"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/phrase/phraseapp-go/phraseapp"
 )
 
+const (
+	REVIEWED_STATE = "reviewed"
+	RETRY_DELAY    = time.Second
+)
+
 type (
 	PhraseappWorker interface {
 		// Upload uploads to phraseapp specified locale. Locale is a go-i18n json.
@@ -23,20 +29,81 @@ type (
 
 	PhraseappContexter interface {
 		Projects() map[string]string
+		// ErrorHandler is invoked with errors of upload and download which were retried Retries times.
 		ErrorHandler(error)
+		Retries() int
 		Etag(project, lang string) string
 		OnDownload(project, lang, newEtag string, data []byte)
-		OnUpload(project, lang string)
-		GetLocalesForUpdate() map[string][]string
-		UpdateTranslationFlag() bool
+		OnUpload(project, lang string, part *UploadPart)
+		// GetLocalesForUpdate returns locale jsons keyed by "project:lang", each part is uploaded separately.
+		// Empty lang stands for the default locale of the project in phraseapp.
+		GetLocalesForUpdate() map[string][]*UploadPart
+		// UpdateTranslationFlag tells whether uploads to the project replace existing translations.
+		UpdateTranslationFlag(project string) bool
+		// OnRemoteTranslations is invoked with current translations of the upload locale before an upload
+		// updating translations, an error aborts the upload of the locale.
+		OnRemoteTranslations(project, lang string, remote []*Translation, parts []*UploadPart) error
+		// UploadTags returns comma separated tags to assign to new keys of the upload.
+		UploadTags(project, lang string, part *UploadPart) string
+		// OnEmptyProject is invoked when a project has no locales at all.
+		OnEmptyProject(project string)
+		// LocaleToCreate returns a locale name to create in an empty project, empty string disables creation.
+		LocaleToCreate(project string) string
+		OnLocaleCreate(project, lang string)
+		OnKeyTag(project, key, tag string)
+		// ProductionDownload enables OnProductionDownload with the set of reviewed keys of every downloaded locale.
+		ProductionDownload() bool
+		OnProductionDownload(project, lang string, data []byte, reviewed map[string]bool)
+		// OnApiCall is invoked for every request made to phraseapp with the amount of transferred bytes.
+		OnApiCall(project string, sent, received int64)
+		// Expired reports whether the run is out of time, remaining locales are passed to OnSkip then.
+		Expired() bool
+		OnSkip(project, lang string)
+		// OnSkipped is invoked with upload or download work skipped for a reason, e.g. SKIP_NOT_MODIFIED.
+		OnSkipped(project, lang, operation, reason string)
+		// DownloadDue reports whether the locale is downloaded by this run, locales not due aren't requested.
+		DownloadDue(project, lang string) bool
+		// UploadParams returns extra upload parameters of the project, file, format, locale, translation updates
+		// and tags are set by the worker.
+		UploadParams(project string) phraseapp.UploadParams
+		// DownloadParams returns extra locale download parameters of the project, file format is set by the worker.
+		DownloadParams(project string) phraseapp.LocaleDownloadParams
+		// DownloadSubsets returns tags keyed by subset name, every downloaded locale is downloaded
+		// once more for each tag and passed to OnSubsetDownload.
+		DownloadSubsets(project string) map[string]string
+		OnSubsetDownload(project, lang, subset string, data []byte)
+		// OnTiming is invoked with the duration of a phase of an upload or download, e.g. TIMING_UPLOAD.
+		OnTiming(project, lang, phase string, d time.Duration)
+	}
+
+	// UploadPart is a locale json uploaded at once, Name tells parts of a split upload apart.
+	UploadPart struct {
+		Name string
+		// Path is the json file of the part, in a temporary folder of its own.
+		Path string
+		// Keys is the number of keys in the file.
+		Keys int
 	}
 
 	PhraseappWorkerContext struct {
 		Client *phraseapp.Client
 		Cfg    *phraseapp.Config
+		// Transport of requests made bypassing Client, http.DefaultTransport if nil.
+		Transport http.RoundTripper
 	}
 )
 
+// retry runs op until it succeeds, at most ctx.Retries() times after the first attempt.
+func retry(ctx PhraseappContexter, op func() error) error {
+	err := op()
+	for attempt := 1; err != nil && attempt <= ctx.Retries() && !ctx.Expired(); attempt++ {
+		log.Printf("Retrying after error, attempt %d of %d: %v\n", attempt, ctx.Retries(), err)
+		time.Sleep(time.Duration(attempt) * RETRY_DELAY)
+		err = op()
+	}
+	return err
+}
+
 func NewPhraseappWorker(cfg *phraseapp.Config, client *phraseapp.Client) *PhraseappWorkerContext {
 	return &PhraseappWorkerContext{
 		Client: client,
@@ -47,16 +114,48 @@ func NewPhraseappWorker(cfg *phraseapp.Config, client *phraseapp.Client) *Phrase
 // Upload invokes PhraseappContexter.OnUpload on successful upload.
 func (c *PhraseappWorkerContext) Upload(ctx PhraseappContexter) {
 	locales := ctx.GetLocalesForUpdate()
-	for k, bufs := range locales {
+	defer removeUploadParts(locales)
+	for k, parts := range locales {
 		strs := strings.Split(k, ":")
 		project, lang := strs[0], strs[1]
+		if ctx.Expired() {
+			ctx.OnSkip(project, lang)
+			continue
+		}
 		projectId, ok := ctx.Projects()[project]
 		if !ok {
 			ctx.ErrorHandler(fmt.Errorf("Config is broken, phraseapp project id for %s is not specified", project))
 			continue
 		}
-		for _, buf := range bufs {
-			c.uploadLocaleImpl(ctx, projectId, project, lang, []byte(buf))
+		locales, err := c.ensureLocales(ctx, projectId, project)
+		if err != nil {
+			ctx.ErrorHandler(err)
+			continue
+		}
+		if len(locales) == 0 {
+			ctx.OnSkipped(project, lang, OPERATION_UPLOAD, SKIP_NO_LOCALES)
+			continue
+		}
+		if lang == "" {
+			lang, err = defaultLocaleName(locales, project)
+			if err != nil {
+				ctx.ErrorHandler(err)
+				continue
+			}
+		}
+		if ctx.UpdateTranslationFlag(project) {
+			if err := c.checkRemoteEdits(ctx, projectId, project, lang, locales, parts); err != nil {
+				ctx.ErrorHandler(err)
+				continue
+			}
+		}
+		for _, part := range parts {
+			err = retry(ctx, func() error {
+				return c.uploadLocaleImpl(ctx, projectId, project, lang, part)
+			})
+			if err != nil {
+				ctx.ErrorHandler(err)
+			}
 		}
 	}
 }
@@ -64,13 +163,29 @@ func (c *PhraseappWorkerContext) Upload(ctx PhraseappContexter) {
 // Download invokes PhraseappContexter.OnDownload on successful download.
 func (c *PhraseappWorkerContext) Download(ctx PhraseappContexter) {
 	for name, projectId := range ctx.Projects() {
-		locales, err := c.getLocales(ctx, projectId)
+		if ctx.Expired() {
+			ctx.OnSkip(name, "")
+			continue
+		}
+		locales, err := c.ensureLocales(ctx, projectId, name)
 		if err != nil {
 			ctx.ErrorHandler(err)
 			continue
 		}
+		if len(locales) == 0 {
+			ctx.OnSkipped(name, "", OPERATION_DOWNLOAD, SKIP_NO_LOCALES)
+		}
 		for _, locale := range locales {
-			err = c.downloadLocale(ctx, projectId, name, locale.ID, locale.Name)
+			if ctx.Expired() {
+				ctx.OnSkip(name, locale.Name)
+				continue
+			}
+			if !ctx.DownloadDue(name, locale.Name) {
+				continue
+			}
+			err = retry(ctx, func() error {
+				return c.downloadLocale(ctx, projectId, name, locale.ID, locale.Name)
+			})
 			if err != nil {
 				ctx.ErrorHandler(err)
 			}
@@ -78,35 +193,121 @@ func (c *PhraseappWorkerContext) Download(ctx PhraseappContexter) {
 	}
 }
 
-func (c *PhraseappWorkerContext) getLocales(ctx PhraseappContexter, projectId string) ([]*phraseapp.Locale, error) {
+func (c *PhraseappWorkerContext) getLocales(ctx PhraseappContexter, projectId, project string) ([]*phraseapp.Locale, error) {
 	allLocales := []*phraseapp.Locale{}
-	for i := 0; ; i++ {
-		locales, err := c.Client.LocalesList(projectId, i, *c.Cfg.PerPage)
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		locales, err := c.Client.LocalesList(projectId, page, perPage)
+		ctx.OnApiCall(project, 0, 0)
 		if err != nil {
-			return nil, fmt.Errorf("Unable to get locale list for project %s, %v", projectId, err)
+			return 0, err
 		}
 		allLocales = append(allLocales, locales...)
-		if len(locales) < *c.Cfg.PerPage {
-			break
-		}
+		return len(locales), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get locale list for project %s, %v", projectId, err)
 	}
 	return allLocales, nil
 }
 
+// ensureLocales returns locales of the project. Empty projects are reported to the context
+// and get a locale created if the context asks for it.
+func (c *PhraseappWorkerContext) ensureLocales(ctx PhraseappContexter, projectId, project string) ([]*phraseapp.Locale, error) {
+	locales, err := c.getLocales(ctx, projectId, project)
+	if err != nil || len(locales) > 0 {
+		return locales, err
+	}
+	ctx.OnEmptyProject(project)
+	lang := ctx.LocaleToCreate(project)
+	if lang == "" {
+		return locales, nil
+	}
+	isDefault := true
+	locale, err := c.Client.LocaleCreate(projectId, &phraseapp.LocaleParams{Name: &lang, Code: &lang, Default: &isDefault})
+	ctx.OnApiCall(project, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to create locale %s for project %s, %v", lang, project, err)
+	}
+	ctx.OnLocaleCreate(project, lang)
+	return []*phraseapp.Locale{&locale.Locale}, nil
+}
+
+func defaultLocaleName(locales []*phraseapp.Locale, project string) (string, error) {
+	for _, locale := range locales {
+		if locale.Default {
+			return locale.Name, nil
+		}
+	}
+	return "", fmt.Errorf("Project %s has no default locale, specify upload locale explicitly", project)
+}
+
 func (c *PhraseappWorkerContext) downloadLocale(ctx PhraseappContexter, projectId, project, langId, lang string) error {
 	etag := ctx.Etag(project, lang)
-	data, etag, err := c.downloadLocaleImpl(ctx, projectId, project, langId, lang, etag)
+	if ctx.ProductionDownload() {
+		// review doesn't change file content and etag, so the file is always downloaded
+		etag = ""
+	}
+	data, etag, err := c.downloadLocaleImpl(ctx, projectId, project, langId, lang, etag, "")
 	if err != nil {
 		return err
-	} else if len(data) == 0 {
+	}
+	for subset, tag := range ctx.DownloadSubsets(project) {
+		subsetData, _, err := c.downloadLocaleImpl(ctx, projectId, project, langId, lang, "", tag)
+		if err != nil {
+			return err
+		}
+		ctx.OnSubsetDownload(project, lang, subset, subsetData)
+	}
+	if len(data) == 0 {
+		ctx.OnSkipped(project, lang, OPERATION_DOWNLOAD, SKIP_NOT_MODIFIED)
 		return nil
 	}
 	ctx.OnDownload(project, lang, etag, data)
+	if ctx.ProductionDownload() {
+		reviewed, err := c.reviewedKeys(ctx, projectId, project, langId)
+		if err != nil {
+			return err
+		}
+		ctx.OnProductionDownload(project, lang, data, reviewed)
+	}
 	return nil
 }
 
-func (c *PhraseappWorkerContext) downloadLocaleImpl(ctx PhraseappContexter, projectId, project, langId, lang, etag string) ([]byte, string, error) {
-	params := phraseapp.LocaleDownloadParams{FileFormat: &c.Cfg.DefaultFileFormat}
+// reviewedKeys returns names of keys whose translations (all plural forms) are reviewed.
+func (c *PhraseappWorkerContext) reviewedKeys(ctx PhraseappContexter, projectId, project, langId string) (map[string]bool, error) {
+	reviewed := map[string]bool{}
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		translations, err := c.Client.TranslationsByLocale(projectId, langId, page, perPage, &phraseapp.TranslationsByLocaleParams{})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return 0, err
+		}
+		for _, t := range translations {
+			if t.Key == nil {
+				continue
+			}
+			isReviewed := t.State == REVIEWED_STATE && !t.Unverified && !t.Excluded
+			if prev, ok := reviewed[t.Key.Name]; ok {
+				isReviewed = isReviewed && prev
+			}
+			reviewed[t.Key.Name] = isReviewed
+		}
+		return len(translations), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get translations of project %s locale %s, %v", project, langId, err)
+	}
+	return reviewed, nil
+}
+
+// downloadLocaleImpl is a thin replacement of Client.LocaleDownload, which neither sends
+// If-None-Match nor returns ETag of the response. Non-empty tag replaces the tag of download parameters.
+func (c *PhraseappWorkerContext) downloadLocaleImpl(ctx PhraseappContexter, projectId, project, langId, lang, etag, tag string) ([]byte, string, error) {
+	params := ctx.DownloadParams(project)
+	params.FileFormat = &c.Cfg.DefaultFileFormat
+	if tag != "" {
+		params.Tag = &tag
+	}
 
 	url := fmt.Sprintf("/v2/projects/%s/locales/%s/download", projectId, langId)
 	paramsBuf := bytes.NewBuffer(nil)
@@ -115,99 +316,327 @@ func (c *PhraseappWorkerContext) downloadLocaleImpl(ctx PhraseappContexter, proj
 		return nil, "", fmt.Errorf("Unable to encode url %s, %v, %s, %s", url, err, project, lang)
 	}
 	endpointUrl := c.Client.Credentials.Host + url
-	req, err := http.NewRequest("GET", endpointUrl, paramsBuf)
-	if err != nil {
-		return nil, "", fmt.Errorf("Unable to create request %s, %v, %s, %s", endpointUrl, err, project, lang)
+	// without a local copy the payload of a teammate is reused while phraseapp answers it is not modified
+	var cached []byte
+	key := ""
+	if cacheUrl != "" {
+		key = cacheKey(endpointUrl, paramsBuf.Bytes())
+		if etag == "" {
+			cached, etag = cachedPayload(key)
+		}
+	}
+	sent := int64(paramsBuf.Len())
+	newRequest := func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(requestContext(), "GET", endpointUrl, bytes.NewReader(paramsBuf.Bytes()))
+		if err != nil {
+			return nil, fmt.Errorf("Unable to create request %s, %v, %s, %s", endpointUrl, err, project, lang)
+		}
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Set("User-Agent", phraseapp.GetUserAgent())
+		req.Header.Set("Authorization", "token "+c.Client.Credentials.Token)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		return req, nil
 	}
-	req.Header.Add("Content-Type", "application/json")
-	req.Header.Set("User-Agent", phraseapp.GetUserAgent())
-	req.Header.Set("Authorization", "token "+c.Client.Credentials.Token)
-	if etag != "" {
-		req.Header.Set("If-None-Match", etag)
+	localClient := http.Client{Transport: c.Transport, Timeout: c.Client.Timeout}
+	received := int64(0)
+	defer func() { ctx.OnApiCall(project, sent, received) }()
+	name := lang
+	if tag != "" {
+		name += " " + tag
 	}
-	localClient := http.Client{}
-	resp, err := localClient.Do(req)
+	start := time.Now()
+	resp, err := pollExport(ctx, &localClient, newRequest, project, name)
 	if err != nil {
 		return nil, "", fmt.Errorf("Unable to do http request %s, %v, %s, %s", endpointUrl, err, project, lang)
 	}
+	ctx.OnTiming(project, name, TIMING_DOWNLOAD_WAIT, time.Since(start))
 	defer resp.Body.Close()
-	newEtag, ok := resp.Header["Etag"]
-	if !ok {
-		return nil, "", fmt.Errorf("Expected etag argument %s, %v, %s, %s", endpointUrl, project, lang, resp.Header)
+	newEtag := resp.Header.Get("Etag")
+	if newEtag == "" {
+		// caching proxies may drop the header, the locale is downloaded in full by every run then
+		warnMissingEtag(project, lang)
 	}
 	if resp.StatusCode == 304 {
+		if cached != nil {
+			logLocale(project, lang, "Reused cached locale", project, name)
+			return cached, etag, nil
+		}
 		return nil, "", nil
 	}
 	if resp.StatusCode != 200 {
 		return nil, "", fmt.Errorf("Error on http request  %s, %v, %s, %s", resp.Status, endpointUrl, project, lang)
 	}
-	retVal, err := ioutil.ReadAll(resp.Body)
+	start = time.Now()
+	retVal, err := readPayload(ctx, &localClient, newRequest, resp, project, lang)
+	received = int64(len(retVal))
+	ctx.OnTiming(project, name, TIMING_DOWNLOAD_TRANSFER, time.Since(start))
 	if err != nil {
-		return nil, "", fmt.Errorf("Unable to read body %#v, %s, %s", resp.Body, project, lang)
+		return nil, "", err
+	}
+	if key != "" && newEtag != "" {
+		storeCachedPayload(key, newEtag, retVal)
 	}
 
-	return retVal, newEtag[0], nil
+	return retVal, newEtag, nil
 }
 
-func (c *PhraseappWorkerContext) uploadLocaleImpl(ctx PhraseappContexter, projectId, project, lang string, buf []byte) {
-	url := fmt.Sprintf("/v2/projects/%s/uploads", projectId)
-	paramsBuf := bytes.NewBuffer(nil)
-	writer := multipart.NewWriter(paramsBuf)
-	ctype := writer.FormDataContentType()
+var (
+	missingEtagMu     sync.Mutex
+	missingEtagWarned = map[string]bool{}
+)
 
-	part, err := writer.CreateFormFile("file", lang+".json")
-	if err != nil {
-		ctx.ErrorHandler(err)
+// warnMissingEtag warns once per project about responses without ETag, every locale with -verbose.
+func warnMissingEtag(project, lang string) {
+	missingEtagMu.Lock()
+	defer missingEtagMu.Unlock()
+	if missingEtagWarned[project] && !verbose {
 		return
 	}
-	_, err = part.Write(buf)
+	missingEtagWarned[project] = true
+	log.Println("WARNING! Download response has no ETag header, a proxy may strip it, locales are not cached", project, lang)
+}
+
+func (c *PhraseappWorkerContext) uploadLocaleImpl(ctx PhraseappContexter, projectId, project, lang string, part *UploadPart) error {
+	path, err := part.localePayload(lang)
 	if err != nil {
-		ctx.ErrorHandler(err)
-		return
+		return err
 	}
-	err = writer.WriteField("locale_id", lang)
+	info, err := os.Stat(path)
 	if err != nil {
-		ctx.ErrorHandler(err)
-		return
+		return err
+	}
+
+	updateTranslations := ctx.UpdateTranslationFlag(project)
+	params := ctx.UploadParams(project)
+	params.File = &path
+	params.FileFormat = &c.Cfg.DefaultFileFormat
+	params.LocaleID = &lang
+	params.UpdateTranslations = &updateTranslations
+	if tags := ctx.UploadTags(project, lang, part); tags != "" {
+		params.Tags = &tags
+	}
+	start := time.Now()
+	_, err = c.Client.UploadCreate(projectId, &params)
+	ctx.OnApiCall(project, info.Size(), 0)
+	if part.Name != "" {
+		ctx.OnTiming(project, lang+" "+part.Name, TIMING_UPLOAD, time.Since(start))
+	} else {
+		ctx.OnTiming(project, lang, TIMING_UPLOAD, time.Since(start))
+	}
+	if err != nil && part.Name != "" {
+		return fmt.Errorf("Unable to upload %s part of locale %s of project %s, %v", part.Name, lang, project, err)
 	}
-	err = writer.WriteField("update_translations", strconv.FormatBool(ctx.UpdateTranslationFlag()))
 	if err != nil {
-		ctx.ErrorHandler(err)
-		return
+		return fmt.Errorf("Unable to upload locale %s of project %s, %v", lang, project, err)
 	}
-	err = writer.WriteField("file_format", c.Cfg.DefaultFileFormat)
+	ctx.OnUpload(project, lang, part)
+	return nil
+}
+
+// KeyDescriptions returns descriptions of all keys of the project which have one, keyed by key name.
+func (c *PhraseappWorkerContext) KeyDescriptions(ctx PhraseappContexter, projectId, project string) (map[string]string, error) {
+	descriptions := map[string]string{}
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		keys, err := c.Client.KeysList(projectId, page, perPage, &phraseapp.KeysListParams{})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range keys {
+			if key.Description != "" {
+				descriptions[key.Name] = key.Description
+			}
+		}
+		return len(keys), nil
+	})
 	if err != nil {
-		ctx.ErrorHandler(err)
-		return
+		return nil, fmt.Errorf("Unable to get keys of project %s, %v", project, err)
 	}
-	// Code was taken from original library "github.com/phrase/phraseapp-go/phraseapp/lib.go"
-	err = writer.WriteField("utf8", "✓")
+	return descriptions, nil
+}
+
+// KeyTags returns tags of all keys of the project which have any, keyed by key name.
+func (c *PhraseappWorkerContext) KeyTags(ctx PhraseappContexter, projectId, project string) (map[string][]string, error) {
+	tags := map[string][]string{}
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		keys, err := c.Client.KeysList(projectId, page, perPage, &phraseapp.KeysListParams{})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range keys {
+			if len(key.Tags) > 0 {
+				tags[key.Name] = key.Tags
+			}
+		}
+		return len(keys), nil
+	})
 	if err != nil {
-		ctx.ErrorHandler(err)
-		return
+		return nil, fmt.Errorf("Unable to get keys of project %s, %v", project, err)
 	}
-	writer.Close()
+	return tags, nil
+}
 
-	endpointUrl := c.Client.Credentials.Host + url
-	req, err := http.NewRequest("POST", endpointUrl, paramsBuf)
+// SetKeyDescription replaces description of the key shown to translators.
+func (c *PhraseappWorkerContext) SetKeyDescription(ctx PhraseappContexter, projectId, project, keyId, description string) error {
+	_, err := c.Client.KeyUpdate(projectId, keyId, &phraseapp.TranslationKeyParams{Description: &description})
+	ctx.OnApiCall(project, int64(len(description)), 0)
+	if err != nil {
+		return fmt.Errorf("Unable to describe key %s of project %s, %v", keyId, project, err)
+	}
+	return nil
+}
+
+// CreateKey creates a key without translations with the description.
+func (c *PhraseappWorkerContext) CreateKey(ctx PhraseappContexter, projectId, project, name, description string) error {
+	_, err := c.Client.KeyCreate(projectId, &phraseapp.TranslationKeyParams{Name: &name, Description: &description})
+	ctx.OnApiCall(project, int64(len(name)+len(description)), 0)
 	if err != nil {
-		ctx.ErrorHandler(fmt.Errorf("Unable to create request %s, %v, %s, %s", endpointUrl, err, project, lang))
+		return fmt.Errorf("Unable to create key %s of project %s, %v", name, project, err)
 	}
-	req.Header.Add("Content-Type", ctype)
-	req.Header.Set("User-Agent", phraseapp.GetUserAgent())
-	req.Header.Set("Authorization", "token "+c.Client.Credentials.Token)
+	return nil
+}
 
-	localClient := http.Client{}
-	resp, err := localClient.Do(req)
+// KeyIds returns ids of the project keys by name.
+func (c *PhraseappWorkerContext) KeyIds(ctx PhraseappContexter, projectId, project string) (map[string]string, error) {
+	ids := map[string]string{}
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		keys, err := c.Client.KeysList(projectId, page, perPage, &phraseapp.KeysListParams{})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return 0, err
+		}
+		for _, key := range keys {
+			ids[key.Name] = key.ID
+		}
+		return len(keys), nil
+	})
 	if err != nil {
-		ctx.ErrorHandler(fmt.Errorf("Unable to do http request %s, %v, %s, %s", endpointUrl, err, project, lang))
-		return
+		return nil, fmt.Errorf("Unable to get keys of project %s, %v", project, err)
 	}
-	defer resp.Body.Close()
+	return ids, nil
+}
 
-	if resp.StatusCode != 201 {
-		ctx.ErrorHandler(fmt.Errorf("Error on http request  %s, %v, %s, %s", resp.Status, endpointUrl, project, lang))
-		return
+// KeyComments returns comments of the key, oldest first.
+func (c *PhraseappWorkerContext) KeyComments(ctx PhraseappContexter, projectId, project, keyId string) ([]*phraseapp.Comment, error) {
+	comments := []*phraseapp.Comment{}
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		pageComments, err := c.Client.CommentsList(projectId, keyId, page, perPage, &phraseapp.CommentsListParams{})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return 0, err
+		}
+		comments = append(comments, pageComments...)
+		return len(pageComments), nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Unable to get comments of key %s of project %s, %v", keyId, project, err)
+	}
+	return comments, nil
+}
+
+func (c *PhraseappWorkerContext) AddComment(ctx PhraseappContexter, projectId, project, keyId, message string) error {
+	_, err := c.Client.CommentCreate(projectId, keyId, &phraseapp.CommentParams{Message: &message})
+	ctx.OnApiCall(project, int64(len(message)), 0)
+	if err != nil {
+		return fmt.Errorf("Unable to comment key %s of project %s, %v", keyId, project, err)
+	}
+	return nil
+}
+
+func (c *PhraseappWorkerContext) UpdateComment(ctx PhraseappContexter, projectId, project, keyId, commentId, message string) error {
+	_, err := c.Client.CommentUpdate(projectId, keyId, commentId, &phraseapp.CommentParams{Message: &message})
+	ctx.OnApiCall(project, int64(len(message)), 0)
+	if err != nil {
+		return fmt.Errorf("Unable to update comment %s of key %s of project %s, %v", commentId, keyId, project, err)
+	}
+	return nil
+}
+
+func (c *PhraseappWorkerContext) DeleteComment(ctx PhraseappContexter, projectId, project, keyId, commentId string) error {
+	err := c.Client.CommentDelete(projectId, keyId, commentId)
+	ctx.OnApiCall(project, 0, 0)
+	if err != nil {
+		return fmt.Errorf("Unable to delete comment %s of key %s of project %s, %v", commentId, keyId, project, err)
+	}
+	return nil
+}
+
+// TagKeys adds the tag to keys with given names which don't have it yet.
+func (c *PhraseappWorkerContext) TagKeys(ctx PhraseappContexter, projectId, project string, names []string, tag string) error {
+	wanted := map[string]bool{}
+	for _, name := range names {
+		wanted[name] = true
+	}
+	keys := []*phraseapp.TranslationKey{}
+	err := paginate(*c.Cfg.PerPage, func(page, perPage int) (int, error) {
+		pageKeys, err := c.Client.KeysList(projectId, page, perPage, &phraseapp.KeysListParams{})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return 0, err
+		}
+		keys = append(keys, pageKeys...)
+		return len(pageKeys), nil
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to get keys of project %s, %v", project, err)
+	}
+	for _, key := range keys {
+		if !wanted[key.Name] || hasTag(key.Tags, tag) {
+			continue
+		}
+		tags := strings.Join(append(key.Tags, tag), ",")
+		_, err := c.Client.KeyUpdate(projectId, key.ID, &phraseapp.TranslationKeyParams{Tags: &tags})
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			return fmt.Errorf("Unable to tag key %s of project %s, %v", key.Name, project, err)
+		}
+		ctx.OnKeyTag(project, key.Name, tag)
+	}
+	return nil
+}
+
+// AddTranslation creates translation of the key, plural form is empty for singular keys.
+// Unverified translations await review of translators.
+func (c *PhraseappWorkerContext) AddTranslation(ctx PhraseappContexter, projectId, project, keyId, localeId, form, content string, unverified bool) error {
+	params := &phraseapp.TranslationParams{
+		Content:    &content,
+		KeyID:      &keyId,
+		LocaleID:   &localeId,
+		Unverified: &unverified,
+	}
+	if form != "" {
+		params.PluralSuffix = &form
+	}
+	_, err := c.Client.TranslationCreate(projectId, params)
+	ctx.OnApiCall(project, int64(len(content)), 0)
+	if err != nil {
+		return fmt.Errorf("Unable to translate key %s of project %s, %v", keyId, project, err)
+	}
+	return nil
+}
+
+func hasTag(tags []string, tag string) bool {
+	for _, t := range tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateProjects checks that every project exists and is accessible with the token,
+// errors of all projects are returned at once.
+func (c *PhraseappWorkerContext) ValidateProjects(ctx PhraseappContexter) []error {
+	errs := []error{}
+	for project, projectId := range ctx.Projects() {
+		_, err := c.Client.ProjectShow(projectId)
+		ctx.OnApiCall(project, 0, 0)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Project %s with id %s is not accessible, %v", project, projectId, err))
+		}
 	}
-	ctx.OnUpload(project, lang)
+	return errs
 }
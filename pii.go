@@ -0,0 +1,172 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const QA_RULE_PII = "pii"
+
+// PiiConfig enables checks of source strings and translations for personal data and secrets.
+// Source strings which look like them are not uploaded, translations are checked by QA rule pii.
+type PiiConfig struct {
+	// InternalDomains are domains of internal hosts, e.g. corp.juno.com, besides .internal, .local, .lan and .corp.
+	InternalDomains []string `json:"internal_domains"`
+	// Allow are regexps of matches which are fine, e.g. support@juno\.com.
+	Allow []string `json:"allow"`
+}
+
+type piiPattern struct {
+	kind    string
+	pattern *regexp.Regexp
+}
+
+var piiPatterns = []*piiPattern{
+	{"email", regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)},
+	{"phone number", regexp.MustCompile(`\+?\d[\d ().-]{8,}\d`)},
+	{"api key", regexp.MustCompile(`\b(?:AKIA[0-9A-Z]{16}|(?:sk|pk|rk)_(?:live|test)_[0-9A-Za-z]{16,}|gh[pousr]_[0-9A-Za-z]{36}|xox[abpr]-[0-9A-Za-z-]{10,}|AIza[0-9A-Za-z_-]{35})\b`)},
+	{"token", regexp.MustCompile(`\b[A-Za-z0-9_-]{32,}\b`)},
+	{"internal host", regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)+(?:internal|local|lan|corp|intranet)\b|\blocalhost\b`)},
+	{"private ip", regexp.MustCompile(`\b(?:10\.\d{1,3}|127\.\d{1,3}|192\.168|172\.(?:1[6-9]|2\d|3[01]))\.\d{1,3}\.\d{1,3}\b`)},
+}
+
+// PHONE_MIN_DIGITS keeps dates, versions and amounts from looking like phone numbers.
+const PHONE_MIN_DIGITS = 10
+
+func (c *PiiConfig) validate() error {
+	if c == nil {
+		return nil
+	}
+	for _, allow := range c.Allow {
+		if _, err := regexp.Compile(allow); err != nil {
+			return fmt.Errorf("Invalid pii allow pattern %s, %v", allow, err)
+		}
+	}
+	return nil
+}
+
+func (c *PiiConfig) patterns() []*piiPattern {
+	patterns := piiPatterns
+	for _, domain := range c.InternalDomains {
+		patterns = append(patterns, &piiPattern{"internal host",
+			regexp.MustCompile(`(?i)\b(?:[a-z0-9-]+\.)*` + regexp.QuoteMeta(strings.TrimPrefix(domain, ".")) + `\b`)})
+	}
+	return patterns
+}
+
+// piiMatches returns matches of text by kind, matches of Allow excluded.
+func (c *PiiConfig) piiMatches(text string) map[string][]string {
+	allow := make([]*regexp.Regexp, 0, len(c.Allow))
+	for _, a := range c.Allow {
+		allow = append(allow, regexp.MustCompile(a))
+	}
+	matches := map[string][]string{}
+	for _, p := range c.patterns() {
+	next:
+		for _, m := range p.pattern.FindAllString(text, -1) {
+			if !isPiiMatch(p.kind, m) {
+				continue
+			}
+			for _, a := range allow {
+				if a.MatchString(m) {
+					continue next
+				}
+			}
+			matches[p.kind] = appendUnique(matches[p.kind], m)
+		}
+	}
+	return matches
+}
+
+func isPiiMatch(kind, m string) bool {
+	switch kind {
+	case "phone number":
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		return digits >= PHONE_MIN_DIGITS
+	case "token":
+		// ids made of words, like snake_case keys, are no tokens
+		return strings.ContainsAny(m, "0123456789") && strings.ToLower(m) != m && strings.ToUpper(m) != m
+	}
+	return true
+}
+
+// piiKinds lists kinds of matches only, reports shouldn't spread what they warn about.
+func piiKinds(matches map[string][]string) string {
+	kinds := make([]string, 0, len(matches))
+	for kind := range matches {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	return strings.Join(kinds, ", ")
+}
+
+// quarantinePii removes keys whose source text looks like personal data or secrets, they are reported instead.
+func quarantinePii(v *FuncVisitor) {
+	for _, id := range v.Ids() {
+		matches := map[string][]string{}
+		for _, text := range v.translation(id).Texts() {
+			for kind, m := range config.Pii.piiMatches(text) {
+				matches[kind] = append(matches[kind], m...)
+			}
+		}
+		if len(matches) == 0 {
+			continue
+		}
+		log.Printf("WARNING! Key %q is not uploaded, it looks like %s\n", id, piiKinds(matches))
+		report.AddPii(fmt.Sprintf("%s: %s", id, piiKinds(matches)))
+		v.remove(id)
+	}
+}
+
+func (r *RunReport) AddPii(key string) {
+	r.Pii = appendUnique(r.Pii, key)
+}
+
+// remove forgets everything extracted for id.
+func (v *FuncVisitor) remove(id string) {
+	v.Lock()
+	defer v.Unlock()
+	delete(v.funcNames, id)
+	delete(v.seeds, id)
+	delete(v.services, id)
+	delete(v.messages, id)
+	delete(v.descriptions, id)
+	delete(v.variants, id)
+}
+
+// checkPii reports translations with personal data or secrets which source text doesn't have.
+func checkPii(locale, source, text string, placeholders *regexp.Regexp) string {
+	if config.Pii == nil {
+		return ""
+	}
+	matches := config.Pii.piiMatches(text)
+	for kind, sourceMatches := range config.Pii.piiMatches(source) {
+		for _, m := range sourceMatches {
+			if matches[kind] = removeString(matches[kind], m); len(matches[kind]) == 0 {
+				delete(matches, kind)
+			}
+		}
+	}
+	if len(matches) == 0 {
+		return ""
+	}
+	return "translation looks like " + piiKinds(matches)
+}
+
+func removeString(items []string, item string) []string {
+	kept := items[:0]
+	for _, i := range items {
+		if i != item {
+			kept = append(kept, i)
+		}
+	}
+	return kept
+}
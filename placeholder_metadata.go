@@ -0,0 +1,106 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// PLACEHOLDERS_DESCRIPTION_PREFIX starts the line of phraseapp key descriptions listing placeholders of the source.
+const PLACEHOLDERS_DESCRIPTION_PREFIX = "Placeholders: "
+
+var (
+	// placeholderMetadata lists placeholders of extracted source strings in descriptions of phraseapp keys.
+	placeholderMetadata bool
+	// sourcePlaceholders are placeholders of source texts of extracted keys of -project in order of appearance,
+	// keys without placeholders have empty lists.
+	sourcePlaceholders map[string][]string
+)
+
+// extractPlaceholders finds placeholders of extracted source strings with placeholder styles of the project.
+func extractPlaceholders(projectName string) {
+	ids := v.Ids()
+	sources := make([]*Translation, 0, len(ids))
+	for _, id := range ids {
+		sources = append(sources, &Translation{ID: sourceText(id)})
+	}
+	pattern := projectPlaceholders(projectName, sources)
+	sourcePlaceholders = make(map[string][]string, len(ids))
+	for _, id := range ids {
+		found := []string{}
+		for _, text := range v.translation(id).Texts() {
+			for _, p := range pattern.FindAllString(text, -1) {
+				found = appendUnique(found, p)
+			}
+		}
+		sourcePlaceholders[id] = found
+	}
+}
+
+// checkExtractedPlaceholders compares placeholders of the translation with ones extracted from its source,
+// plural forms may leave out placeholders of other forms, like the count of "one".
+func checkExtractedPlaceholders(expected []string, text string, plural bool, placeholders *regexp.Regexp) string {
+	want := map[string]bool{}
+	for _, p := range expected {
+		want[p] = true
+		if !plural && !strings.Contains(text, p) {
+			return fmt.Sprintf("placeholder %s is missing", p)
+		}
+	}
+	for _, p := range placeholders.FindAllString(text, -1) {
+		if !want[p] {
+			return fmt.Sprintf("placeholder %s is not in source", p)
+		}
+	}
+	return ""
+}
+
+// placeholderDescription replaces the placeholders line of the description, it is dropped if there are none.
+func placeholderDescription(description string, placeholders []string) string {
+	description = stripPlaceholderMetadata(description)
+	if len(placeholders) == 0 {
+		return description
+	}
+	line := PLACEHOLDERS_DESCRIPTION_PREFIX + strings.Join(placeholders, " ")
+	if description == "" {
+		return line
+	}
+	return description + "\n\n" + line
+}
+
+// stripPlaceholderMetadata returns the description written by people, without the placeholders line.
+func stripPlaceholderMetadata(description string) string {
+	lines := strings.Split(description, "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if !strings.HasPrefix(line, PLACEHOLDERS_DESCRIPTION_PREFIX) {
+			kept = append(kept, line)
+		}
+	}
+	return strings.TrimRight(strings.Join(kept, "\n"), " \t\n")
+}
+
+// keyDescriptions returns descriptions keys of the project should have: descriptions of message literals and,
+// with -placeholder-metadata, current descriptions with placeholders of extracted sources.
+func keyDescriptions(projectName string, current map[string]string) map[string]string {
+	wanted := map[string]string{}
+	for id, description := range v.descriptions {
+		if keyProject(id) == projectName {
+			wanted[id] = description
+		}
+	}
+	if !placeholderMetadata {
+		return wanted
+	}
+	for id, placeholders := range sourcePlaceholders {
+		if keyProject(id) != projectName {
+			continue
+		}
+		description, ok := wanted[id]
+		if !ok {
+			description = current[id]
+		}
+		wanted[id] = placeholderDescription(description, placeholders)
+	}
+	return wanted
+}
@@ -0,0 +1,121 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	QA_RULE_PLACEHOLDERS = "placeholders"
+
+	PLACEHOLDER_GO_TEMPLATE = "go-template"
+	PLACEHOLDER_PRINTF      = "printf"
+	PLACEHOLDER_BRACE       = "brace"
+	// PLACEHOLDER_AUTO detects styles used by source strings of the project.
+	PLACEHOLDER_AUTO = "auto"
+)
+
+// placeholderStyles match {{.Name}}, %s and {name} placeholders. Printf placeholders are verbs of fmt,
+// flags don't include space, so percents of "50% off" are no placeholders.
+var placeholderStyles = map[string]string{
+	PLACEHOLDER_GO_TEMPLATE: `\{\{[^}]*\}\}`,
+	PLACEHOLDER_PRINTF:      `%(?:\[[0-9]+\])?[-+#0]*(?:[0-9]+|\*)?(?:\.(?:[0-9]+|\*))?[vTtbcdoqxXUeEfFgGsp]`,
+	PLACEHOLDER_BRACE:       `\{[A-Za-z_][A-Za-z0-9_]*\}`,
+}
+
+// detectedPlaceholders are patterns of styles detected by project, detected once per run.
+var detectedPlaceholders = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: map[string]*regexp.Regexp{}}
+
+// resetDetectedPlaceholders forgets styles detected by the previous run, sources may have changed since.
+func resetDetectedPlaceholders() {
+	detectedPlaceholders.Lock()
+	defer detectedPlaceholders.Unlock()
+	detectedPlaceholders.patterns = map[string]*regexp.Regexp{}
+}
+
+func validatePlaceholderStyles(styles map[string]string) error {
+	for project, style := range styles {
+		for _, s := range strings.Split(style, ",") {
+			if _, ok := placeholderStyles[s]; !ok && s != PLACEHOLDER_AUTO {
+				return fmt.Errorf("Unknown placeholder style %s of project %s", s, project)
+			}
+		}
+	}
+	return nil
+}
+
+// projectPlaceholders returns pattern of placeholder styles of the project: comma separated styles
+// of config.Placeholders, "*" applying to projects not listed, or styles detected from source strings.
+func projectPlaceholders(projectName string, translations []*Translation) *regexp.Regexp {
+	style, ok := config.Placeholders[projectName]
+	if !ok {
+		style, ok = config.Placeholders["*"]
+	}
+	if ok && style != PLACEHOLDER_AUTO {
+		return placeholderPattern(strings.Split(style, ","))
+	}
+
+	detectedPlaceholders.Lock()
+	defer detectedPlaceholders.Unlock()
+	if p, ok := detectedPlaceholders.patterns[projectName]; ok {
+		return p
+	}
+	styles := detectPlaceholderStyles(translations)
+	if len(styles) > 0 {
+		log.Printf("Placeholder styles of project %s are %s\n", projectName, strings.Join(styles, ", "))
+	}
+	p := placeholderPattern(styles)
+	detectedPlaceholders.patterns[projectName] = p
+	return p
+}
+
+// detectPlaceholderStyles returns styles found in source strings of qaSource, go-template and printf if none is found.
+func detectPlaceholderStyles(translations []*Translation) []string {
+	styles := []string{}
+	for style, expr := range placeholderStyles {
+		re := regexp.MustCompile(expr)
+		for _, t := range translations {
+			if re.MatchString(qaSource(t.ID)) {
+				styles = append(styles, style)
+				break
+			}
+		}
+	}
+	if len(styles) == 0 {
+		styles = []string{PLACEHOLDER_GO_TEMPLATE, PLACEHOLDER_PRINTF}
+	}
+	sort.Strings(styles)
+	return styles
+}
+
+func placeholderPattern(styles []string) *regexp.Regexp {
+	exprs := []string{}
+	for _, style := range styles {
+		exprs = append(exprs, placeholderStyles[style])
+	}
+	return regexp.MustCompile(strings.Join(exprs, "|"))
+}
+
+// checkPlaceholders reports placeholders of source missing in the translation and unknown ones added to it.
+func checkPlaceholders(locale, source, text string, placeholders *regexp.Regexp) string {
+	want := map[string]bool{}
+	for _, p := range placeholders.FindAllString(source, -1) {
+		want[p] = true
+		if !strings.Contains(text, p) {
+			return fmt.Sprintf("placeholder %s is missing", p)
+		}
+	}
+	for _, p := range placeholders.FindAllString(text, -1) {
+		if !want[p] {
+			return fmt.Sprintf("placeholder %s is not in source", p)
+		}
+	}
+	return ""
+}
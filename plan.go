@@ -0,0 +1,279 @@
+package i18n_gen
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// planOnly prints the plan of the sync as json and exits without changing anything.
+	planOnly    bool
+	autoApprove bool
+)
+
+type (
+	// Plan is what a sync is going to do, printed before it starts.
+	Plan struct {
+		// Sources are files extraction reads, relative to -path.
+		Sources     []string        `json:"sources"`
+		Uploads     []*PlanUpload   `json:"uploads"`
+		Downloads   []*PlanDownload `json:"downloads"`
+		Validations []string        `json:"validations"`
+		// Outputs are files, folders and endpoints the sync writes to.
+		Outputs []string `json:"outputs"`
+	}
+
+	PlanUpload struct {
+		Project string `json:"project"`
+		// Locale is empty for the default locale of the project.
+		Locale string `json:"locale"`
+		Split  bool   `json:"split,omitempty"`
+		Tag    string `json:"tag,omitempty"`
+		// Blocked tells the freeze blocking the upload.
+		Blocked string `json:"blocked,omitempty"`
+	}
+
+	PlanDownload struct {
+		Project string   `json:"project"`
+		Locales []string `json:"locales"`
+		// NotDue are locales kept by the schedule.
+		NotDue []string `json:"not_due,omitempty"`
+	}
+)
+
+// stdinIsTerminal reports whether somebody may answer the confirmation prompt.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirmPlan prints the plan as json with -plan, or as text followed by a confirmation prompt in a terminal
+// unless -auto-approve is set. It reports whether the sync should go on.
+func confirmPlan() (bool, error) {
+	plan, err := buildPlan()
+	if err != nil {
+		return false, err
+	}
+	if planOnly {
+		data, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return false, err
+		}
+		fmt.Println(string(data))
+		return false, nil
+	}
+	for _, line := range plan.lines() {
+		log.Println(line)
+	}
+	if autoApprove || !stdinIsTerminal() {
+		return true, nil
+	}
+	fmt.Fprint(os.Stderr, "Proceed? Only yes is accepted: ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	if strings.TrimSpace(answer) != "yes" {
+		log.Println("Sync was cancelled")
+		return false, nil
+	}
+	return true, nil
+}
+
+func buildPlan() (*Plan, error) {
+	plan := &Plan{}
+	sources, err := planSources()
+	if err != nil {
+		return nil, err
+	}
+	plan.Sources = sources
+
+	for _, projectName := range routeProjects() {
+		upload := &PlanUpload{Project: projectName, Locale: defaultLocale, Split: splitUploads}
+		if w := activeFreeze(time.Now(), projectName); w != nil {
+			if w.Tag == "" {
+				upload.Blocked = w.Describe()
+			}
+			upload.Tag = w.Tag
+		}
+		plan.Uploads = append(plan.Uploads, upload)
+	}
+
+	projects := syncProjects()
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		locales, err := ctx.getLocales(&i18nGenContext{}, projects[name], name)
+		if err != nil {
+			return nil, err
+		}
+		d := &PlanDownload{Project: name, Locales: []string{}}
+		for _, l := range locales {
+			if isDownloadDue(name, l.Name, time.Now()) {
+				d.Locales = append(d.Locales, l.Name)
+			} else {
+				d.NotDue = append(d.NotDue, l.Name)
+			}
+		}
+		plan.Downloads = append(plan.Downloads, d)
+	}
+
+	for _, rule := range qaRules {
+		if severity := config.Qa.severity(rule.name); severity != QA_SEVERITY_OFF {
+			plan.Validations = append(plan.Validations, "qa "+rule.name+": "+severity)
+		}
+	}
+	if len(config.Sms) > 0 {
+		if severity := config.Qa.severity(QA_RULE_SMS); severity != QA_SEVERITY_OFF {
+			plan.Validations = append(plan.Validations, "qa "+QA_RULE_SMS+": "+severity)
+		}
+	}
+	if strictExtract {
+		plan.Validations = append(plan.Validations, "strict-extract")
+	}
+	if quarantineFailing {
+		plan.Validations = append(plan.Validations, "quarantine")
+	}
+	plan.Outputs = planOutputs(names)
+	return plan, nil
+}
+
+// planSources lists files extraction reads: scanned go files, seeds, key mapping, notes and email templates.
+func planSources() ([]string, error) {
+	sources := []string{}
+	if sourceFiles != nil {
+		for name, data := range sourceFiles {
+			if strings.HasSuffix(name, ".go") && inSourceDirs(name, syncDirs()) &&
+				(isLocalizationSource(name) || inConstPackage(name) || containsExtractionMarkers(data)) {
+				sources = append(sources, name)
+			}
+		}
+	} else {
+		ignore := newSourceIgnore(basepath)
+		for _, root := range sourceRoots(basepath, syncDirs()) {
+			err := filepath.Walk(root, func(p string, info os.FileInfo, err error) error {
+				if err != nil {
+					return nil
+				}
+				if ignore.skip(p, info) || isNestedModule(p, info) {
+					if info.IsDir() {
+						return filepath.SkipDir
+					}
+					return nil
+				}
+				if isScannedFile(p, info) {
+					if rel, err := filepath.Rel(basepath, p); err == nil {
+						p = rel
+					}
+					sources = append(sources, filepath.ToSlash(p))
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	sources = append(sources, config.Seeds...)
+	for _, p := range []string{config.KeyMapping, config.Notes, config.Overrides} {
+		if p != "" {
+			sources = append(sources, p)
+		}
+	}
+	if config.Emails != nil {
+		names, err := emailTemplateNames(basepath, config.Emails.Dir)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list email templates, %v", err)
+		}
+		for _, name := range names {
+			sources = append(sources, path.Join(filepath.ToSlash(config.Emails.Dir), name))
+		}
+	}
+	sort.Strings(sources)
+	return sources, nil
+}
+
+func planOutputs(projects []string) []string {
+	outputs := []string{}
+	for _, name := range projects {
+		outputs = append(outputs, filepath.Join(getLocalizationFolderName(), name))
+		if prodDownload {
+			outputs = append(outputs, filepath.Join(getProdFolderName(), name))
+		}
+	}
+	if mergeProjects {
+		outputs = append(outputs, getLocalizationFolderName()+MERGED_SUFFIX)
+	}
+	if pseudoRtl {
+		outputs = append(outputs, filepath.Join(getLocalizationFolderName(), defaultProject, PSEUDO_RTL_LOCALE+".json"))
+	}
+	for _, name := range projects {
+		if t, ok := config.Typescript[name]; ok {
+			outputs = append(outputs, t.Dir)
+		}
+	}
+	for _, p := range []string{codegenPath, hashIdsPath, statusPath, badgesDir, changelogDir, bundleLocation, auditTarget} {
+		if p != "" {
+			outputs = append(outputs, p)
+		}
+	}
+	if config.Emails != nil {
+		outputs = append(outputs, config.Emails.Out)
+	}
+	if stateLocation != "" {
+		outputs = append(outputs, stateLocation)
+	} else {
+		outputs = append(outputs, "run info in the user cache dir")
+	}
+	return outputs
+}
+
+// lines describes the plan, sources are counted unless -verbose lists them.
+func (p *Plan) lines() []string {
+	lines := []string{"Plan:"}
+	if verbose {
+		lines = append(lines, "  read sources:")
+		for _, s := range p.Sources {
+			lines = append(lines, "    "+s)
+		}
+	} else {
+		lines = append(lines, fmt.Sprintf("  read %d source files", len(p.Sources)))
+	}
+	for _, u := range p.Uploads {
+		locale := u.Locale
+		if locale == "" {
+			locale = "default locale"
+		}
+		line := fmt.Sprintf("  upload keys to %s %s", u.Project, locale)
+		switch {
+		case u.Blocked != "":
+			line += ", blocked by freeze " + u.Blocked
+		case u.Tag != "":
+			line += " tagged " + u.Tag
+		}
+		if u.Split {
+			line += " by service"
+		}
+		lines = append(lines, line)
+	}
+	for _, d := range p.Downloads {
+		line := fmt.Sprintf("  download %s: %s", d.Project, strings.Join(d.Locales, ", "))
+		if len(d.NotDue) > 0 {
+			line += fmt.Sprintf(" (not due: %s)", strings.Join(d.NotDue, ", "))
+		}
+		lines = append(lines, line)
+	}
+	if len(p.Validations) > 0 {
+		lines = append(lines, "  validate: "+strings.Join(p.Validations, ", "))
+	}
+	lines = append(lines, "  write: "+strings.Join(p.Outputs, ", "))
+	return lines
+}
@@ -0,0 +1,170 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PROVENANCE_KEY is a key of the default project without translations, its description records the code version
+// which uploaded keys of the project. It is dropped from downloaded locales if anybody translates it.
+const PROVENANCE_KEY = "i18n_gen.provenance"
+
+// Provenance tells which commit, branch and i18n_gen version uploaded keys of a project.
+type Provenance struct {
+	Commit   string
+	Branch   string
+	Version  string
+	Uploaded time.Time
+}
+
+var (
+	provenanceOnce sync.Once
+	provenance     *Provenance
+	// provenanceDue is set when keys of -project were uploaded by this run.
+	provenanceDue bool
+)
+
+// currentProvenance returns provenance of this run, CI variables win over the git checkout of -path.
+func currentProvenance() *Provenance {
+	provenanceOnce.Do(func() {
+		provenance = &Provenance{
+			Commit:   firstEnv("GITHUB_SHA", "CI_COMMIT_SHA", "BUILDKITE_COMMIT", "GIT_COMMIT"),
+			Branch:   firstEnv("GITHUB_HEAD_REF", "GITHUB_REF_NAME", "CI_COMMIT_REF_NAME", "BUILDKITE_BRANCH", "GIT_BRANCH"),
+			Version:  Version,
+			Uploaded: time.Now().UTC(),
+		}
+		if provenance.Commit == "" {
+			provenance.Commit = gitOutput("rev-parse", "HEAD")
+		}
+		if provenance.Branch == "" {
+			provenance.Branch = gitOutput("rev-parse", "--abbrev-ref", "HEAD")
+		}
+	})
+	return provenance
+}
+
+func firstEnv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// gitOutput runs git in -path, "unknown" stands for sources out of a git checkout like archives.
+func gitOutput(args ...string) string {
+	out, err := exec.Command("git", append([]string{"-C", basepath}, args...)...).Output()
+	if err != nil || strings.TrimSpace(string(out)) == "" {
+		return "unknown"
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func (p *Provenance) String() string {
+	return fmt.Sprintf("commit=%s branch=%s version=%s uploaded=%s", p.Commit, p.Branch, p.Version, p.Uploaded.Format(time.RFC3339))
+}
+
+// parseProvenance reads the translation of PROVENANCE_KEY, unknown fields are skipped.
+func parseProvenance(text string) (*Provenance, error) {
+	p := &Provenance{}
+	for _, field := range strings.Fields(text) {
+		kv := strings.SplitN(field, "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+		switch kv[0] {
+		case "commit":
+			p.Commit = kv[1]
+		case "branch":
+			p.Branch = kv[1]
+		case "version":
+			p.Version = kv[1]
+		case "uploaded":
+			uploaded, err := time.Parse(time.RFC3339, kv[1])
+			if err != nil {
+				return nil, fmt.Errorf("Unable to parse upload time %s, %v", kv[1], err)
+			}
+			p.Uploaded = uploaded
+		}
+	}
+	if p.Commit == "" {
+		return nil, fmt.Errorf("Provenance %q has no commit", text)
+	}
+	return p, nil
+}
+
+// recordProvenance sets provenance of this run as the description of PROVENANCE_KEY, creating the key.
+// The description is replaced on every upload, unlike translations uploaded without -update_translations.
+func recordProvenance(worker *PhraseappWorkerContext, projectName string) {
+	localCtx := &i18nGenContext{}
+	projectId := phraseappProjects[projectName]
+	keyIds, err := worker.KeyIds(localCtx, projectId, projectName)
+	if err != nil {
+		log.Println("WARNING! Unable to record provenance", err)
+		return
+	}
+	description := currentProvenance().String()
+	if keyIds[PROVENANCE_KEY] == "" {
+		err = worker.CreateKey(localCtx, projectId, projectName, PROVENANCE_KEY, description)
+	} else {
+		err = worker.SetKeyDescription(localCtx, projectId, projectName, keyIds[PROVENANCE_KEY], description)
+	}
+	if err != nil {
+		log.Println("WARNING! Unable to record provenance", err)
+	}
+}
+
+// dropProvenance removes PROVENANCE_KEY from downloaded translations, consumers never see it.
+func dropProvenance(translations []*Translation) []*Translation {
+	kept := make([]*Translation, 0, len(translations))
+	for _, t := range translations {
+		if t.ID != PROVENANCE_KEY {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+var remoteCommand = &command{
+	name:        "remote",
+	description: "show state of the translation management system, 'remote status' prints provenance of the last upload",
+	setFlags:    setCommonFlags,
+	run:         runRemote,
+}
+
+func runRemote(fs *flag.FlagSet) {
+	if fs.Arg(0) != "status" {
+		fs.Usage()
+		os.Exit(2)
+	}
+	// flags may follow the subcommand as well
+	fs.Parse(fs.Args()[1:])
+	validateCommonFlags()
+	worker := connect()
+	ctx := &i18nGenContext{}
+	projectId := phraseappProjects[defaultProject]
+	descriptions, err := worker.KeyDescriptions(ctx, projectId, defaultProject)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if description, ok := descriptions[PROVENANCE_KEY]; ok {
+		p, err := parseProvenance(description)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("project:  %s\n", defaultProject)
+		fmt.Printf("commit:   %s\n", p.Commit)
+		fmt.Printf("branch:   %s\n", p.Branch)
+		fmt.Printf("version:  %s\n", p.Version)
+		fmt.Printf("uploaded: %s\n", p.Uploaded.Format(time.RFC3339))
+		return
+	}
+	log.Fatalf("Project %s has no provenance, keys were not uploaded by i18n_gen with provenance yet\n", defaultProject)
+}
@@ -0,0 +1,78 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+const (
+	PROVIDER_PHRASEAPP = "phraseapp"
+	// PROVIDER_PHRASE_STRINGS is PhraseApp renamed, its API keeps v2 endpoints, tokens and ETags of phraseapp
+	// on a new host.
+	PROVIDER_PHRASE_STRINGS = "phrase-strings"
+	PHRASE_STRINGS_HOST     = "https://api.phrase.com"
+)
+
+// ProviderConfig selects the translation API during the migration from phraseapp to Phrase Strings.
+type ProviderConfig struct {
+	// Name is phraseapp, the default, or phrase-strings.
+	Name string `json:"name"`
+	// Host overrides API host of the provider, e.g. https://api.us.app.phrase.com for the US data center.
+	Host string `json:"host"`
+	// Branch makes all requests work with the branch of projects instead of the main one.
+	Branch string `json:"branch"`
+}
+
+func (p *ProviderConfig) validate() error {
+	if p == nil {
+		return nil
+	}
+	switch p.Name {
+	case "", PROVIDER_PHRASEAPP, PROVIDER_PHRASE_STRINGS:
+	default:
+		return fmt.Errorf("Unknown provider %s, expected %s or %s", p.Name, PROVIDER_PHRASEAPP, PROVIDER_PHRASE_STRINGS)
+	}
+	if p.Host != "" {
+		if u, err := url.Parse(p.Host); err != nil || u.Scheme == "" || u.Host == "" {
+			return fmt.Errorf("Provider host %s should be an url like %s", p.Host, PHRASE_STRINGS_HOST)
+		}
+	}
+	return nil
+}
+
+// providerHost returns API host of the configured provider, empty for the phraseapp client default.
+func providerHost() string {
+	p := config.Provider
+	switch {
+	case p == nil:
+		return ""
+	case p.Host != "":
+		return p.Host
+	case p.Name == PROVIDER_PHRASE_STRINGS:
+		return PHRASE_STRINGS_HOST
+	}
+	return ""
+}
+
+func providerBranch() string {
+	if config.Provider == nil {
+		return ""
+	}
+	return config.Provider.Branch
+}
+
+// branchTransport adds branch parameter to every request, the API takes parameters of any request
+// from the query as well as from the body.
+type branchTransport struct {
+	next   http.RoundTripper
+	branch string
+}
+
+func (t *branchTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	q := req.URL.Query()
+	q.Set("branch", t.branch)
+	req.URL.RawQuery = q.Encode()
+	return t.next.RoundTrip(req)
+}
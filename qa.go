@@ -0,0 +1,257 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+const (
+	QA_SEVERITY_ERROR   = "error"
+	QA_SEVERITY_WARNING = "warning"
+	QA_SEVERITY_OFF     = "off"
+
+	QA_RULE_WHITESPACE   = "whitespace"
+	QA_RULE_DOUBLE_SPACE = "double-space"
+	QA_RULE_PUNCTUATION  = "punctuation"
+	QA_RULE_CASING       = "casing"
+	QA_RULE_UNTRANSLATED = "untranslated"
+	QA_RULE_CURRENCY     = "currency"
+	QA_RULE_DATE         = "date"
+	QA_RULE_NUMBER       = "number"
+
+	// QA_TERMINAL_PUNCTUATION are sentence endings compared between source and translation.
+	QA_TERMINAL_PUNCTUATION = ".!?:…。！？：؟"
+)
+
+type (
+	// QaConfig sets severity of QA rules, rules are warnings by default.
+	QaConfig struct {
+		Rules    map[string]string `json:"rules"`
+		Suppress []*QaSuppression  `json:"suppress"`
+		// SourceLocale is exempt from rules comparing translations with source text,
+		// -locale or en-US if empty.
+		SourceLocale string `json:"source_locale"`
+	}
+
+	// QaSuppression silences Rule, empty fields match anything.
+	QaSuppression struct {
+		Rule    string `json:"rule"`
+		Project string `json:"project"`
+		Locale  string `json:"locale"`
+		Key     string `json:"key"`
+	}
+
+	qaRule struct {
+		name string
+		// compare rules check translation against source text and skip the source locale.
+		compare bool
+		check   func(locale, source, text string, placeholders *regexp.Regexp) string
+	}
+)
+
+var qaRules = []*qaRule{
+	{QA_RULE_WHITESPACE, false, checkWhitespace},
+	{QA_RULE_DOUBLE_SPACE, false, checkDoubleSpace},
+	{QA_RULE_PUNCTUATION, true, checkPunctuation},
+	{QA_RULE_CASING, true, checkCasing},
+	{QA_RULE_UNTRANSLATED, true, checkUntranslated},
+	{QA_RULE_CURRENCY, false, checkCurrency},
+	{QA_RULE_DATE, false, checkDate},
+	{QA_RULE_NUMBER, false, checkNumber},
+	{QA_RULE_BIDI, false, checkBidi},
+	{QA_RULE_PLACEHOLDERS, true, checkPlaceholders},
+	{QA_RULE_TEMPLATE, false, checkTemplate},
+	{QA_RULE_PII, false, checkPii},
+}
+
+func validateQaConfig(cfg *QaConfig) error {
+	if cfg == nil {
+		return nil
+	}
+	for rule, severity := range cfg.Rules {
+		// sms and entities rules check downloads of their own, they have no check of qaRules
+		if findQaRule(rule) == nil && rule != QA_RULE_SMS && rule != QA_RULE_ENTITIES {
+			return fmt.Errorf("Unknown qa rule %s", rule)
+		}
+		if severity != QA_SEVERITY_ERROR && severity != QA_SEVERITY_WARNING && severity != QA_SEVERITY_OFF {
+			return fmt.Errorf("Severity of qa rule %s should be %s, %s or %s, got %s",
+				rule, QA_SEVERITY_ERROR, QA_SEVERITY_WARNING, QA_SEVERITY_OFF, severity)
+		}
+	}
+	return nil
+}
+
+func findQaRule(name string) *qaRule {
+	for _, r := range qaRules {
+		if r.name == name {
+			return r
+		}
+	}
+	return nil
+}
+
+func (cfg *QaConfig) severity(rule string) string {
+	if cfg != nil {
+		if s, ok := cfg.Rules[rule]; ok {
+			return s
+		}
+	}
+	return QA_SEVERITY_WARNING
+}
+
+func (cfg *QaConfig) sourceLocale() string {
+	if cfg != nil && cfg.SourceLocale != "" {
+		return cfg.SourceLocale
+	}
+	if defaultLocale != "" {
+		return defaultLocale
+	}
+	return FALLBACK_LOCALE
+}
+
+func (cfg *QaConfig) isSuppressed(issue *Issue) bool {
+	if cfg == nil {
+		return false
+	}
+	for _, s := range cfg.Suppress {
+		if (s.Rule == "" || s.Rule == issue.Rule) &&
+			(s.Project == "" || s.Project == issue.Project) &&
+			(s.Locale == "" || s.Locale == issue.Locale) &&
+			(s.Key == "" || s.Key == issue.Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkTranslations runs QA rules on a downloaded locale, against source text of keys of qaSource.
+// Issues of error severity are returned to be reported as run errors or quarantined.
+func checkTranslations(cfg *QaConfig, projectName, localeName string, translations []*Translation) []*Issue {
+	failed := []*Issue{}
+	isSource := localeName == cfg.sourceLocale()
+	placeholders := projectPlaceholders(projectName, translations)
+	sources := make(map[string]string, len(translations))
+	for _, t := range translations {
+		sources[t.ID] = qaSource(t.ID)
+	}
+	for _, rule := range qaRules {
+		severity := cfg.severity(rule.name)
+		if severity == QA_SEVERITY_OFF || (rule.compare && isSource) {
+			continue
+		}
+		for _, t := range translations {
+			for _, text := range t.Texts() {
+				if text == "" {
+					continue
+				}
+				message := ""
+				if expected, ok := sourcePlaceholders[t.ID]; ok && rule.name == QA_RULE_PLACEHOLDERS && keyProject(t.ID) == projectName {
+					message = checkExtractedPlaceholders(expected, text, t.IsPlural(), placeholders)
+				} else {
+					message = rule.check(localeName, sources[t.ID], text, placeholders)
+				}
+				if message == "" {
+					continue
+				}
+				issue := &Issue{Project: projectName, Locale: localeName, Key: t.ID, Rule: rule.name, Message: message}
+				if cfg.isSuppressed(issue) {
+					continue
+				}
+				report.AddIssue(issue)
+				if severity == QA_SEVERITY_ERROR {
+					failed = append(failed, issue)
+				}
+				break
+			}
+		}
+	}
+	return failed
+}
+
+// qaSource returns source text of the key: text of extracted message literals, "other" form of plural ones,
+// and text of hashed and mapped ids, other keys are their source text.
+func qaSource(id string) string {
+	if v != nil {
+		if t := v.translation(id); t != nil {
+			if t.IsPlural() {
+				return t.Plural["other"]
+			}
+			return t.Text
+		}
+	}
+	return sourceText(id)
+}
+
+func checkWhitespace(locale, source, text string, placeholders *regexp.Regexp) string {
+	if strings.TrimSpace(text) != text && strings.TrimSpace(source) == source {
+		return fmt.Sprintf("leading or trailing whitespace in %q", text)
+	}
+	return ""
+}
+
+func checkDoubleSpace(locale, source, text string, placeholders *regexp.Regexp) string {
+	if strings.Contains(text, "  ") && !strings.Contains(source, "  ") {
+		return fmt.Sprintf("double space in %q", text)
+	}
+	return ""
+}
+
+func checkPunctuation(locale, source, text string, placeholders *regexp.Regexp) string {
+	sourceEnding, textEnding := terminalPunctuation(source), terminalPunctuation(text)
+	if (sourceEnding == 0) != (textEnding == 0) {
+		return fmt.Sprintf("ending punctuation differs from source, %q vs %q", text, source)
+	}
+	return ""
+}
+
+func terminalPunctuation(s string) rune {
+	runes := []rune(strings.TrimSpace(s))
+	if len(runes) == 0 {
+		return 0
+	}
+	last := runes[len(runes)-1]
+	if strings.ContainsRune(QA_TERMINAL_PUNCTUATION, last) {
+		return last
+	}
+	return 0
+}
+
+func checkCasing(locale, source, text string, placeholders *regexp.Regexp) string {
+	if isAllCaps(source) != isAllCaps(text) && hasCase(text) {
+		return fmt.Sprintf("all-caps differs from source, %q vs %q", text, source)
+	}
+	return ""
+}
+
+// isAllCaps reports whether s has at least two letters and all of them are upper case.
+func isAllCaps(s string) bool {
+	letters := 0
+	for _, r := range s {
+		if unicode.IsLetter(r) {
+			if !unicode.IsUpper(r) {
+				return false
+			}
+			letters++
+		}
+	}
+	return letters > 1
+}
+
+// hasCase reports whether the script of s distinguishes upper and lower case.
+func hasCase(s string) bool {
+	for _, r := range s {
+		if unicode.IsUpper(r) || unicode.IsLower(r) {
+			return true
+		}
+	}
+	return false
+}
+
+func checkUntranslated(locale, source, text string, placeholders *regexp.Regexp) string {
+	if text == source && hasCase(source) {
+		return "translation is identical to source"
+	}
+	return ""
+}
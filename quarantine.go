@@ -0,0 +1,61 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+)
+
+// quarantineFailing keeps a single bad translation from breaking a locale: malformed entries and translations
+// failing qa rules of error severity are reported and written with source text, the id, instead.
+var quarantineFailing bool
+
+// parseQuarantined decodes entries of a locale file one by one, malformed entries are quarantined.
+// The file is rejected only if it is not a json array at all.
+func parseQuarantined(projectName, localeName string, data []byte) ([]*Translation, error) {
+	entries := []json.RawMessage{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+	translations := []*Translation{}
+	for i, entry := range entries {
+		t := &Translation{}
+		err := json.Unmarshal(entry, t)
+		if err == nil {
+			translations = append(translations, t)
+			continue
+		}
+		raw := translationJson{}
+		if json.Unmarshal(entry, &raw) != nil || raw.ID == "" {
+			report.AddQuarantined(fmt.Sprintf("%s:%s entry %d is dropped, %v", projectName, localeName, i, err))
+			continue
+		}
+		report.AddQuarantined(fmt.Sprintf("%s:%s %s %v", projectName, localeName, raw.ID, err))
+		translations = append(translations, &Translation{ID: raw.ID, Text: sourceText(raw.ID)})
+	}
+	return translations, nil
+}
+
+// quarantineFailed replaces translations of failed issues with source text and reports whether any was replaced.
+func quarantineFailed(translations []*Translation, failed []*Issue) bool {
+	if len(failed) == 0 {
+		return false
+	}
+	byId := map[string]*Translation{}
+	for _, t := range translations {
+		byId[t.ID] = t
+	}
+	for _, issue := range failed {
+		report.AddQuarantined(issue.String())
+		t, ok := byId[issue.Key]
+		if !ok {
+			continue
+		}
+		t.Text = sourceText(t.ID)
+		for form := range t.Plural {
+			t.Plural[form] = t.Text
+		}
+	}
+	log.Printf("WARNING! %d translations of %s %s are quarantined\n", len(failed), failed[0].Project, failed[0].Locale)
+	return true
+}
@@ -0,0 +1,112 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// REGIONAL_FOLDER is the folder of regional files in project folders, apart from downloaded locales
+// so they never overwrite a locale and aren't checked like one.
+const REGIONAL_FOLDER = "regional"
+
+// RegionalConfig builds a language file from Base locale, translations of Overrides replace base ones in order,
+// the last override wins. Locales are phraseapp locale names.
+type RegionalConfig struct {
+	Base      string   `json:"base"`
+	Overrides []string `json:"overrides"`
+}
+
+func validateRegional(regional map[string]*RegionalConfig, aliases map[string]string) error {
+	for name, r := range regional {
+		if name == "" || r == nil || r.Base == "" {
+			return fmt.Errorf("Regional file %q needs a name and a base locale", name)
+		}
+		for _, l := range append([]string{r.Base}, r.Overrides...) {
+			if l == name || aliases[l] == name {
+				return fmt.Errorf("Regional file %s would overwrite locale %s", name, l)
+			}
+		}
+	}
+	return nil
+}
+
+// writeRegionalFiles writes regional files of config.Regional to REGIONAL_FOLDER of synced projects under folder.
+func writeRegionalFiles(folder string) {
+	names := make([]string, 0, len(config.Regional))
+	for name := range config.Regional {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for projectName := range syncProjects() {
+		for _, name := range names {
+			writeRegionalFile(folder, projectName, name, config.Regional[name])
+		}
+	}
+}
+
+func writeRegionalFile(folder, projectName, name string, r *RegionalConfig) {
+	dir := filepath.Join(folder, projectName)
+	base, err := readRegionalLocale(dir, r.Base)
+	if os.IsNotExist(err) {
+		// projects may lack the language altogether
+		if verbose {
+			log.Println("WARNING! Regional file is not written without base locale", projectName, name, r.Base)
+		}
+		return
+	}
+	if err != nil {
+		log.Println("WARNING! Unable to write regional file", projectName, name, err)
+		return
+	}
+	merged := map[string]*Translation{}
+	for _, t := range base {
+		merged[t.ID] = t
+	}
+	for _, l := range r.Overrides {
+		overrides, err := readRegionalLocale(dir, l)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Println("WARNING! Unable to write regional file", projectName, name, err)
+			return
+		}
+		for _, t := range overrides {
+			if !t.IsUntranslated() {
+				merged[t.ID] = t
+			}
+		}
+	}
+	translations := make([]*Translation, 0, len(merged))
+	for _, t := range merged {
+		translations = append(translations, t)
+	}
+	sort.Slice(translations, func(i, j int) bool { return translations[i].ID < translations[j].ID })
+	data, err := json.MarshalIndent(translations, "", "  ")
+	if err != nil {
+		fail(fmt.Errorf("Unable to encode regional file, %v", err))
+	}
+	if err := mkdirOutput(filepath.Join(dir, REGIONAL_FOLDER)); err != nil {
+		fail(fmt.Errorf("Unable to create regional folder %s, %v", projectName, err))
+	}
+	if err := writeOutputFile(filepath.Join(dir, REGIONAL_FOLDER, name+".json"), data); err != nil {
+		fail(fmt.Errorf("Unable to write regional file %s %s, %v", projectName, name, err))
+	}
+}
+
+func readRegionalLocale(dir, localeName string) ([]*Translation, error) {
+	data, err := ioutil.ReadFile(filepath.Join(dir, runtimeLocale(localeName)+".json"))
+	if err != nil {
+		return nil, err
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to parse locale %s, %v", localeName, err)
+	}
+	return translations, nil
+}
@@ -0,0 +1,130 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/phrase/phraseapp-go/phraseapp"
+)
+
+const (
+	REMOTE_EDITS_ABORT = "abort"
+	REMOTE_EDITS_WARN  = "warn"
+	// UPLOADED_HASH_SIZE is the number of hex digits of sha256 kept for every uploaded translation.
+	UPLOADED_HASH_SIZE = 16
+)
+
+// remoteEdits is the policy of uploads updating translations edited in phraseapp since the last upload.
+var remoteEdits string
+
+func validateRemoteEdits() error {
+	switch remoteEdits {
+	case REMOTE_EDITS_ABORT, REMOTE_EDITS_WARN:
+		return nil
+	}
+	return fmt.Errorf("Unknown -remote-edits policy %s, expected %s or %s", remoteEdits, REMOTE_EDITS_ABORT, REMOTE_EDITS_WARN)
+}
+
+// checkRemoteEdits passes current translations of the upload locale to the context before they are updated.
+func (c *PhraseappWorkerContext) checkRemoteEdits(ctx PhraseappContexter, projectId, project, lang string, locales []*phraseapp.Locale, parts []*UploadPart) error {
+	langId := ""
+	for _, l := range locales {
+		if l.Name == lang || l.ID == lang {
+			langId = l.ID
+		}
+	}
+	if langId == "" {
+		return fmt.Errorf("Unable to check remote edits, project %s has no locale %s", project, lang)
+	}
+	data, _, err := c.downloadLocaleImpl(ctx, projectId, project, langId, lang, "", "")
+	if err != nil {
+		return err
+	}
+	remote, err := ParseLocaleFile(data)
+	if err != nil {
+		return fmt.Errorf("Unable to parse locale %s of project %s, %v", lang, project, err)
+	}
+	return ctx.OnRemoteTranslations(project, lang, remote, parts)
+}
+
+func translationHash(t *Translation) string {
+	data, _ := json.Marshal(t)
+	return dataSha256(data)[:UPLOADED_HASH_SIZE]
+}
+
+// OnRemoteTranslations compares translations of the upload with remote ones: a key conflicts when its remote
+// translation is neither the one uploaded last time nor the one being uploaded, a translator edited it meanwhile.
+// Keys without a translation uploaded by a previous run with updates are not compared.
+func (c *i18nGenContext) OnRemoteTranslations(projectName, localeName string, remote []*Translation, parts []*UploadPart) error {
+	uploaded := runInfo.Uploaded[projectName+":"+localeName]
+	if len(uploaded) == 0 {
+		return nil
+	}
+	remoteHashes := map[string]string{}
+	for _, t := range remote {
+		remoteHashes[t.ID] = translationHash(t)
+	}
+	conflicts := []string{}
+	for _, part := range parts {
+		translations, err := readUploadPart(part)
+		if err != nil {
+			return err
+		}
+		for _, t := range translations {
+			last, ok := uploaded[t.ID]
+			current, exists := remoteHashes[t.ID]
+			if ok && exists && current != last && current != translationHash(t) {
+				conflicts = append(conflicts, t.ID)
+			}
+		}
+	}
+	if len(conflicts) == 0 {
+		return nil
+	}
+	sort.Strings(conflicts)
+	for _, id := range conflicts {
+		report.AddRemoteEdit(fmt.Sprintf("%s:%s %s", projectName, localeName, id))
+	}
+	err := fmt.Errorf("%d translations of %s %s were edited in phraseapp since the last upload: %s",
+		len(conflicts), projectName, localeName, strings.Join(conflicts, ", "))
+	if remoteEdits == REMOTE_EDITS_WARN {
+		log.Println("WARNING!", err)
+		return nil
+	}
+	return err
+}
+
+// recordUploaded remembers hashes of translations an upload updated, for OnRemoteTranslations of the next one.
+func recordUploaded(projectName, localeName string, part *UploadPart) {
+	translations, err := readUploadPart(part)
+	if err != nil {
+		log.Println("WARNING! Unable to record uploaded translations", projectName, localeName, err)
+		return
+	}
+	if runInfo.Uploaded == nil {
+		runInfo.Uploaded = map[string]map[string]string{}
+	}
+	key := projectName + ":" + localeName
+	if runInfo.Uploaded[key] == nil {
+		runInfo.Uploaded[key] = map[string]string{}
+	}
+	for _, t := range translations {
+		runInfo.Uploaded[key][t.ID] = translationHash(t)
+	}
+}
+
+func readUploadPart(part *UploadPart) ([]*Translation, error) {
+	data, err := ioutil.ReadFile(part.Path)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read upload payload %s, %v", part.Path, err)
+	}
+	return ParseLocaleFile(data)
+}
+
+func (r *RunReport) AddRemoteEdit(key string) {
+	r.RemoteEdits = append(r.RemoteEdits, key)
+}
@@ -0,0 +1,70 @@
+package i18n_gen
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOnRemoteTranslationsConflicts(t *testing.T) {
+	text := func(id, s string) *Translation { return &Translation{ID: id, Text: s} }
+	hashes := func(translations ...*Translation) map[string]string {
+		m := map[string]string{}
+		for _, t := range translations {
+			m[t.ID] = translationHash(t)
+		}
+		return m
+	}
+	upload := map[string]*Translation{"Log in": text("Log in", "Einloggen"), "Log out": text("Log out", "Ausloggen")}
+	tests := []struct {
+		name     string
+		uploaded map[string]string
+		remote   []*Translation
+		policy   string
+		err      string
+		edits    []string
+	}{
+		{name: "no previous upload", remote: []*Translation{text("Log in", "Anmelden")}, policy: REMOTE_EDITS_ABORT},
+		{name: "unchanged since upload", uploaded: hashes(text("Log in", "Login")),
+			remote: []*Translation{text("Log in", "Login")}, policy: REMOTE_EDITS_ABORT},
+		{name: "already up to date", uploaded: hashes(text("Log in", "Login")),
+			remote: []*Translation{text("Log in", "Einloggen")}, policy: REMOTE_EDITS_ABORT},
+		{name: "key not in remote", uploaded: hashes(text("Log in", "Login")), policy: REMOTE_EDITS_ABORT},
+		{name: "key never uploaded", uploaded: hashes(text("Log in", "Login")),
+			remote: []*Translation{text("Log in", "Login"), text("Log out", "Abmelden")}, policy: REMOTE_EDITS_ABORT},
+		{name: "edited aborts", uploaded: hashes(text("Log in", "Login"), text("Log out", "Logout")),
+			remote: []*Translation{text("Log in", "Anmelden"), text("Log out", "Abmelden")}, policy: REMOTE_EDITS_ABORT,
+			err:   "2 translations of Backend de were edited in phraseapp since the last upload: Log in, Log out",
+			edits: []string{"Backend:de Log in", "Backend:de Log out"}},
+		{name: "edited warns", uploaded: hashes(text("Log in", "Login")),
+			remote: []*Translation{text("Log in", "Anmelden")}, policy: REMOTE_EDITS_WARN,
+			edits: []string{"Backend:de Log in"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runInfo = RunInfo{}
+			if tt.uploaded != nil {
+				runInfo.Uploaded = map[string]map[string]string{"Backend:de": tt.uploaded}
+			}
+			report = RunReport{}
+			remoteEdits = tt.policy
+			defer func() { runInfo, report, remoteEdits = RunInfo{}, RunReport{}, "" }()
+
+			part, err := newUploadPart("", []string{"Log in", "Log out"}, func(id string) *Translation { return upload[id] })
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer removeUploadParts(map[string][]*UploadPart{"de": {part}})
+
+			err = (&i18nGenContext{}).OnRemoteTranslations("Backend", "de", tt.remote, []*UploadPart{part})
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.err != "" && (err == nil || err.Error() != tt.err) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			if strings.Join(report.RemoteEdits, "|") != strings.Join(tt.edits, "|") {
+				t.Errorf("expected remote edits %v, got %v", tt.edits, report.RemoteEdits)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+	"sort"
+)
+
+// ISSUE_SAMPLES is the number of issues of a project locale and rule listed without -verbose.
+const ISSUE_SAMPLES = 3
+
+// Issue is a problem found in a downloaded locale, Key is empty for file level issues.
+type Issue struct {
+	Project string
+	Locale  string
+	Key     string
+	Rule    string
+	Message string
+}
+
+// RunReport collects notable events of a run which are printed as a summary at exit.
+type RunReport struct {
+	EmptyProjects  []string
+	EmptyLocales   []string
+	CreatedLocales []string
+	// LocaleGaps are locales some synced projects have and others miss.
+	LocaleGaps     []string
+	FrozenProjects []string
+	NewKeys        []*ProjectDigest
+	Issues         []*Issue
+	Locales        []*LocaleStatus
+	Stubbed        []string
+	ExpiredKeys    []string
+	// UnseenKeys are keys missing in sources for -unseen-days, with first and last seen dates.
+	UnseenKeys []string
+	// RemoteEdits are translations edited in phraseapp since the last upload which an upload would update.
+	RemoteEdits []string
+	// UntranslatedHits are untranslated keys of -key-hits, most displayed first.
+	UntranslatedHits []string
+	// NeverDisplayed are keys without hits in -key-hits.
+	NeverDisplayed []string
+	SeedCollisions []string
+	Errors         []string
+	Skipped        []string
+	// SkippedWork are uploads and downloads skipped for reasons other than the deadline.
+	SkippedWork []*SkippedWork
+	// Uploads are results of -split-uploads by service.
+	Uploads []string
+	// Changes summarize translations added, changed and removed by downloads, recorded with -changelog.
+	Changes []string
+	// Quarantined are translations replaced with source text by -quarantine.
+	Quarantined []string
+	// Suggestions are translations of similar keys offered for new keys by -suggest-translations.
+	Suggestions []string
+	// MovedKeys are keys which moved between projects, as "id: from -> to".
+	MovedKeys []string
+	// Pii are keys not uploaded because their source text looks like personal data or secrets.
+	Pii   []string
+	Usage map[string]*ApiUsage
+	// Timings are durations of phases of the run, of every upload and locale download.
+	Timings []*Timing
+	// Bundle is the version of downloaded locales kept by -bundles.
+	Bundle string
+	// localeLog are messages of logLocale printed grouped by Print.
+	localeLog []*localeLogLine
+	// usageWarned and limitWarned keep API usage warnings to one per run.
+	usageWarned, limitWarned bool
+}
+
+var report RunReport
+
+func (r *RunReport) AddEmptyProject(projectName string) {
+	r.EmptyProjects = appendUnique(r.EmptyProjects, projectName)
+}
+
+func (r *RunReport) AddEmptyLocale(projectName, localeName string) {
+	r.EmptyLocales = appendUnique(r.EmptyLocales, projectName+":"+localeName)
+}
+
+func (r *RunReport) AddCreatedLocale(projectName, localeName string) {
+	r.CreatedLocales = appendUnique(r.CreatedLocales, projectName+":"+localeName)
+}
+
+func (r *RunReport) AddFrozenProject(projectName string) {
+	r.FrozenProjects = appendUnique(r.FrozenProjects, projectName)
+}
+
+func (r *RunReport) AddDigest(d *ProjectDigest) {
+	r.NewKeys = append(r.NewKeys, d)
+}
+
+func (r *RunReport) AddIssue(issue *Issue) {
+	r.Issues = append(r.Issues, issue)
+}
+
+func (r *RunReport) AddLocaleStatus(s *LocaleStatus) {
+	r.Locales = append(r.Locales, s)
+}
+
+func (r *RunReport) AddStubbed(projectName, localeName string, count int) {
+	r.Stubbed = append(r.Stubbed, fmt.Sprintf("%s:%s %d keys", projectName, localeName, count))
+}
+
+func (r *RunReport) AddExpiredKey(key string) {
+	r.ExpiredKeys = appendUnique(r.ExpiredKeys, key)
+}
+
+func (r *RunReport) AddUnseenKey(key string) {
+	r.UnseenKeys = append(r.UnseenKeys, key)
+}
+
+func (r *RunReport) AddSeedCollision(collision string) {
+	r.SeedCollisions = append(r.SeedCollisions, collision)
+}
+
+func (r *RunReport) AddError(err error) {
+	r.Errors = append(r.Errors, sanitize(err.Error()))
+}
+
+// AddSkipped records a locale skipped by the deadline, empty localeName stands for the whole project.
+func (r *RunReport) AddSkipped(projectName, localeName string) {
+	if localeName == "" {
+		localeName = "*"
+	}
+	r.Skipped = appendUnique(r.Skipped, projectName+":"+localeName)
+}
+
+func (r *RunReport) AddUpload(upload string) {
+	r.Uploads = append(r.Uploads, upload)
+}
+
+func (r *RunReport) AddChange(change string) {
+	r.Changes = append(r.Changes, change)
+}
+
+func (r *RunReport) AddQuarantined(entry string) {
+	r.Quarantined = append(r.Quarantined, entry)
+}
+
+func (r *RunReport) AddSuggestion(suggestion string) {
+	r.Suggestions = append(r.Suggestions, suggestion)
+}
+
+func (r *RunReport) AddApiCall(projectName string, sent, received int64) {
+	if r.Usage == nil {
+		r.Usage = map[string]*ApiUsage{}
+	}
+	u, ok := r.Usage[projectName]
+	if !ok {
+		u = &ApiUsage{}
+		r.Usage[projectName] = u
+	}
+	u.Add(sent, received)
+}
+
+func (r *RunReport) Print() {
+	r.printLocaleLog()
+	printReportSection("Errors:", r.Errors)
+	printReportSection("Locales skipped by deadline:", r.Skipped)
+	printReportSection("Skipped work:", r.skippedWorkLines())
+	printReportSection("Projects without locales:", r.EmptyProjects)
+	printReportSection("Locales without translations:", r.EmptyLocales)
+	printReportSection("Created locales:", r.CreatedLocales)
+	printReportSection("Locales missing in projects:", r.LocaleGaps)
+	printReportSection("Uploads by service:", r.Uploads)
+	printReportSection("Translations edited in phraseapp since the last upload:", r.RemoteEdits)
+	printReportSection("Changed translations:", r.Changes)
+	printReportSection("Projects with uploads blocked by translation freeze:", r.FrozenProjects)
+	printReportSection("Issues in downloaded locales:", r.issueLines())
+	printReportSection("Quarantined translations replaced with source text:", r.Quarantined)
+	printReportSection("Translations suggested for new keys:", r.Suggestions)
+	printReportSection("Keys moved between projects:", r.MovedKeys)
+	printReportSection("Keys not uploaded, they look like personal data or secrets:", r.Pii)
+	printReportSection("Keys stubbed with source text:", r.Stubbed)
+	printReportSection("Deprecated keys past sunset still used in code:", r.ExpiredKeys)
+	printReportSection("Keys unseen in sources:", r.UnseenKeys)
+	printReportSection("Most displayed untranslated keys:", keyHitsSamples(r.UntranslatedHits))
+	printReportSection("Keys never displayed:", keyHitsSamples(r.NeverDisplayed))
+	printReportSection("Seed keys colliding with other definitions:", r.SeedCollisions)
+	printReportSection("New keys awaiting translation:", r.newKeyLines())
+	printReportSection("API usage:", r.usageLines())
+	printOrderedReportSection("Timings:", r.timingLines())
+	if r.Bundle != "" {
+		log.Println("Bundle version:", r.Bundle)
+	}
+}
+
+func (i *Issue) String() string {
+	s := i.Project + ":" + i.Locale
+	if i.Key != "" {
+		s += " " + i.Key
+	}
+	return s + " [" + i.Rule + "] " + i.Message
+}
+
+// issueLines lists ISSUE_SAMPLES issues of every project locale and rule and counts the rest, all with -verbose.
+func (r *RunReport) issueLines() []string {
+	lines := []string{}
+	counts := map[string]int{}
+	for _, i := range r.Issues {
+		group := i.Project + ":" + i.Locale + " [" + i.Rule + "]"
+		counts[group]++
+		if verbose || counts[group] <= ISSUE_SAMPLES {
+			lines = append(lines, i.String())
+		}
+	}
+	groups := make([]string, 0, len(counts))
+	for group := range counts {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+	for _, group := range groups {
+		if count := counts[group]; !verbose && count > ISSUE_SAMPLES {
+			lines = append(lines, fmt.Sprintf("%s %d more", group, count-ISSUE_SAMPLES))
+		}
+	}
+	return lines
+}
+
+func (r *RunReport) newKeyLines() []string {
+	lines := []string{}
+	for _, d := range r.NewKeys {
+		lines = append(lines, d.String())
+	}
+	return lines
+}
+
+func (r *RunReport) usageLines() []string {
+	lines := []string{}
+	for projectName, u := range r.Usage {
+		lines = append(lines, fmt.Sprintf("%s: %d calls, %d bytes uploaded, %d bytes downloaded",
+			projectName, u.Calls, u.BytesSent, u.BytesReceived))
+	}
+	return lines
+}
+
+func printReportSection(title string, items []string) {
+	sorted := append([]string{}, items...)
+	sort.Strings(sorted)
+	printOrderedReportSection(title, sorted)
+}
+
+// printOrderedReportSection prints items in their order, like timings of phases.
+func printOrderedReportSection(title string, items []string) {
+	if len(items) == 0 {
+		return
+	}
+	log.Println(title)
+	for _, item := range items {
+		log.Println("  ", item)
+	}
+}
+
+func appendUnique(items []string, item string) []string {
+	for _, i := range items {
+		if i == item {
+			return items
+		}
+	}
+	return append(items, item)
+}
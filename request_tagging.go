@@ -0,0 +1,26 @@
+package i18n_gen
+
+import "net/http"
+
+const REQUEST_SOURCE_HEADER = "X-Request-Source"
+
+var (
+	userAgentSuffix string
+	requestSource   string
+)
+
+// headerTransport attributes phraseapp requests to the pipeline or machine making them.
+type headerTransport struct {
+	next http.RoundTripper
+}
+
+func (t *headerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if userAgentSuffix != "" {
+		req.Header.Set("User-Agent", req.Header.Get("User-Agent")+" "+userAgentSuffix)
+	}
+	if requestSource != "" {
+		req.Header.Set(REQUEST_SOURCE_HEADER, requestSource)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,98 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// RESUME_ATTEMPTS is the number of Range requests continuing an interrupted download.
+const RESUME_ATTEMPTS = 3
+
+// readPayload reads the body of a download response. A read interrupted before Content-Length is resumed with
+// Range requests when the server accepts them for a strong ETag, the file is then checked against Content-Length
+// and Digest or Content-MD5 headers, if any.
+func readPayload(ctx PhraseappContexter, client *http.Client, newRequest func() (*http.Request, error), resp *http.Response, project, lang string) ([]byte, error) {
+	data, err := ioutil.ReadAll(resp.Body)
+	for attempt := 1; isIncomplete(resp, data, err) && isResumable(resp) && attempt <= RESUME_ATTEMPTS && !ctx.Expired(); attempt++ {
+		logLocalef(project, lang, "Resuming download of locale %s of project %s at byte %d of %d, attempt %d\n", lang, project, len(data), resp.ContentLength, attempt)
+		var rest []byte
+		rest, err = resumePayload(client, newRequest, resp.Header.Get("Etag"), len(data))
+		ctx.OnApiCall(project, 0, 0)
+		data = append(data, rest...)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read body, %v, %s, %s", err, project, lang)
+	}
+	if isIncomplete(resp, data, nil) {
+		return nil, fmt.Errorf("Download is incomplete, %d of %d bytes, %s, %s", len(data), resp.ContentLength, project, lang)
+	}
+	if err := verifyDigest(resp.Header, data); err != nil {
+		return nil, fmt.Errorf("%v, %s, %s", err, project, lang)
+	}
+	return data, nil
+}
+
+func isIncomplete(resp *http.Response, data []byte, err error) bool {
+	return err != nil || (resp.ContentLength >= 0 && int64(len(data)) < resp.ContentLength)
+}
+
+// isResumable tells whether the rest of the body may be requested, offsets of bodies decompressed by the
+// transport don't match ones of the server.
+func isResumable(resp *http.Response) bool {
+	etag := resp.Header.Get("Etag")
+	return resp.Header.Get("Accept-Ranges") == "bytes" && etag != "" && !strings.HasPrefix(etag, "W/") && !resp.Uncompressed
+}
+
+// resumePayload requests the body from offset on, If-Range makes sure it is of the same file.
+func resumePayload(client *http.Client, newRequest func() (*http.Request, error), etag string, offset int) ([]byte, error) {
+	req, err := newRequest()
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Del("If-None-Match")
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	req.Header.Set("If-Range", etag)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent || !strings.HasPrefix(resp.Header.Get("Content-Range"), fmt.Sprintf("bytes %d-", offset)) {
+		return nil, fmt.Errorf("Unable to resume download, %s", resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// verifyDigest checks sha-256 and md5 of Digest (RFC 3230) and Content-MD5 headers, other algorithms are skipped.
+func verifyDigest(header http.Header, data []byte) error {
+	expected := map[string]string{}
+	for _, d := range strings.Split(header.Get("Digest"), ",") {
+		kv := strings.SplitN(strings.TrimSpace(d), "=", 2)
+		if len(kv) == 2 {
+			expected[strings.ToLower(kv[0])] = kv[1]
+		}
+	}
+	if contentMd5 := header.Get("Content-Md5"); contentMd5 != "" {
+		expected["md5"] = contentMd5
+	}
+	sha := sha256.Sum256(data)
+	sum := md5.Sum(data)
+	actual := map[string][]byte{"sha-256": sha[:], "md5": sum[:]}
+	for algorithm, digest := range expected {
+		a, ok := actual[algorithm]
+		if !ok {
+			continue
+		}
+		decoded, err := base64.StdEncoding.DecodeString(digest)
+		if err != nil || !bytes.Equal(decoded, a) {
+			return fmt.Errorf("Download doesn't match %s digest of the server", algorithm)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,141 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyDigest(t *testing.T) {
+	data := []byte(`[{"id": "Log in", "translation": "Einloggen"}]`)
+	sha := sha256.Sum256(data)
+	sum := md5.Sum(data)
+	shaDigest, md5Digest := base64.StdEncoding.EncodeToString(sha[:]), base64.StdEncoding.EncodeToString(sum[:])
+	tests := []struct {
+		name   string
+		header http.Header
+		err    string
+	}{
+		{"no digest", http.Header{}, ""},
+		{"sha-256", http.Header{"Digest": {"SHA-256=" + shaDigest}}, ""},
+		{"md5 and sha-256", http.Header{"Digest": {"MD5=" + md5Digest + ", SHA-256=" + shaDigest}}, ""},
+		{"content-md5", http.Header{"Content-Md5": {md5Digest}}, ""},
+		{"unknown algorithm", http.Header{"Digest": {"UNIXsum=30637"}}, ""},
+		{"wrong sha-256", http.Header{"Digest": {"SHA-256=" + md5Digest}}, "doesn't match sha-256"},
+		{"wrong content-md5", http.Header{"Content-Md5": {shaDigest}}, "doesn't match md5"},
+		{"malformed digest", http.Header{"Digest": {"SHA-256=%%%"}}, "doesn't match sha-256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifyDigest(tt.header, data)
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// brokenBody returns data and fails as if the connection dropped.
+type brokenBody struct {
+	io.Reader
+}
+
+func (b *brokenBody) Read(p []byte) (int, error) {
+	n, err := b.Reader.Read(p)
+	if err == io.EOF {
+		return n, errors.New("unexpected EOF")
+	}
+	return n, err
+}
+
+func (b *brokenBody) Close() error { return nil }
+
+func TestReadPayloadResumes(t *testing.T) {
+	payload := []byte(strings.Repeat(`{"id": "key", "translation": "text"},`, 100))
+	sha := sha256.Sum256(payload)
+	tests := []struct {
+		name string
+		// cut is the number of bytes of the first response, and of every resumed one if step is set
+		cut, step int
+		etag      string
+		ranges    bool
+		digest    string
+		err       string
+		requests  int
+	}{
+		{name: "complete", cut: len(payload), etag: `"v1"`, ranges: true, requests: 0},
+		{name: "resumed once", cut: 100, etag: `"v1"`, ranges: true, requests: 1},
+		{name: "resumed with digest", cut: 100, etag: `"v1"`, ranges: true, digest: base64.StdEncoding.EncodeToString(sha[:]), requests: 1},
+		{name: "resumed till attempts run out", cut: 100, step: 100, etag: `"v1"`, ranges: true, requests: RESUME_ATTEMPTS,
+			err: "Download is incomplete"},
+		{name: "weak etag", cut: 100, etag: `W/"v1"`, ranges: true, err: "Unable to read body"},
+		{name: "no ranges", cut: 100, etag: `"v1"`, err: "Unable to read body"},
+		{name: "digest of another file", cut: 100, etag: `"v1"`, ranges: true, digest: base64.StdEncoding.EncodeToString(sha[:4]),
+			requests: 1, err: "doesn't match sha-256"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests := 0
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests++
+				offset := 0
+				fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-", &offset)
+				if r.Header.Get("If-Range") != tt.etag {
+					http.Error(w, "wrong If-Range", http.StatusPreconditionFailed)
+					return
+				}
+				end := len(payload)
+				if tt.step > 0 && offset+tt.step < end {
+					end = offset + tt.step
+				}
+				w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, len(payload)-1, len(payload)))
+				w.WriteHeader(http.StatusPartialContent)
+				w.Write(payload[offset:end])
+			}))
+			defer server.Close()
+
+			header := http.Header{"Etag": {tt.etag}}
+			if tt.ranges {
+				header.Set("Accept-Ranges", "bytes")
+			}
+			if tt.digest != "" {
+				header.Set("Digest", "SHA-256="+tt.digest)
+			}
+			resp := &http.Response{
+				StatusCode:    http.StatusOK,
+				Header:        header,
+				ContentLength: int64(len(payload)),
+				Body:          &brokenBody{bytes.NewReader(payload[:tt.cut])},
+			}
+			if tt.cut == len(payload) {
+				resp.Body = ioutil.NopCloser(bytes.NewReader(payload))
+			}
+			newRequest := func() (*http.Request, error) { return http.NewRequest("GET", server.URL, nil) }
+			data, err := readPayload(&i18nGenContext{}, server.Client(), newRequest, resp, "Backend", "de")
+			if tt.err == "" && err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Fatalf("expected error %q, got %v", tt.err, err)
+			}
+			if tt.err == "" && !bytes.Equal(data, payload) {
+				t.Errorf("expected the whole payload, got %d of %d bytes", len(data), len(payload))
+			}
+			if requests != tt.requests {
+				t.Errorf("expected %d range requests, got %d", tt.requests, requests)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+type (
+	// ScheduleEntry downloads matching locales at most once per Every, runs in between keep their files.
+	// Locales and Projects are glob patterns, empty Projects match any project.
+	ScheduleEntry struct {
+		Locales  []string       `json:"locales"`
+		Projects []string       `json:"projects"`
+		Every    ConfigDuration `json:"every"`
+	}
+
+	// ConfigDuration is a duration like "5m" or "1h".
+	ConfigDuration struct {
+		time.Duration
+	}
+
+	keptLocaleFile struct {
+		project, locale string
+		path            string
+		data            []byte
+		// prod and subset files don't count in locale status
+		extra bool
+	}
+)
+
+// ignoreSchedule downloads all locales regardless of schedule of the config.
+var ignoreSchedule bool
+
+func (d *ConfigDuration) UnmarshalJSON(data []byte) error {
+	s := ""
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("Expected duration like 5m or 1h, got %s", s)
+	}
+	d.Duration = parsed
+	return nil
+}
+
+func validateSchedule(schedule []*ScheduleEntry) error {
+	for _, e := range schedule {
+		for _, pattern := range append(append([]string{}, e.Locales...), e.Projects...) {
+			if _, err := path.Match(pattern, ""); err != nil {
+				return fmt.Errorf("Invalid schedule pattern %s, %v", pattern, err)
+			}
+		}
+		if e.Every.Duration <= 0 {
+			return fmt.Errorf("Schedule interval of %v should be positive", e.Locales)
+		}
+	}
+	return nil
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// downloadInterval returns interval of the first schedule entry matching the locale, zero if none matches.
+func downloadInterval(projectName, localeName string) time.Duration {
+	for _, e := range config.Schedule {
+		if (len(e.Projects) == 0 || matchAny(e.Projects, projectName)) && matchAny(e.Locales, localeName) {
+			return e.Every.Duration
+		}
+	}
+	return 0
+}
+
+func isDownloadDue(projectName, localeName string, now time.Time) bool {
+	return downloadSkipReason(projectName, localeName, now) == ""
+}
+
+// downloadSkipReason tells why the locale isn't downloaded by this run, it is empty if the download is due.
+func downloadSkipReason(projectName, localeName string, now time.Time) string {
+	if bootstrapMode && isBootstrapped(projectName, localeName) {
+		return SKIP_BOOTSTRAPPED
+	}
+	if ignoreSchedule {
+		return ""
+	}
+	last, ok := runInfo.Downloaded[projectName+":"+localeName]
+	if ok && now.Sub(last) < downloadInterval(projectName, localeName) {
+		// run info shared through -state doesn't bring files, runners without the file download it
+		if _, err := os.Stat(liveFileName(getLocalizationFileName(projectName, localeName))); err == nil {
+			return SKIP_NOT_DUE
+		}
+	}
+	return ""
+}
+
+func (c *i18nGenContext) DownloadDue(projectName, localeName string) bool {
+	reason := downloadSkipReason(projectName, localeName, time.Now())
+	if reason == "" {
+		paceBootstrap()
+		return true
+	}
+	c.OnSkipped(projectName, localeName, OPERATION_DOWNLOAD, reason)
+	return false
+}
+
+func recordDownloadTime(projectName, localeName string, now time.Time) {
+	if runInfo.Downloaded == nil {
+		runInfo.Downloaded = map[string]time.Time{}
+	}
+	runInfo.Downloaded[projectName+":"+localeName] = now
+}
+
+// keepLocalesNotDue reads files of locales which won't be downloaded by this run, from live folders in -candidate
+// mode, the returned restore writes them back once folders are cleared. reportStatus adds their status once sources
+// are extracted.
+func keepLocalesNotDue() (restore, reportStatus func()) {
+	now := time.Now()
+	kept := []*keptLocaleFile{}
+	keep := func(e *CheckSum, path string, extra bool) {
+		if data, err := ioutil.ReadFile(liveFileName(path)); err == nil {
+			kept = append(kept, &keptLocaleFile{e.ProjectName, e.LocaleName, path, data, extra})
+		}
+	}
+	for _, e := range runInfo.CheckSumList {
+		if isDownloadDue(e.ProjectName, e.LocaleName, now) {
+			continue
+		}
+		keep(e, getLocalizationFileName(e.ProjectName, e.LocaleName), false)
+		for subset := range (&i18nGenContext{}).DownloadSubsets(e.ProjectName) {
+			keep(e, getSubsetFileName(e.ProjectName, subset, e.LocaleName), true)
+		}
+		if prodDownload {
+			keep(e, getProdFileName(e.ProjectName, e.LocaleName), true)
+		}
+	}
+	restore = func() {
+		for _, f := range kept {
+			if err := mkdirOutput(filepath.Dir(f.path)); err != nil {
+				fail(fmt.Errorf("Unable to restore folder of scheduled locale %s, %v", f.path, err))
+			}
+			if err := writeOutputFile(f.path, f.data); err != nil {
+				fail(fmt.Errorf("Unable to restore scheduled locale %s, %v", f.path, err))
+			}
+		}
+		if len(kept) > 0 {
+			log.Printf("%d locale files not due for download were kept\n", len(kept))
+		}
+	}
+	reportStatus = func() {
+		for _, f := range kept {
+			if translations, err := ParseLocaleFile(f.data); err == nil && !f.extra {
+				addLocaleStatus(f.project, f.locale, translations)
+			}
+		}
+	}
+	return restore, reportStatus
+}
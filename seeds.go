@@ -0,0 +1,36 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"log"
+)
+
+// mergeSeeds adds keys of hand-maintained go-i18n json files to extracted keys.
+// Ids defined both in code and a seed, or in two seeds, are reported, the first definition wins
+// with code going before seeds and seeds going in config order.
+func mergeSeeds(v *FuncVisitor, paths []string, basepath string) error {
+	origins := map[string]string{}
+	for id := range v.funcNames {
+		origins[id] = "code"
+	}
+	for _, path := range paths {
+		data, err := readSourceFile(basepath, path)
+		if err != nil {
+			return fmt.Errorf("Unable to read seed file %s, %v", path, err)
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			return fmt.Errorf("Unable to parse seed file %s, %v", path, err)
+		}
+		for _, t := range translations {
+			if origin, ok := origins[t.ID]; ok {
+				log.Printf("WARNING! Key %s from %s is already defined in %s\n", t.ID, path, origin)
+				report.AddSeedCollision(t.ID + ": " + origin + ", " + path)
+				continue
+			}
+			origins[t.ID] = path
+			v.seeds[t.ID] = t
+		}
+	}
+	return nil
+}
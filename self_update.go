@@ -0,0 +1,156 @@
+package i18n_gen
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+const RELEASES_URL = "https://api.github.com/repos/gojuno/i18n_gen/releases/latest"
+
+// UpdatePublicKey is a base64 ed25519 public key verifying release binaries, set at link time.
+// Every release asset is accompanied by "<asset>.sig" holding base64 signature of the binary.
+var UpdatePublicKey = ""
+
+type (
+	githubRelease struct {
+		TagName string         `json:"tag_name"`
+		Assets  []*githubAsset `json:"assets"`
+	}
+
+	githubAsset struct {
+		Name string `json:"name"`
+		Url  string `json:"browser_download_url"`
+	}
+)
+
+var (
+	selfUpdateCheckOnly bool
+	selfUpdateUrl       string
+)
+
+var selfUpdateCommand = &command{
+	name:        "self-update",
+	description: "replace the binary with the latest signed release",
+	setFlags: func(fs *flag.FlagSet) {
+		fs.BoolVar(&selfUpdateCheckOnly, "check", false, "only report whether a newer release is available")
+		fs.StringVar(&selfUpdateUrl, "releases-url", RELEASES_URL, "github api url of the latest release")
+	},
+	run: runSelfUpdate,
+}
+
+func runSelfUpdate(fs *flag.FlagSet) {
+	release, err := fetchLatestRelease(selfUpdateUrl)
+	if err != nil {
+		log.Fatalln("Unable to get latest release", err)
+	}
+	if release.TagName == Version {
+		log.Println("i18n_gen is up to date", Version)
+		return
+	}
+	log.Printf("New release %s is available, current version is %s.\n", release.TagName, Version)
+	if selfUpdateCheckOnly {
+		return
+	}
+
+	publicKey, err := base64.StdEncoding.DecodeString(UpdatePublicKey)
+	if err != nil || len(publicKey) != ed25519.PublicKeySize {
+		log.Fatalln("The binary is built without release public key, self-update is disabled")
+	}
+
+	name := "i18n_gen-" + runtime.GOOS + "-" + runtime.GOARCH
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	binary, err := downloadAsset(release, name)
+	if err != nil {
+		log.Fatalln("Unable to download release binary", err)
+	}
+	encodedSig, err := downloadAsset(release, name+".sig")
+	if err != nil {
+		log.Fatalln("Unable to download release signature", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(encodedSig)))
+	if err != nil {
+		log.Fatalln("Release signature is malformed", err)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(publicKey), binary, sig) {
+		log.Fatalln("Release signature does not match, refusing to update")
+	}
+
+	if err := replaceExecutable(binary); err != nil {
+		log.Fatalln("Unable to replace executable", err)
+	}
+	log.Println("i18n_gen was updated to", release.TagName)
+}
+
+func fetchLatestRelease(url string) (*githubRelease, error) {
+	data, err := httpGet(url)
+	if err != nil {
+		return nil, err
+	}
+	release := &githubRelease{}
+	if err := json.Unmarshal(data, release); err != nil {
+		return nil, err
+	}
+	return release, nil
+}
+
+func downloadAsset(release *githubRelease, name string) ([]byte, error) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return httpGet(asset.Url)
+		}
+	}
+	return nil, fmt.Errorf("Release %s has no asset %s", release.TagName, name)
+}
+
+func httpGet(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Error on http request %s, %s", resp.Status, url)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// replaceExecutable writes the new binary next to the running one and renames it over.
+// Running executable can't be overwritten on windows, so it is moved aside first.
+func replaceExecutable(binary []byte) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	exe, err = filepath.EvalSymlinks(exe)
+	if err != nil {
+		return err
+	}
+	tmp := exe + ".new"
+	if err := ioutil.WriteFile(tmp, binary, 0755); err != nil {
+		return err
+	}
+	old := exe + ".old"
+	os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, exe); err != nil {
+		os.Rename(old, exe)
+		return err
+	}
+	os.Remove(old)
+	return nil
+}
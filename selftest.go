@@ -0,0 +1,156 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"reflect"
+
+	"github.com/phrase/phraseapp-go/phraseapp"
+)
+
+// SELFTEST_PROJECT names the sandbox project in logs and api usage.
+const SELFTEST_PROJECT = "selftest"
+
+var selftestSandbox string
+
+var selftestCommand = &command{
+	name:        "selftest",
+	description: "upload extracted source strings to a sandbox project, download them back and verify they round-trip losslessly",
+	setFlags: func(fs *flag.FlagSet) {
+		setCommonFlags(fs)
+		fs.StringVar(&defaultLocale, "locale", "", "sandbox locale to upload to, default locale of the sandbox if empty")
+		fs.StringVar(&selftestSandbox, "sandbox", "", "phraseapp id of the sandbox project, its translations are overwritten")
+	},
+	run: runSelftest,
+}
+
+// selftestContext uploads with translations updated, so sandbox keeps no stale texts of previous runs,
+// and downloads with parameters of -project except the tag, which would drop untagged keys.
+type selftestContext struct {
+	*i18nGenContext
+}
+
+func (c *selftestContext) UpdateTranslationFlag(project string) bool {
+	return true
+}
+
+func (c *selftestContext) UploadTags(projectName, localeName string, part *UploadPart) string {
+	return ""
+}
+
+// UploadParams are none, machine translations of the sandbox would be paid for nothing.
+func (c *selftestContext) UploadParams(project string) phraseapp.UploadParams {
+	return phraseapp.UploadParams{}
+}
+
+func (c *selftestContext) LocaleToCreate(projectName string) string {
+	if defaultLocale == "" {
+		return FALLBACK_LOCALE
+	}
+	return defaultLocale
+}
+
+func (c *selftestContext) DownloadParams(project string) phraseapp.LocaleDownloadParams {
+	params := c.i18nGenContext.DownloadParams(defaultProject)
+	params.Tag = nil
+	return params
+}
+
+func runSelftest(fs *flag.FlagSet) {
+	validateCommonFlags()
+	if selftestSandbox == "" {
+		log.Fatalln("Please, specify -sandbox project id, real projects are never used by selftest")
+	}
+	for name, id := range phraseappProjects {
+		if id == selftestSandbox {
+			log.Fatalf("Sandbox %s is project %s, please, use a project of its own\n", id, name)
+		}
+	}
+	if isSourceArchive(basepath) {
+		if err := loadSourceArchive(basepath); err != nil {
+			log.Fatalln(err)
+		}
+		basepath = "."
+	}
+	if err := loadKeyMapping(config.KeyMapping, basepath); err != nil {
+		log.Fatalln(err)
+	}
+	if err := extractSources(basepath); err != nil {
+		log.Fatalln(err)
+	}
+	ids := v.Ids()
+	if len(ids) == 0 {
+		log.Fatalln("No keys were extracted from", basepath)
+	}
+	worker := connect()
+	localCtx := &selftestContext{&i18nGenContext{}}
+	locales, err := worker.ensureLocales(localCtx, selftestSandbox, SELFTEST_PROJECT)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	lang := defaultLocale
+	if lang == "" {
+		if lang, err = defaultLocaleName(locales, SELFTEST_PROJECT); err != nil {
+			log.Fatalln(err)
+		}
+	}
+	langId := ""
+	for _, l := range locales {
+		if l.Name == lang {
+			langId = l.ID
+		}
+	}
+	if langId == "" {
+		log.Fatalf("Sandbox has no locale %s\n", lang)
+	}
+	part, err := newUploadPart("", ids, v.translation)
+	if err != nil {
+		log.Fatalln("Unable to write upload payload", err)
+	}
+	err = worker.uploadLocaleImpl(localCtx, selftestSandbox, SELFTEST_PROJECT, lang, part)
+	removeUploadParts(map[string][]*UploadPart{lang: {part}})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	data, _, err := worker.downloadLocaleImpl(localCtx, selftestSandbox, SELFTEST_PROJECT, langId, lang, "", "")
+	if err != nil {
+		log.Fatalln(err)
+	}
+	downloaded, err := ParseLocaleFile(data)
+	if err != nil {
+		log.Fatalln("Unable to parse downloaded locale", err)
+	}
+	problems := roundTripProblems(ids, v.translation, downloaded)
+	for _, p := range problems {
+		log.Println(p)
+	}
+	if len(problems) > 0 {
+		log.Fatalf("%d of %d keys didn't round-trip through %s\n", len(problems), len(ids), lang)
+	}
+	log.Printf("%d keys round-tripped through %s losslessly\n", len(ids), lang)
+}
+
+// roundTripProblems compares uploaded keys with downloaded ones regardless of order,
+// keys left in the sandbox by other runs are ignored.
+func roundTripProblems(ids []string, source func(id string) *Translation, downloaded []*Translation) []string {
+	byId := map[string]*Translation{}
+	for _, t := range downloaded {
+		byId[t.ID] = t
+	}
+	problems := []string{}
+	for _, id := range ids {
+		want, got := source(id), byId[id]
+		switch {
+		case got == nil:
+			problems = append(problems, fmt.Sprintf("Key %q is missing in download", id))
+		case want.IsPlural() != got.IsPlural():
+			problems = append(problems, fmt.Sprintf("Key %q plural forms %v came back as %v", id, want.Texts(), got.Texts()))
+		case want.IsPlural() && !reflect.DeepEqual(want.Plural, got.Plural):
+			problems = append(problems, fmt.Sprintf("Key %q plural forms %q came back as %q", id, want.Plural, got.Plural))
+		case !want.IsPlural() && want.Text != got.Text:
+			problems = append(problems, fmt.Sprintf("Key %q text %q came back as %q", id, want.Text, got.Text))
+		}
+	}
+	return problems
+}
@@ -0,0 +1,175 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	serveAddr string
+	// serveRoot is the folder repo paths of requests are relative to, path requests are rejected if it is empty.
+	serveRoot       string
+	serveMaxArchive int64
+)
+
+// extractMu serializes extractions, they share global state of the extractor.
+var extractMu sync.Mutex
+
+// ExtractRequest is the json body of POST /extract with a repo path, Dirs limit extraction like -only.
+type ExtractRequest struct {
+	Path string   `json:"path"`
+	Dirs []string `json:"dirs"`
+}
+
+// ExtractResponse lists extracted keys with source texts and problems found by extraction.
+type ExtractResponse struct {
+	Keys        []*Translation `json:"keys"`
+	Diagnostics []string       `json:"diagnostics"`
+}
+
+var serveCommand = &command{
+	name:        "serve",
+	description: "serve POST /extract returning keys extracted from a repo path or an uploaded archive of sources as json",
+	setFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&configPath, "config", "", "path to json config file")
+		fs.StringVar(&serveAddr, "listen", ":8080", "address to listen on")
+		fs.StringVar(&serveRoot, "root", "", "folder repo paths of requests are relative to, only archives are accepted if empty")
+		fs.Int64Var(&serveMaxArchive, "max-archive", 64<<20, "maximal size of uploaded archives in bytes")
+	},
+	run: runServe,
+}
+
+func runServe(fs *flag.FlagSet) {
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	config = cfg
+	if configPath != "" {
+		if info, err := os.Stat(configPath); err == nil {
+			configModTime = info.ModTime()
+		}
+	}
+	if serveRoot != "" {
+		if serveRoot, err = filepath.Abs(serveRoot); err != nil {
+			log.Fatalln("Unable to resolve -root", err)
+		}
+	}
+	http.HandleFunc("/extract", handleExtract)
+	log.Println("Serving extraction on", serveAddr)
+	log.Fatalln(http.ListenAndServe(serveAddr, nil))
+}
+
+// handleExtract extracts keys of a repo path given as json or of a tar, tar.gz or zip archive body.
+func handleExtract(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	var (
+		resp *ExtractResponse
+		err  error
+	)
+	switch mediaType {
+	case "application/json":
+		req := &ExtractRequest{}
+		if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+			http.Error(w, fmt.Sprintf("Unable to parse request, %v", err), http.StatusBadRequest)
+			return
+		}
+		resp, err = extractPath(req)
+	case "application/gzip", "application/x-gzip", "application/x-tar", "application/zip":
+		var data []byte
+		data, err = ioutil.ReadAll(http.MaxBytesReader(w, r.Body, serveMaxArchive))
+		if err == nil {
+			resp, err = extractArchive(data, mediaType == "application/zip", r.URL.Query()["dir"])
+		}
+	default:
+		http.Error(w, "Content-Type should be application/json or an archive: application/gzip, application/x-tar, application/zip", http.StatusUnsupportedMediaType)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Println("WARNING! Unable to write extraction response", err)
+	}
+}
+
+func extractPath(req *ExtractRequest) (*ExtractResponse, error) {
+	if serveRoot == "" {
+		return nil, fmt.Errorf("Repo paths are not served, upload an archive of sources")
+	}
+	path := filepath.Join(serveRoot, filepath.Clean("/"+req.Path))
+	if info, err := os.Stat(path); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("Repo path %s is not a folder", req.Path)
+	}
+	extractMu.Lock()
+	defer extractMu.Unlock()
+	basepath = path
+	return extractKeys(path, req.Dirs)
+}
+
+func extractArchive(data []byte, isZip bool, dirs []string) (*ExtractResponse, error) {
+	var (
+		files map[string][]byte
+		err   error
+	)
+	if isZip {
+		files, err = readZipSources(data)
+	} else {
+		files, err = readTarSources(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read sources archive, %v", err)
+	}
+	extractMu.Lock()
+	defer extractMu.Unlock()
+	sourceFiles, basepath = files, "."
+	defer func() { sourceFiles = nil }()
+	return extractKeys(".", dirs)
+}
+
+// extractKeys runs extraction of path like sync does, extractMu should be held.
+func extractKeys(path string, dirs []string) (*ExtractResponse, error) {
+	// every request is a run of its own
+	defer func() { report = RunReport{} }()
+	reloadConfig()
+	if err := loadKeyMapping(config.KeyMapping, path); err != nil {
+		return nil, err
+	}
+	for _, dir := range dirs {
+		if strings.HasPrefix(filepath.Clean(dir), "..") || filepath.IsAbs(dir) {
+			return nil, fmt.Errorf("Folder %s is outside of sources", dir)
+		}
+		if sourceFiles != nil && !hasSourceDir(dir) {
+			return nil, fmt.Errorf("Folder %s has no sources", dir)
+		}
+		if info, err := os.Stat(filepath.Join(path, dir)); sourceFiles == nil && (err != nil || !info.IsDir()) {
+			return nil, fmt.Errorf("Folder %s is not found", dir)
+		}
+	}
+	resp := &ExtractResponse{Keys: []*Translation{}, Diagnostics: []string{}}
+	if err := extractSources(path, dirs...); err != nil {
+		return nil, err
+	}
+	for _, id := range v.Ids() {
+		resp.Keys = append(resp.Keys, v.translation(id))
+	}
+	for _, d := range v.diagnostics {
+		resp.Diagnostics = append(resp.Diagnostics, d.String())
+	}
+	return resp, nil
+}
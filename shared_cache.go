@@ -0,0 +1,242 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// CACHE_TOKEN_ENV is a secret shared by the cache server and its clients, the server doesn't start without it.
+	CACHE_TOKEN_ENV = "I18N_GEN_CACHE_TOKEN"
+	CACHE_PATH      = "/cache/"
+	CACHE_TIMEOUT   = 10 * time.Second
+)
+
+var (
+	// cacheUrl is the shared cache server downloads reuse payloads of, e.g. http://i18n-cache:8081.
+	cacheUrl string
+
+	cacheServerAddr string
+	cacheServerDir  string
+	cacheMaxEntry   int64
+)
+
+var cacheKeyPattern = regexp.MustCompile(`^[0-9a-f]{64}$`)
+
+var cacheServerCommand = &command{
+	name:        "cache-server",
+	description: "serve ETags and payloads of locale downloads to CLIs pointed at it by -cache-url",
+	setFlags: func(fs *flag.FlagSet) {
+		fs.StringVar(&cacheServerAddr, "listen", ":8081", "address to listen on")
+		fs.StringVar(&cacheServerDir, "dir", "", "folder to keep cached payloads in")
+		fs.Int64Var(&cacheMaxEntry, "max-entry", 64<<20, "maximal size of a cached payload in bytes")
+		fs.BoolVar(&useStateKeyring, "state-keyring", false, "read cache encryption key from OS keyring when "+STATE_KEY_ENV+" is not set")
+	},
+	run: runCacheServer,
+}
+
+// cacheKey identifies a download by project, locale and parameters, payloads of equal requests are equal.
+func cacheKey(url string, params []byte) string {
+	sum := sha256.Sum256([]byte(url + "\n" + string(params)))
+	return hex.EncodeToString(sum[:])
+}
+
+func runCacheServer(fs *flag.FlagSet) {
+	if cacheServerDir == "" {
+		log.Fatalln("Please, specify -dir to keep cached payloads in")
+	}
+	if err := os.MkdirAll(cacheServerDir, 0700); err != nil {
+		log.Fatalln("Unable to create cache folder", err)
+	}
+	// entries are encrypted at rest with the state key of the server
+	if err := initStateKey(); err != nil {
+		log.Fatalln(err)
+	}
+	if os.Getenv(CACHE_TOKEN_ENV) == "" {
+		log.Fatalln("Please, set", CACHE_TOKEN_ENV, "shared with clients of the cache")
+	}
+	http.HandleFunc(CACHE_PATH, handleCache)
+	log.Println("Serving cache of", cacheServerDir, "on", cacheServerAddr)
+	log.Fatalln(http.ListenAndServe(cacheServerAddr, nil))
+}
+
+// handleCache serves GET and PUT of /cache/<key>, entries are the ETag header and the payload body.
+func handleCache(w http.ResponseWriter, r *http.Request) {
+	if token := os.Getenv(CACHE_TOKEN_ENV); token == "" || r.Header.Get("Authorization") != "Bearer "+token {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+	key := strings.TrimPrefix(r.URL.Path, CACHE_PATH)
+	if !cacheKeyPattern.MatchString(key) {
+		http.NotFound(w, r)
+		return
+	}
+	path := filepath.Join(cacheServerDir, key)
+	switch r.Method {
+	case http.MethodGet:
+		data, err := ioutil.ReadFile(path)
+		if err == nil {
+			data, err = openState(data)
+		}
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Etag", string(data[:i]))
+		w.Header().Set("Digest", payloadDigest(data[i+1:]))
+		w.Write(data[i+1:])
+	case http.MethodPut:
+		etag := r.Header.Get("Etag")
+		if etag == "" || strings.ContainsAny(etag, "\r\n") {
+			http.Error(w, "Etag header is required", http.StatusBadRequest)
+			return
+		}
+		data, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, cacheMaxEntry))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+			return
+		}
+		// payloads are checked against the digest of the uploader, so clients may verify what they get
+		if r.Header.Get("Digest") == "" {
+			http.Error(w, "Digest header is required", http.StatusBadRequest)
+			return
+		}
+		if err := verifyDigest(r.Header, data); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		// an entry of an ETag is never replaced with another payload
+		if stored, err := ioutil.ReadFile(path); err == nil {
+			if stored, err = openState(stored); err == nil && bytes.HasPrefix(stored, []byte(etag+"\n")) &&
+				!bytes.Equal(stored[len(etag)+1:], data) {
+				http.Error(w, "Entry of the ETag has another payload", http.StatusConflict)
+				return
+			}
+		}
+		if err := storeCacheEntry(path, etag, data); err != nil {
+			log.Println("WARNING! Unable to store cache entry", key, err)
+			http.Error(w, "Unable to store entry", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Only GET and PUT are supported", http.StatusMethodNotAllowed)
+	}
+}
+
+// storeCacheEntry replaces the entry at once, readers never see an ETag with a payload of another one.
+func storeCacheEntry(path, etag string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), "entry")
+	if err != nil {
+		return err
+	}
+	sealed, err := sealState(append([]byte(etag+"\n"), data...))
+	if err == nil {
+		_, err = f.Write(sealed)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(f.Name(), path)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+	}
+	return err
+}
+
+var (
+	cacheClient     = &http.Client{Timeout: CACHE_TIMEOUT}
+	cacheWarnedOnce sync.Once
+)
+
+// warnCache warns about the first failure of the shared cache only, downloads go on without it.
+func warnCache(err error) {
+	cacheWarnedOnce.Do(func() {
+		log.Println("WARNING! Shared cache is unavailable, locales are downloaded from phraseapp", err)
+	})
+}
+
+func cacheRequest(method, key string, etag string, data []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(requestContext(), method, strings.TrimSuffix(cacheUrl, "/")+CACHE_PATH+key, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if token := os.Getenv(CACHE_TOKEN_ENV); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if etag != "" {
+		req.Header.Set("Etag", etag)
+	}
+	if data != nil {
+		req.Header.Set("Digest", payloadDigest(data))
+	}
+	return cacheClient.Do(req)
+}
+
+// payloadDigest is the Digest header (RFC 3230) of the payload.
+func payloadDigest(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha-256=" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// cachedPayload returns the payload and ETag of the shared cache entry, nil if there is none or it doesn't match
+// its digest.
+func cachedPayload(key string) ([]byte, string) {
+	resp, err := cacheRequest(http.MethodGet, key, "", nil)
+	if err != nil {
+		warnCache(err)
+		return nil, ""
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ""
+	}
+	if resp.StatusCode != http.StatusOK {
+		warnCache(fmt.Errorf("cache responded %s", resp.Status))
+		return nil, ""
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err == nil && resp.Header.Get("Digest") == "" {
+		err = fmt.Errorf("cache entry has no digest")
+	}
+	if err == nil {
+		err = verifyDigest(resp.Header, data)
+	}
+	if err != nil {
+		warnCache(err)
+		return nil, ""
+	}
+	return data, resp.Header.Get("Etag")
+}
+
+func storeCachedPayload(key, etag string, data []byte) {
+	resp, err := cacheRequest(http.MethodPut, key, etag, data)
+	if err != nil {
+		warnCache(err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		warnCache(fmt.Errorf("cache responded %s", resp.Status))
+	}
+}
@@ -0,0 +1,79 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	OPERATION_SYNC     = "sync"
+	OPERATION_UPLOAD   = "upload"
+	OPERATION_DOWNLOAD = "download"
+
+	SKIP_MIN_INTERVAL = "previous run finished less than 2s ago"
+	SKIP_NOT_MODIFIED = "not modified since the last download, checksum of the file matches (304)"
+	SKIP_NOT_DUE      = "not due by schedule"
+	SKIP_BOOTSTRAPPED = "downloaded by -bootstrap already"
+	SKIP_NOT_SYNCED   = "project is not synced by -only"
+	SKIP_FROZEN       = "translation freeze"
+	SKIP_NO_LOCALES   = "project has no locales"
+)
+
+// SkippedWork is an upload or download the run didn't do, and why. Empty Locale stands for the whole project.
+type SkippedWork struct {
+	Project   string `json:"project"`
+	Locale    string `json:"locale,omitempty"`
+	Operation string `json:"operation"`
+	Reason    string `json:"reason"`
+}
+
+func (s *SkippedWork) String() string {
+	name := s.Project
+	if s.Locale != "" {
+		name += ":" + s.Locale
+	}
+	return fmt.Sprintf("%s %s, %s", name, s.Operation, s.Reason)
+}
+
+// OnSkipped records work skipped for a reason other than the deadline, it is logged with -verbose.
+func (c *i18nGenContext) OnSkipped(projectName, localeName, operation, reason string) {
+	report.AddSkippedWork(&SkippedWork{projectName, localeName, operation, reason})
+	if verbose {
+		logLocalef(projectName, localeName, "Skipped %s of %s %s, %s\n", operation, projectName, localeName, reason)
+	}
+}
+
+// reportProjectsNotSynced records downloads of projects left out by -only.
+func reportProjectsNotSynced() {
+	synced := syncProjects()
+	for projectName := range phraseappProjects {
+		if _, ok := synced[projectName]; !ok {
+			(&i18nGenContext{}).OnSkipped(projectName, "", OPERATION_DOWNLOAD, SKIP_NOT_SYNCED)
+		}
+	}
+}
+
+func (r *RunReport) AddSkippedWork(s *SkippedWork) {
+	r.SkippedWork = append(r.SkippedWork, s)
+}
+
+// skippedWorkLines counts skipped work by operation and reason, every entry is listed with -verbose.
+func (r *RunReport) skippedWorkLines() []string {
+	lines := []string{}
+	if verbose {
+		for _, s := range r.SkippedWork {
+			lines = append(lines, s.String())
+		}
+		sort.Strings(lines)
+		return lines
+	}
+	counts := map[string]int{}
+	for _, s := range r.SkippedWork {
+		counts[s.Operation+", "+s.Reason]++
+	}
+	for group, count := range counts {
+		lines = append(lines, fmt.Sprintf("%s: %d", group, count))
+	}
+	sort.Strings(lines)
+	return lines
+}
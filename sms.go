@@ -0,0 +1,190 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	QA_RULE_SMS = "sms"
+
+	SMS_CHARSET_GSM7 = "gsm7"
+	SMS_CHARSET_UCS2 = "ucs2"
+
+	// GSM_7_BASIC are characters of the GSM 03.38 default alphabet taking a septet, GSM_7_EXTENSION take two.
+	GSM_7_BASIC     = "@£$¥èéùìòÇ\nØø\rÅåΔ_ΦΓΛΩΠΨΣΘΞÆæßÉ !\"#¤%&'()*+,-./0123456789:;<=>?¡ABCDEFGHIJKLMNOPQRSTUVWXYZÄÖÑÜ§¿abcdefghijklmnopqrstuvwxyzäöñüà"
+	GSM_7_EXTENSION = "\f^{}\\[~]|€"
+
+	// lengths of a single message and of a part of a multi-part one, multi-part messages spend some on headers.
+	SMS_GSM_7_SINGLE = 160
+	SMS_GSM_7_PART   = 153
+	SMS_UCS_2_SINGLE = 70
+	SMS_UCS_2_PART   = 67
+)
+
+// SmsConfig constrains translations of keys tagged with Tag, which are delivered by SMS.
+type SmsConfig struct {
+	Tag string `json:"tag"`
+	// Charset is gsm7, default, or ucs2, Locales override it by locale name.
+	Charset string            `json:"charset"`
+	Locales map[string]string `json:"locales"`
+	// MaxParts is the number of parts a message may take, 1 if zero.
+	MaxParts int `json:"max_parts"`
+	// MaxLength limits characters of a translation, unlimited if zero.
+	MaxLength int `json:"max_length"`
+}
+
+func validateSms(sms []*SmsConfig) error {
+	for _, s := range sms {
+		if s.Tag == "" {
+			return fmt.Errorf("Sms constraints need a tag of keys they apply to")
+		}
+		if s.MaxParts < 0 || s.MaxLength < 0 {
+			return fmt.Errorf("Sms limits of tag %s should not be negative", s.Tag)
+		}
+		charsets := []string{s.Charset}
+		for _, c := range s.Locales {
+			charsets = append(charsets, c)
+		}
+		for _, c := range charsets {
+			if c != "" && c != SMS_CHARSET_GSM7 && c != SMS_CHARSET_UCS2 {
+				return fmt.Errorf("Sms charset of tag %s should be %s or %s, got %s", s.Tag, SMS_CHARSET_GSM7, SMS_CHARSET_UCS2, c)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *SmsConfig) charset(localeName string) string {
+	if c, ok := s.Locales[localeName]; ok && c != "" {
+		return c
+	}
+	if s.Charset != "" {
+		return s.Charset
+	}
+	return SMS_CHARSET_GSM7
+}
+
+func (s *SmsConfig) maxParts() int {
+	if s.MaxParts > 0 {
+		return s.MaxParts
+	}
+	return 1
+}
+
+// smsEncoding returns characters of text outside of GSM-7 and the number of parts of the message:
+// septets of GSM-7 text, UTF-16 code units once a single character needs UCS-2.
+func smsEncoding(text string) (ucs2 []string, parts int) {
+	septets := 0
+	for _, r := range text {
+		switch {
+		case strings.ContainsRune(GSM_7_BASIC, r):
+			septets++
+		case strings.ContainsRune(GSM_7_EXTENSION, r):
+			septets += 2
+		default:
+			ucs2 = appendUnique(ucs2, string(r))
+		}
+	}
+	if len(ucs2) > 0 {
+		return ucs2, smsParts(len(utf16.Encode([]rune(text))), SMS_UCS_2_SINGLE, SMS_UCS_2_PART)
+	}
+	return nil, smsParts(septets, SMS_GSM_7_SINGLE, SMS_GSM_7_PART)
+}
+
+func smsParts(length, single, part int) int {
+	if length <= single {
+		return 1
+	}
+	return (length + part - 1) / part
+}
+
+// check returns problems of the translation in the locale, empty if it fits.
+func (s *SmsConfig) check(localeName, text string) []string {
+	problems := []string{}
+	ucs2, parts := smsEncoding(text)
+	if len(ucs2) > 0 && s.charset(localeName) == SMS_CHARSET_GSM7 {
+		problems = append(problems, fmt.Sprintf("forces UCS-2 with %s", strings.Join(ucs2, " ")))
+	}
+	if parts > s.maxParts() {
+		problems = append(problems, fmt.Sprintf("takes %d parts, %d allowed", parts, s.maxParts()))
+	}
+	if length := len([]rune(text)); s.MaxLength > 0 && length > s.MaxLength {
+		problems = append(problems, fmt.Sprintf("has %d characters, %d allowed", length, s.MaxLength))
+	}
+	return problems
+}
+
+// checkSmsLimits reports translations of SMS keys, by config.Sms tags, in downloaded locales of synced projects
+// which need UCS-2 where GSM-7 is declared, take more parts than allowed or are too long.
+func checkSmsLimits(worker *PhraseappWorkerContext) {
+	severity := config.Qa.severity(QA_RULE_SMS)
+	if severity == QA_SEVERITY_OFF {
+		return
+	}
+	projects := syncProjects()
+	names := make([]string, 0, len(projects))
+	for name := range projects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, projectName := range names {
+		tags, err := worker.KeyTags(&i18nGenContext{}, projects[projectName], projectName)
+		if err != nil {
+			log.Println("WARNING! Unable to check sms limits", err)
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+		if err != nil {
+			log.Println("WARNING! Unable to list locale files", projectName, err)
+			continue
+		}
+		for _, path := range files {
+			localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+			checkSmsLocale(projectName, localeName, path, tags, severity)
+		}
+	}
+}
+
+func checkSmsLocale(projectName, localeName, path string, tags map[string][]string, severity string) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		log.Println("WARNING! Unable to read locale file", path, err)
+		return
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		// subset files and merged projects are not locale files of the project
+		return
+	}
+	for _, t := range translations {
+		for _, s := range config.Sms {
+			if !hasTag(tags[t.ID], s.Tag) {
+				continue
+			}
+			problems := []string{}
+			for _, text := range t.Texts() {
+				for _, p := range s.check(localeName, text) {
+					problems = appendUnique(problems, p)
+				}
+			}
+			if len(problems) == 0 {
+				continue
+			}
+			issue := &Issue{Project: projectName, Locale: localeName, Key: t.ID, Rule: QA_RULE_SMS,
+				Message: fmt.Sprintf("sms %s", strings.Join(problems, ", "))}
+			if config.Qa.isSuppressed(issue) {
+				continue
+			}
+			report.AddIssue(issue)
+			if severity == QA_SEVERITY_ERROR {
+				report.AddError(fmt.Errorf("%s", issue))
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package i18n_gen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSmsEncoding(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		ucs2  []string
+		parts int
+	}{
+		{"empty", "", nil, 1},
+		{"single gsm-7 part", strings.Repeat("a", SMS_GSM_7_SINGLE), nil, 1},
+		{"two gsm-7 parts", strings.Repeat("a", SMS_GSM_7_SINGLE+1), nil, 2},
+		{"three gsm-7 parts", strings.Repeat("a", 2*SMS_GSM_7_PART+1), nil, 3},
+		{"extension characters take two septets", strings.Repeat("€", SMS_GSM_7_SINGLE/2+1), nil, 2},
+		{"single ucs-2 part", "Привет", []string{"П", "р", "и", "в", "е", "т"}, 1},
+		{"ucs-2 forced by one character", strings.Repeat("a", SMS_UCS_2_SINGLE) + "’", []string{"’"}, 2},
+		{"characters out of bmp take two code units", strings.Repeat("😀", SMS_UCS_2_SINGLE/2), []string{"😀"}, 1},
+		{"two ucs-2 parts of surrogate pairs", strings.Repeat("😀", SMS_UCS_2_SINGLE/2+1), []string{"😀"}, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ucs2, parts := smsEncoding(tt.text)
+			if !reflect.DeepEqual(ucs2, tt.ucs2) {
+				t.Errorf("expected ucs-2 characters %q, got %q", tt.ucs2, ucs2)
+			}
+			if parts != tt.parts {
+				t.Errorf("expected %d parts, got %d", tt.parts, parts)
+			}
+		})
+	}
+}
@@ -0,0 +1,173 @@
+package i18n_gen
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// STDIN_PATH is -path reading a tar stream of sources, gzipped or not, from stdin.
+const STDIN_PATH = "-"
+
+// sourceFiles are files of the -path archive by slash separated path, nil when -path is a folder.
+// Only files extraction may need are kept: go sources, json seeds and key mapping, yaml notes, email templates.
+var sourceFiles map[string][]byte
+
+func isSourceArchive(path string) bool {
+	lower := strings.ToLower(path)
+	return path == STDIN_PATH || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz") ||
+		strings.HasSuffix(lower, ".tar") || strings.HasSuffix(lower, ".zip")
+}
+
+func isArchivedSource(name string) bool {
+	switch path.Ext(name) {
+	case ".go", ".json", ".yaml", ".yml":
+		return true
+	}
+	return isEmailTemplate(name)
+}
+
+// loadSourceArchive reads sources of the archive into memory.
+func loadSourceArchive(archive string) error {
+	var (
+		data []byte
+		err  error
+	)
+	if archive == STDIN_PATH {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		data, err = ioutil.ReadFile(archive)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read sources archive %s, %v", archive, err)
+	}
+	if strings.HasSuffix(strings.ToLower(archive), ".zip") {
+		sourceFiles, err = readZipSources(data)
+	} else {
+		sourceFiles, err = readTarSources(data)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to read sources archive %s, %v", archive, err)
+	}
+	return nil
+}
+
+func readTarSources(data []byte) (map[string][]byte, error) {
+	var r io.Reader = bytes.NewReader(data)
+	// gzip magic number
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		r = gz
+	}
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !isArchivedSource(header.Name) {
+			continue
+		}
+		content, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(header.Name)] = content
+	}
+}
+
+func readZipSources(data []byte) (map[string][]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() || !isArchivedSource(f.Name) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(f.Name)] = content
+	}
+	return files, nil
+}
+
+// readSourceFile reads a file of the config, relative to base, from the archive when -path is one.
+func readSourceFile(base, name string) ([]byte, error) {
+	if filepath.IsAbs(name) {
+		return ioutil.ReadFile(name)
+	}
+	if sourceFiles == nil {
+		return ioutil.ReadFile(filepath.Join(base, name))
+	}
+	data, ok := sourceFiles[path.Clean(filepath.ToSlash(name))]
+	if !ok {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	return data, nil
+}
+
+// hasSourceDir reports whether the archive has files in dir.
+func hasSourceDir(dir string) bool {
+	prefix := path.Clean(filepath.ToSlash(dir)) + "/"
+	for name := range sourceFiles {
+		if prefix == "./" || strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// scanSourceArchive scans go files of the archive in dirs, the whole archive if no dirs given.
+func scanSourceArchive(dirs []string) {
+	names := make([]string, 0, len(sourceFiles))
+	for name := range sourceFiles {
+		if strings.HasSuffix(name, ".go") && inSourceDirs(name, dirs) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		data := sourceFiles[name]
+		isSource := isLocalizationSource(name)
+		if isSource || inConstPackage(name) || containsExtractionMarkers(data) {
+			scanAsync(name, data, isSource)
+		}
+	}
+}
+
+func inSourceDirs(name string, dirs []string) bool {
+	if len(dirs) == 0 {
+		return true
+	}
+	for _, dir := range dirs {
+		if strings.HasPrefix(name, path.Clean(filepath.ToSlash(dir))+"/") {
+			return true
+		}
+	}
+	return false
+}
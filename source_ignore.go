@@ -0,0 +1,142 @@
+package i18n_gen
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ignoreFiles list paths the source walk skips in every folder, .gitignore syntax.
+var ignoreFiles = []string{".gitignore", ".i18nignore"}
+
+// walkIgnore prunes the walk of extraction.
+var walkIgnore *sourceIgnore
+
+type (
+	// sourceIgnore prunes paths under -path matching ignore files of their folders.
+	sourceIgnore struct {
+		root string
+		// rules are rules of ignore files by folder, loaded on first use
+		rules map[string][]*ignoreRule
+	}
+
+	ignoreRule struct {
+		pattern string
+		negate  bool
+		dirOnly bool
+		// anchored patterns are matched against the path relative to the folder of the ignore file,
+		// others against the name
+		anchored bool
+	}
+)
+
+func newSourceIgnore(root string) *sourceIgnore {
+	return &sourceIgnore{root: filepath.Clean(root), rules: map[string][]*ignoreRule{}}
+}
+
+// skip reports whether the walk should skip path, the last matching rule of the closest folder wins.
+func (s *sourceIgnore) skip(p string, info os.FileInfo) bool {
+	if info.IsDir() && info.Name() == ".git" {
+		return true
+	}
+	rel, err := filepath.Rel(s.root, p)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return false
+	}
+	parts := strings.Split(filepath.ToSlash(rel), "/")
+	ignored := false
+	dir := s.root
+	for i := range parts {
+		for _, r := range s.load(dir) {
+			if r.match(strings.Join(parts[i:], "/"), info.IsDir()) {
+				ignored = !r.negate
+			}
+		}
+		dir = filepath.Join(dir, parts[i])
+	}
+	return ignored
+}
+
+func (s *sourceIgnore) load(dir string) []*ignoreRule {
+	if rules, ok := s.rules[dir]; ok {
+		return rules
+	}
+	rules := []*ignoreRule{}
+	for _, name := range ignoreFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if !os.IsNotExist(err) {
+				log.Println("WARNING! Unable to read ignore file", filepath.Join(dir, name), err)
+			}
+			continue
+		}
+		rules = append(rules, parseIgnoreRules(data)...)
+	}
+	s.rules[dir] = rules
+	return rules
+}
+
+func parseIgnoreRules(data []byte) []*ignoreRule {
+	rules := []*ignoreRule{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		r := &ignoreRule{}
+		if strings.HasPrefix(line, "!") {
+			r.negate, line = true, line[1:]
+		} else if strings.HasPrefix(line, `\`) {
+			line = line[1:]
+		}
+		if strings.HasSuffix(line, "/") {
+			r.dirOnly, line = true, strings.TrimRight(line, "/")
+		}
+		r.anchored = strings.Contains(line, "/")
+		r.pattern = strings.TrimPrefix(line, "/")
+		if r.pattern != "" {
+			rules = append(rules, r)
+		}
+	}
+	return rules
+}
+
+// match reports whether rel, relative to the folder of the rule, matches it.
+func (r *ignoreRule) match(rel string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if !r.anchored {
+		ok, _ := path.Match(r.pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(r.pattern, "/"), strings.Split(rel, "/"))
+}
+
+// matchSegments matches path segments with glob segments, ** matches any number of segments.
+func matchSegments(pattern, segments []string) bool {
+	if len(pattern) == 0 {
+		return len(segments) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(segments); i++ {
+			if matchSegments(pattern[1:], segments[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(segments) == 0 {
+		return false
+	}
+	if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+		return false
+	}
+	return matchSegments(pattern[1:], segments[1:])
+}
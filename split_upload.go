@@ -0,0 +1,51 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// SERVICE_TAG_PREFIX starts tags of keys uploaded by -split-uploads, followed by the service folder name.
+const SERVICE_TAG_PREFIX = "service-"
+
+// splitUploads uploads keys of every top folder of -path, a service, separately and tagged by the service,
+// locales are still downloaded whole.
+var splitUploads bool
+
+// serviceOf returns the top folder of path relative to -path, empty for files outside of folders.
+func serviceOf(path string) string {
+	rel, err := filepath.Rel(basepath, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) < 2 {
+		return ""
+	}
+	return parts[0]
+}
+
+// serviceParts returns upload parts of services of keys routed to the project sorted by name, keys found outside
+// of services and keys of seeds only go in an untagged part.
+func serviceParts(v *FuncVisitor, projectName string) []*UploadPart {
+	parts := []*UploadPart{}
+	serviceIds := map[string][]string{}
+	for service, ids := range v.serviceIds() {
+		routed := routeIds(ids)[projectName]
+		if len(routed) > 0 || service == "" {
+			serviceIds[service] = routed
+		}
+	}
+	source := unescapingSource(projectName, v.translation)
+	for service, ids := range serviceIds {
+		part, err := newUploadPart(service, ids, source)
+		if err != nil {
+			fail(fmt.Errorf("Unable to write upload payload, %v", err))
+		}
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Name < parts[j].Name })
+	return parts
+}
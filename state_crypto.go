@@ -0,0 +1,118 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+const (
+	STATE_KEY_ENV      = "I18N_GEN_STATE_KEY"
+	STATE_KEY_SERVICE  = "i18n_gen"
+	SEALED_STATE_MAGIC = "i18n_gen:aes-gcm:"
+)
+
+// stateKey encrypts run info and cached payloads at rest when not empty.
+var stateKey []byte
+
+// loadStateKey derives AES-256 key from I18N_GEN_STATE_KEY or, if asked, from the OS keyring.
+func loadStateKey(useKeyring bool) ([]byte, error) {
+	secret := os.Getenv(STATE_KEY_ENV)
+	if secret == "" && useKeyring {
+		var err error
+		secret, err = readKeyring()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if secret == "" {
+		return nil, nil
+	}
+	key := sha256.Sum256([]byte(secret))
+	return key[:], nil
+}
+
+// initStateKey loads the state key of the run honoring -state-keyring, it's called once by commands keeping state at rest.
+func initStateKey() error {
+	key, err := loadStateKey(useStateKeyring)
+	if err != nil {
+		return fmt.Errorf("Unable to load state key, %v", err)
+	}
+	stateKey = key
+	return nil
+}
+
+func readKeyring() (string, error) {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("security", "find-generic-password", "-s", STATE_KEY_SERVICE, "-w")
+	case "linux":
+		cmd = exec.Command("secret-tool", "lookup", "service", STATE_KEY_SERVICE)
+	default:
+		return "", fmt.Errorf("Keyring is not supported on %s, use %s", runtime.GOOS, STATE_KEY_ENV)
+	}
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("Unable to read state key from keyring, %v", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// sealState encrypts data with the state key, data is returned as is when encryption is disabled.
+func sealState(data []byte) ([]byte, error) {
+	if len(stateKey) == 0 {
+		return data, nil
+	}
+	gcm, err := newStateCipher()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	sealed := append([]byte(SEALED_STATE_MAGIC), nonce...)
+	return gcm.Seal(sealed, nonce, data, []byte(SEALED_STATE_MAGIC)), nil
+}
+
+// openState decrypts data produced by sealState. Plain data is passed through so
+// state written before encryption was enabled is still readable.
+func openState(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte(SEALED_STATE_MAGIC)) {
+		return data, nil
+	}
+	if len(stateKey) == 0 {
+		return nil, fmt.Errorf("State is encrypted, but %s is not set", STATE_KEY_ENV)
+	}
+	gcm, err := newStateCipher()
+	if err != nil {
+		return nil, err
+	}
+	data = data[len(SEALED_STATE_MAGIC):]
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("Encrypted state is truncated")
+	}
+	nonce, sealed := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plain, err := gcm.Open(nil, nonce, sealed, []byte(SEALED_STATE_MAGIC))
+	if err != nil {
+		return nil, fmt.Errorf("Unable to decrypt state, %v", err)
+	}
+	return plain, nil
+}
+
+func newStateCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(stateKey)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
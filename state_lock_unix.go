@@ -0,0 +1,18 @@
+//go:build !windows
+// +build !windows
+
+package i18n_gen
+
+import (
+	"os"
+	"syscall"
+)
+
+// lockFile takes exclusive flock of the file, waiting for other runs to release it.
+func lockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_EX)
+}
+
+func unlockFile(file *os.File) error {
+	return syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+}
@@ -0,0 +1,15 @@
+//go:build windows
+// +build windows
+
+package i18n_gen
+
+import "os"
+
+// lockFile is a no-op on windows, state files are not shared between runners there.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) error {
+	return nil
+}
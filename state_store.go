@@ -0,0 +1,91 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// StateStore persists run info between runs. Store is locked from open till Close,
+// so runners sharing the state don't overwrite each other's checksums.
+type StateStore interface {
+	// Load returns stored state, nil if nothing was stored yet.
+	Load() ([]byte, error)
+	Save(data []byte) error
+	// Close releases the lock of the store.
+	Close() error
+}
+
+var (
+	// stateLocation is a file path, s3://bucket/key or redis://[:password@]host:port/key, local cache file if empty.
+	stateLocation string
+	stateStore    StateStore
+)
+
+func openStateStore(location string) (StateStore, error) {
+	if location == "" {
+		return openFileStore(getRunInfoFileName())
+	}
+	u, err := url.Parse(location)
+	if err != nil || u.Scheme == "" || len(u.Scheme) == 1 { // windows drive letters look like schemes
+		return openFileStore(location)
+	}
+	switch u.Scheme {
+	case "file":
+		return openFileStore(u.Path)
+	case "s3":
+		return newS3Store(u.Host, strings.TrimPrefix(u.Path, "/"))
+	case "redis":
+		return openRedisStore(u)
+	}
+	return nil, fmt.Errorf("Unsupported state store %s", location)
+}
+
+// fileStore keeps the state in a local file locked with flock.
+type fileStore struct {
+	file *os.File
+}
+
+func openFileStore(path string) (*fileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("Unable to create state folder, %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to open state file %s, %v", path, err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Unable to lock state file %s, %v", path, err)
+	}
+	return &fileStore{file}, nil
+}
+
+func (s *fileStore) Load() ([]byte, error) {
+	if _, err := s.file.Seek(0, 0); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(s.file)
+	if err != nil || len(data) == 0 {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *fileStore) Save(data []byte) error {
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := s.file.WriteAt(data, 0); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *fileStore) Close() error {
+	unlockFile(s.file)
+	return s.file.Close()
+}
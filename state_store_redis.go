@@ -0,0 +1,158 @@
+package i18n_gen
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	REDIS_DEFAULT_ADDR  = "localhost:6379"
+	REDIS_LOCK_TTL      = 10 * time.Minute
+	REDIS_LOCK_WAIT     = time.Second
+	REDIS_LOCK_ATTEMPTS = 600
+	// REDIS_UNLOCK_SCRIPT deletes the lock only if it is still held by this run.
+	REDIS_UNLOCK_SCRIPT = `if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`
+)
+
+// redisStore keeps the state in a redis key, the lock is key+":lock" expiring after REDIS_LOCK_TTL.
+type redisStore struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	key    string
+	token  string
+}
+
+func openRedisStore(u *url.URL) (*redisStore, error) {
+	s, err := dialRedis(u)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.lock(); err != nil {
+		s.conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// dialRedis connects to redis://[:password@]host:port/key of u without locking the key.
+func dialRedis(u *url.URL) (*redisStore, error) {
+	addr := u.Host
+	if addr == "" {
+		addr = REDIS_DEFAULT_ADDR
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" {
+		return nil, fmt.Errorf("Expected redis://host:port/key location")
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to connect to redis %s, %v", addr, err)
+	}
+	s := &redisStore{conn: conn, reader: bufio.NewReader(conn), key: key}
+	if password, ok := u.User.Password(); ok {
+		if _, err := s.command("AUTH", password); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Unable to authenticate to redis %s, %v", addr, err)
+		}
+	}
+	return s, nil
+}
+
+func (s *redisStore) lock() error {
+	token, err := redisToken()
+	if err != nil {
+		return err
+	}
+	s.token = token
+	ttl := strconv.FormatInt(int64(REDIS_LOCK_TTL/time.Millisecond), 10)
+	for attempt := 0; attempt < REDIS_LOCK_ATTEMPTS; attempt++ {
+		reply, err := s.command("SET", s.key+":lock", s.token, "NX", "PX", ttl)
+		if err != nil {
+			return fmt.Errorf("Unable to lock redis state %s, %v", s.key, err)
+		}
+		if reply != nil {
+			return nil
+		}
+		time.Sleep(REDIS_LOCK_WAIT)
+	}
+	return fmt.Errorf("Redis state %s is locked by another run", s.key)
+}
+
+func (s *redisStore) Load() ([]byte, error) {
+	reply, err := s.command("GET", s.key)
+	if err != nil || reply == nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
+func (s *redisStore) Save(data []byte) error {
+	_, err := s.command("SET", s.key, string(data))
+	return err
+}
+
+func (s *redisStore) Close() error {
+	s.command("EVAL", REDIS_UNLOCK_SCRIPT, "1", s.key+":lock", s.token)
+	return s.conn.Close()
+}
+
+// command sends a command in RESP and returns its reply, nil for null replies.
+func (s *redisStore) command(args ...string) ([]byte, error) {
+	buf := strings.Builder{}
+	fmt.Fprintf(&buf, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&buf, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(s.conn, buf.String()); err != nil {
+		return nil, err
+	}
+	return s.reply()
+}
+
+func (s *redisStore) reply() ([]byte, error) {
+	line, err := s.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("Empty redis reply")
+	}
+	switch line[0] {
+	case '+', ':':
+		return []byte(line[1:]), nil
+	case '-':
+		return nil, fmt.Errorf("Redis error, %s", line[1:])
+	case '$':
+		size, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if size < 0 {
+			return nil, nil
+		}
+		data := make([]byte, size+2)
+		if _, err := io.ReadFull(s.reader, data); err != nil {
+			return nil, err
+		}
+		return data[:size], nil
+	}
+	return nil, fmt.Errorf("Unexpected redis reply %s", line)
+}
+
+// redisToken identifies a holder of a lock, only the holder releases it.
+func redisToken() (string, error) {
+	token := make([]byte, 16)
+	if _, err := io.ReadFull(rand.Reader, token); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(token), nil
+}
@@ -0,0 +1,122 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	S3_DEFAULT_REGION = "us-east-1"
+	S3_TIME_FORMAT    = "20060102T150405Z"
+)
+
+// s3Store keeps the state in an s3 object, credentials are read from the standard AWS_* variables.
+// S3 has no locks, concurrent runs sharing the object keep the state of the last one.
+type s3Store struct {
+	bucket, key                  string
+	region, accessKey, secretKey string
+	sessionToken                 string
+}
+
+func newS3Store(bucket, key string) (*s3Store, error) {
+	s := &s3Store{
+		bucket:       bucket,
+		key:          key,
+		region:       os.Getenv("AWS_REGION"),
+		accessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		secretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		sessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if s.region == "" {
+		s.region = S3_DEFAULT_REGION
+	}
+	if bucket == "" || key == "" {
+		return nil, fmt.Errorf("Expected s3://bucket/key state location")
+	}
+	if s.accessKey == "" || s.secretKey == "" {
+		return nil, fmt.Errorf("Please, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY for s3 state store")
+	}
+	return s, nil
+}
+
+func (s *s3Store) Load() ([]byte, error) {
+	resp, err := s.do("GET", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == 404 {
+		return nil, nil
+	}
+	if resp.StatusCode != 200 {
+		return nil, fmt.Errorf("Unable to get s3 state %s/%s, %s", s.bucket, s.key, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (s *s3Store) Save(data []byte) error {
+	resp, err := s.do("PUT", data)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		return fmt.Errorf("Unable to put s3 state %s/%s, %s", s.bucket, s.key, resp.Status)
+	}
+	return nil
+}
+
+func (s *s3Store) Close() error {
+	return nil
+}
+
+// do sends AWS signature v4 signed request for the object.
+func (s *s3Store) do(method string, body []byte) (*http.Response, error) {
+	host := fmt.Sprintf("%s.s3.%s.amazonaws.com", s.bucket, s.region)
+	path := "/" + strings.TrimPrefix(s.key, "/")
+	// state is saved after Sync is canceled as well, requests of the store are never canceled
+	req, err := http.NewRequest(method, "https://"+host+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	payloadHash := dataSha256(body)
+	req.Header.Set("Host", host)
+	req.Header.Set("X-Amz-Date", now.Format(S3_TIME_FORMAT))
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + host + "\n" +
+		"x-amz-content-sha256:" + payloadHash + "\n" +
+		"x-amz-date:" + now.Format(S3_TIME_FORMAT) + "\n"
+	if s.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.sessionToken)
+		signedHeaders += ";x-amz-security-token"
+		canonicalHeaders += "x-amz-security-token:" + s.sessionToken + "\n"
+	}
+
+	canonicalRequest := strings.Join([]string{method, req.URL.EscapedPath(), "", canonicalHeaders, signedHeaders, payloadHash}, "\n")
+	scope := now.Format("20060102") + "/" + s.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + now.Format(S3_TIME_FORMAT) + "\n" + scope + "\n" + dataSha256([]byte(canonicalRequest))
+	signingKey := []byte("AWS4" + s.secretKey)
+	for _, part := range []string{now.Format("20060102"), s.region, "s3", "aws4_request"} {
+		signingKey = hmacSha256(signingKey, part)
+	}
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.accessKey, scope, signedHeaders, signature))
+	return http.DefaultClient.Do(req)
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
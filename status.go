@@ -0,0 +1,132 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+)
+
+type (
+	// LocaleStatus is completeness of a downloaded locale.
+	LocaleStatus struct {
+		Project      string  `json:"project"`
+		Locale       string  `json:"locale"`
+		Total        int     `json:"total"`
+		Translated   int     `json:"translated"`
+		Completeness float64 `json:"completeness"`
+		// UntranslatedHits are hits of untranslated keys of -key-hits.
+		UntranslatedHits int64 `json:"untranslated_hits,omitempty"`
+	}
+
+	// Status is written to -status file after each sync for dashboards to poll.
+	Status struct {
+		UpdatedAt time.Time       `json:"updated_at"`
+		Locales   []*LocaleStatus `json:"locales"`
+	}
+
+	// shieldsBadge is shields.io endpoint badge schema.
+	shieldsBadge struct {
+		SchemaVersion int    `json:"schemaVersion"`
+		Label         string `json:"label"`
+		Message       string `json:"message"`
+		Color         string `json:"color"`
+	}
+)
+
+var (
+	statusPath string
+	badgesDir  string
+)
+
+// addLocaleStatus reports completeness of a non source locale.
+func addLocaleStatus(projectName, localeName string, translations []*Translation) {
+	if localeName == config.Qa.sourceLocale() {
+		return
+	}
+	report.AddLocaleStatus(newLocaleStatus(projectName, localeName, translations, sourceKeys(projectName)))
+}
+
+// sourceKeys returns keys extracted for the project, nil when sources were not extracted as a whole this run.
+func sourceKeys(projectName string) []string {
+	if v == nil || isPartialSync() {
+		return nil
+	}
+	return routeIds(v.Ids())[projectName]
+}
+
+// newLocaleStatus counts keys missing in the locale as untranslated, keys of the file are counted without source keys.
+func newLocaleStatus(projectName, localeName string, translations []*Translation, keys []string) *LocaleStatus {
+	s := &LocaleStatus{Project: projectName, Locale: localeName, Completeness: 1}
+	if keys == nil {
+		for _, t := range translations {
+			keys = append(keys, t.ID)
+		}
+	}
+	translated := make(map[string]bool, len(translations))
+	for _, t := range translations {
+		translated[t.ID] = !t.IsUntranslated()
+	}
+	for _, id := range keys {
+		if isDeprecated(id) {
+			continue
+		}
+		s.Total++
+		if translated[id] {
+			s.Translated++
+		}
+	}
+	if s.Total > 0 {
+		s.Completeness = float64(s.Translated) / float64(s.Total)
+	}
+	return s
+}
+
+func (s *LocaleStatus) badge() *shieldsBadge {
+	color := "red"
+	switch {
+	case s.Completeness >= 1:
+		color = "brightgreen"
+	case s.Completeness >= 0.9:
+		color = "green"
+	case s.Completeness >= 0.7:
+		color = "yellow"
+	}
+	return &shieldsBadge{
+		SchemaVersion: 1,
+		Label:         s.Project + " " + s.Locale,
+		Message:       fmt.Sprintf("%.0f%%", s.Completeness*100),
+		Color:         color,
+	}
+}
+
+func writeStatus(locales []*LocaleStatus) {
+	if statusPath != "" {
+		status := &Status{UpdatedAt: time.Now().UTC(), Locales: locales}
+		if err := writeJsonFile(statusPath, status); err != nil {
+			log.Println("WARNING! Unable to write status", err)
+		}
+	}
+	if badgesDir == "" {
+		return
+	}
+	if err := mkdirOutput(badgesDir); err != nil {
+		log.Println("WARNING! Unable to create badges folder", err)
+		return
+	}
+	for _, s := range locales {
+		path := filepath.Join(badgesDir, s.Project+"_"+s.Locale+".json")
+		if err := writeJsonFile(path, s.badge()); err != nil {
+			log.Println("WARNING! Unable to write badge", err)
+		}
+	}
+}
+
+func writeJsonFile(path string, v interface{}) error {
+	encoded, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutputFile(path, encoded)
+}
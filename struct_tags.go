@@ -0,0 +1,38 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"reflect"
+	"strconv"
+)
+
+// extractStructTags adds values of config.StructTags tags of struct fields: Label string `i18n:"field.label"`.
+func extractStructTags(v *FuncVisitor, fset *token.FileSet, file *ast.File) {
+	if len(config.StructTags) == 0 {
+		return
+	}
+	ast.Inspect(file, func(node ast.Node) bool {
+		field, ok := node.(*ast.Field)
+		if !ok || field.Tag == nil {
+			return true
+		}
+		tag, err := strconv.Unquote(field.Tag.Value)
+		if err != nil {
+			return true
+		}
+		for _, name := range config.StructTags {
+			id, ok := reflect.StructTag(tag).Lookup(name)
+			if !ok || id == "-" {
+				continue
+			}
+			if id == "" {
+				v.AddDiagnostic(fset.Position(field.Pos()), fmt.Sprintf("empty %s struct tag", name))
+				continue
+			}
+			v.AddRef(id, fset.Position(field.Pos()).String())
+		}
+		return true
+	})
+}
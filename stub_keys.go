@@ -0,0 +1,67 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	stubNewKeys bool
+	stubSuffix  string
+)
+
+// stubMissingKeys adds extracted keys missing in downloaded locale files of the project with
+// source text as translation, so services show source text instead of raw ids until translations arrive.
+// Stubbed files don't match stored checksums and are downloaded in full on the next run.
+func stubMissingKeys(projectName string, ids []string) {
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", projectName, err)
+		return
+	}
+	for _, path := range files {
+		localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+		stubbed, err := stubLocaleFile(path, ids)
+		if err != nil {
+			logLocale(projectName, localeName, "WARNING! Unable to stub new keys", projectName, localeName, err)
+			continue
+		}
+		if stubbed > 0 {
+			logLocalef(projectName, localeName, "%d new keys were stubbed in %s %s\n", stubbed, projectName, localeName)
+			report.AddStubbed(projectName, localeName, stubbed)
+		}
+	}
+}
+
+func stubLocaleFile(path string, ids []string) (int, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		return 0, err
+	}
+	existing := map[string]bool{}
+	for _, t := range translations {
+		existing[t.ID] = true
+	}
+	stubbed := 0
+	for _, id := range ids {
+		if !existing[id] {
+			translations = append(translations, &Translation{ID: id, Text: sourceText(id) + stubSuffix})
+			stubbed++
+		}
+	}
+	if stubbed == 0 {
+		return 0, nil
+	}
+	encoded, err := json.MarshalIndent(translations, "", "  ")
+	if err != nil {
+		return 0, err
+	}
+	return stubbed, writeOutputFile(path, encoded)
+}
@@ -0,0 +1,78 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// TABLE_DIRECTIVE placed above a map or slice literal makes all its string values keys.
+const TABLE_DIRECTIVE = "table"
+
+// hasExtractionMarkers cheaply checks go files outside of i18n packages for table directives
+// configured struct tags and message types before parsing them.
+func hasExtractionMarkers(path string, info os.FileInfo) bool {
+	if info.IsDir() || !strings.HasSuffix(path, ".go") {
+		return false
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	return containsExtractionMarkers(data)
+}
+
+func containsExtractionMarkers(data []byte) bool {
+	if bytes.Contains(data, []byte(DIRECTIVE_PREFIX+TABLE_DIRECTIVE)) {
+		return true
+	}
+	for _, name := range config.StructTags {
+		if bytes.Contains(data, []byte(name+`:"`)) {
+			return true
+		}
+	}
+	for _, m := range config.Messages {
+		if bytes.Contains(data, []byte(messageTypeName(m))) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractTables adds string values of literals marked with the table directive, nested literals included.
+func extractTables(v *FuncVisitor, fset *token.FileSet, file *ast.File, directives fileDirectives) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		lit, ok := node.(*ast.CompositeLit)
+		if !ok {
+			return true
+		}
+		if _, ok := directives.find(fset, lit, TABLE_DIRECTIVE); !ok {
+			return true
+		}
+		addTableValues(v, fset, lit)
+		return false
+	})
+}
+
+func addTableValues(v *FuncVisitor, fset *token.FileSet, lit *ast.CompositeLit) {
+	for _, elt := range lit.Elts {
+		if kv, ok := elt.(*ast.KeyValueExpr); ok {
+			elt = kv.Value
+		}
+		switch expr := elt.(type) {
+		case *ast.BasicLit:
+			if expr.Kind != token.STRING {
+				continue
+			}
+			if id, err := strconv.Unquote(expr.Value); err == nil && id != "" {
+				v.AddLocation(id, fset.Position(expr.Pos()).String())
+			}
+		case *ast.CompositeLit:
+			addTableValues(v, fset, expr)
+		}
+	}
+}
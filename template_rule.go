@@ -0,0 +1,32 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const QA_RULE_TEMPLATE = "template"
+
+// undefinedFunction matches parse errors of functions provided by services at render time.
+var undefinedFunction = regexp.MustCompile(`function "([^"]+)" not defined`)
+
+// checkTemplate reports translations which text/template fails to parse, a stray {{ breaks rendering of the whole message.
+func checkTemplate(locale, source, text string, placeholders *regexp.Regexp) string {
+	if !strings.Contains(text, "{{") {
+		return ""
+	}
+	funcs := template.FuncMap{}
+	for {
+		_, err := template.New("translation").Funcs(funcs).Parse(text)
+		if err == nil {
+			return ""
+		}
+		m := undefinedFunction.FindStringSubmatch(err.Error())
+		if m == nil || funcs[m[1]] != nil {
+			return fmt.Sprintf("template doesn't parse, %v", err)
+		}
+		funcs[m[1]] = func() string { return "" }
+	}
+}
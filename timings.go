@@ -0,0 +1,75 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	TIMING_RUN        = "run"
+	TIMING_EXTRACTION = "extraction"
+	TIMING_UPLOAD     = "upload"
+	// TIMING_DOWNLOAD_WAIT is the time until response headers of a download, it is spent by phraseapp mostly.
+	TIMING_DOWNLOAD_WAIT = "download-wait"
+	// TIMING_DOWNLOAD_TRANSFER is the time of reading the downloaded locale, it is spent by the network mostly.
+	TIMING_DOWNLOAD_TRANSFER = "download-transfer"
+	TIMING_VALIDATION        = "validation"
+	// TIMING_SAMPLES is the number of slowest entries of every phase listed in the report.
+	TIMING_SAMPLES = 3
+)
+
+// timingPhases are phases in report order.
+var timingPhases = []string{TIMING_RUN, TIMING_EXTRACTION, TIMING_UPLOAD, TIMING_DOWNLOAD_WAIT, TIMING_DOWNLOAD_TRANSFER, TIMING_VALIDATION}
+
+// Timing is the duration of a phase of the run, Name tells the project and locale it was spent on.
+type Timing struct {
+	Phase    string
+	Name     string
+	Duration time.Duration
+}
+
+var timingsMu sync.Mutex
+
+func (r *RunReport) AddTiming(phase, name string, d time.Duration) {
+	timingsMu.Lock()
+	defer timingsMu.Unlock()
+	r.Timings = append(r.Timings, &Timing{Phase: phase, Name: name, Duration: d})
+}
+
+func (c *i18nGenContext) OnTiming(projectName, localeName, phase string, d time.Duration) {
+	report.AddTiming(phase, projectName+":"+localeName, d)
+}
+
+// timingLines lists total time of every phase and its TIMING_SAMPLES slowest entries, all with -verbose.
+func (r *RunReport) timingLines() []string {
+	byPhase := map[string][]*Timing{}
+	for _, t := range r.Timings {
+		byPhase[t.Phase] = append(byPhase[t.Phase], t)
+	}
+	lines := []string{}
+	for _, phase := range timingPhases {
+		timings := byPhase[phase]
+		if len(timings) == 0 {
+			continue
+		}
+		total := time.Duration(0)
+		for _, t := range timings {
+			total += t.Duration
+		}
+		if len(timings) == 1 && timings[0].Name == "" {
+			lines = append(lines, fmt.Sprintf("%s: %v", phase, total.Round(time.Millisecond)))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s: %v in %d", phase, total.Round(time.Millisecond), len(timings)))
+		sort.SliceStable(timings, func(i, j int) bool { return timings[i].Duration > timings[j].Duration })
+		for i, t := range timings {
+			if !verbose && i == TIMING_SAMPLES {
+				break
+			}
+			lines = append(lines, fmt.Sprintf("  %s %v", t.Name, t.Duration.Round(time.Millisecond)))
+		}
+	}
+	return lines
+}
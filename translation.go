@@ -0,0 +1,96 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+type (
+	// Translation is a single entry of a go-i18n v1 json file. The translation
+	// field is either a plain string or an object of plural forms
+	// (`zero`, `one`, `two`, `few`, `many`, `other`).
+	Translation struct {
+		ID     string
+		Text   string
+		Plural map[string]string
+	}
+
+	translationJson struct {
+		ID          string          `json:"id"`
+		Translation json.RawMessage `json:"translation"`
+	}
+)
+
+func (t *Translation) IsPlural() bool {
+	return t.Plural != nil
+}
+
+// Texts returns all translated strings of the entry, plural forms are sorted by form name.
+func (t *Translation) Texts() []string {
+	if !t.IsPlural() {
+		return []string{t.Text}
+	}
+	forms := make([]string, 0, len(t.Plural))
+	for form := range t.Plural {
+		forms = append(forms, form)
+	}
+	sort.Strings(forms)
+	texts := make([]string, 0, len(forms))
+	for _, form := range forms {
+		texts = append(texts, t.Plural[form])
+	}
+	return texts
+}
+
+// IsUntranslated reports whether every translated string is empty or equal to the id.
+func (t *Translation) IsUntranslated() bool {
+	for _, text := range t.Texts() {
+		if text != "" && text != t.ID {
+			return false
+		}
+	}
+	return true
+}
+
+func (t *Translation) UnmarshalJSON(data []byte) error {
+	raw := translationJson{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	t.ID = raw.ID
+	t.Text = ""
+	t.Plural = nil
+	if len(raw.Translation) == 0 || string(raw.Translation) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw.Translation, &t.Text); err == nil {
+		return nil
+	}
+	plural := map[string]string{}
+	if err := json.Unmarshal(raw.Translation, &plural); err != nil {
+		return fmt.Errorf("Translation of %s is neither a string nor plural forms, %v", t.ID, err)
+	}
+	t.Plural = plural
+	return nil
+}
+
+func (t *Translation) MarshalJSON() ([]byte, error) {
+	var translation interface{} = t.Text
+	if t.IsPlural() {
+		translation = t.Plural
+	}
+	return json.Marshal(map[string]interface{}{
+		"id":          t.ID,
+		"translation": translation,
+	})
+}
+
+// ParseLocaleFile decodes go-i18n v1 json with both simple and plural translations.
+func ParseLocaleFile(data []byte) ([]*Translation, error) {
+	translations := []*Translation{}
+	if err := json.Unmarshal(data, &translations); err != nil {
+		return nil, err
+	}
+	return translations, nil
+}
@@ -0,0 +1,244 @@
+package i18n_gen
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const (
+	SUGGEST_REPORT = "report"
+	SUGGEST_APPLY  = "apply"
+)
+
+var (
+	// suggestTranslations offers translations of keys with similar source text for new keys:
+	// report lists them, apply also adds them as unverified translations.
+	suggestTranslations string
+	suggestSimilarity   float64
+)
+
+// memoryMatch is a key with source text similar to source text of a new key.
+type memoryMatch struct {
+	id         string
+	similarity float64
+}
+
+func validateSuggestions() error {
+	switch suggestTranslations {
+	case "", SUGGEST_REPORT, SUGGEST_APPLY:
+	default:
+		return fmt.Errorf("Unknown -suggest-translations mode %s, expected %s or %s", suggestTranslations, SUGGEST_REPORT, SUGGEST_APPLY)
+	}
+	if suggestSimilarity <= 0 || suggestSimilarity > 1 {
+		return fmt.Errorf("Suggestion similarity should be above 0 and up to 1")
+	}
+	return nil
+}
+
+// suggestNewKeyTranslations looks up translations of new keys of the project in downloaded locales of
+// other extracted keys with the same or similar source text, e.g. button labels repeated across services.
+// Applied suggestions are written to locale files too, so they are used before translators verify them.
+func suggestNewKeyTranslations(worker *PhraseappWorkerContext, projectName string, ids []string) {
+	newIds := []string{}
+	for _, d := range report.NewKeys {
+		if d.Project == projectName {
+			newIds = append(newIds, d.Keys...)
+		}
+	}
+	if len(newIds) == 0 {
+		return
+	}
+	matches := memoryMatches(newIds, ids)
+	if len(matches) == 0 {
+		return
+	}
+
+	var keyIds, localeIds map[string]string
+	if suggestTranslations == SUGGEST_APPLY {
+		var err error
+		if keyIds, localeIds, err = translationTargets(worker, projectName); err != nil {
+			log.Println("WARNING! Unable to apply suggested translations", err)
+			return
+		}
+	}
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", projectName, err)
+		return
+	}
+	sourceFile := runtimeLocale(config.Qa.sourceLocale())
+	for _, path := range files {
+		localeName := strings.TrimSuffix(filepath.Base(path), ".json")
+		if localeName == sourceFile {
+			continue
+		}
+		if err := suggestLocaleFile(worker, projectName, localeName, path, matches, keyIds, localeIds); err != nil {
+			log.Println("WARNING! Unable to suggest translations", projectName, localeName, err)
+		}
+	}
+}
+
+// memoryMatches returns keys of ids similar to every new key, best match first.
+func memoryMatches(newIds, ids []string) map[string][]*memoryMatch {
+	isNew := map[string]bool{}
+	for _, id := range newIds {
+		isNew[id] = true
+	}
+	matches := map[string][]*memoryMatch{}
+	for _, newId := range newIds {
+		source := normalizeSource(sourceText(newId))
+		for _, id := range ids {
+			if isNew[id] {
+				continue
+			}
+			if similarity := textSimilarity(source, normalizeSource(sourceText(id))); similarity >= suggestSimilarity {
+				matches[newId] = append(matches[newId], &memoryMatch{id: id, similarity: similarity})
+			}
+		}
+		sort.SliceStable(matches[newId], func(i, j int) bool {
+			return matches[newId][i].similarity > matches[newId][j].similarity
+		})
+	}
+	return matches
+}
+
+// translationTargets returns phraseapp ids of keys by name and of locales by runtime code.
+func translationTargets(worker *PhraseappWorkerContext, projectName string) (map[string]string, map[string]string, error) {
+	localCtx := &i18nGenContext{}
+	projectId := phraseappProjects[projectName]
+	keyIds, err := worker.KeyIds(localCtx, projectId, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	locales, err := worker.getLocales(localCtx, projectId, projectName)
+	if err != nil {
+		return nil, nil, err
+	}
+	localeIds := map[string]string{}
+	for _, l := range locales {
+		localeIds[runtimeLocale(l.Name)] = l.ID
+	}
+	return keyIds, localeIds, nil
+}
+
+func suggestLocaleFile(worker *PhraseappWorkerContext, projectName, localeName, path string,
+	matches map[string][]*memoryMatch, keyIds, localeIds map[string]string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	translations, err := ParseLocaleFile(data)
+	if err != nil {
+		return err
+	}
+	byId := map[string]*Translation{}
+	for _, t := range translations {
+		byId[t.ID] = t
+	}
+	newIds := make([]string, 0, len(matches))
+	for id := range matches {
+		newIds = append(newIds, id)
+	}
+	sort.Strings(newIds)
+	applied := 0
+	for _, newId := range newIds {
+		if t, ok := byId[newId]; ok && (t.IsPlural() || t.Text != "") {
+			continue
+		}
+		for _, m := range matches[newId] {
+			match, ok := byId[m.id]
+			if !ok || match.IsPlural() || match.Text == "" {
+				continue
+			}
+			report.AddSuggestion(fmt.Sprintf("%s:%s %s <- %s (%.0f%%): %s", projectName, localeName, newId, m.id, m.similarity*100, match.Text))
+			if suggestTranslations == SUGGEST_APPLY {
+				keyId, localeId := keyIds[newId], localeIds[localeName]
+				if keyId == "" || localeId == "" {
+					log.Println("WARNING! Unable to apply suggested translation, key or locale is unknown", projectName, localeName, newId)
+					break
+				}
+				if err := worker.AddTranslation(&i18nGenContext{}, phraseappProjects[projectName], projectName, keyId, localeId, "", match.Text, true); err != nil {
+					log.Println("WARNING!", err)
+					break
+				}
+				audit(AUDIT_TRANSLATION, projectName, localeName, newId)
+				if t, ok := byId[newId]; ok {
+					t.Text = match.Text
+				} else {
+					translations = append(translations, &Translation{ID: newId, Text: match.Text})
+				}
+				applied++
+			}
+			break
+		}
+	}
+	if applied == 0 {
+		return nil
+	}
+	logLocalef(projectName, localeName, "%d suggested translations were applied to %s %s\n", applied, projectName, localeName)
+	encoded, err := json.MarshalIndent(translations, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeOutputFile(path, encoded)
+}
+
+func normalizeSource(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// textSimilarity is 1 for equal texts down to 0 by levenshtein distance relative to the longer text.
+func textSimilarity(a, b string) float64 {
+	if a == b {
+		return 1
+	}
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+	diff := len(ra) - len(rb)
+	if diff < 0 {
+		diff = -diff
+	}
+	// the distance is at least the difference of lengths
+	if 1-float64(diff)/float64(longest) < suggestSimilarity {
+		return 0
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(longest)
+}
+
+func levenshtein(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(minInt(prev[j]+1, cur[j-1]+1), prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
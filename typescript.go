@@ -0,0 +1,110 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+)
+
+// TYPESCRIPT_TYPES_FILE declares keys of the project for frontend imports of locale modules.
+const TYPESCRIPT_TYPES_FILE = "messages.d.ts"
+
+// TypescriptConfig writes downloaded locales of a project as json modules, <locale>.json of ids to text or plural
+// forms, and TYPESCRIPT_TYPES_FILE with the Messages interface of their keys, to Dir.
+type TypescriptConfig struct {
+	Dir string `json:"dir"`
+}
+
+func validateTypescript(typescript map[string]*TypescriptConfig) error {
+	for projectName, t := range typescript {
+		if t == nil || t.Dir == "" {
+			return fmt.Errorf("Typescript output of project %s needs a dir", projectName)
+		}
+	}
+	return nil
+}
+
+// writeTypescriptOutputs writes typescript outputs of synced projects configured for them.
+func writeTypescriptOutputs() {
+	for projectName := range syncProjects() {
+		if t, ok := config.Typescript[projectName]; ok {
+			writeTypescript(projectName, t)
+		}
+	}
+}
+
+func writeTypescript(projectName string, t *TypescriptConfig) {
+	files, err := filepath.Glob(filepath.Join(getLocalizationFolderName(), projectName, "*.json"))
+	if err != nil {
+		log.Println("WARNING! Unable to list locale files", projectName, err)
+		return
+	}
+	if err := mkdirOutput(t.Dir); err != nil {
+		fail(fmt.Errorf("Unable to create typescript folder %s, %v", t.Dir, err))
+	}
+	plural := map[string]bool{}
+	for _, path := range files {
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			log.Println("WARNING! Unable to read locale file", path, err)
+			continue
+		}
+		translations, err := ParseLocaleFile(data)
+		if err != nil {
+			log.Println("WARNING! Unable to parse locale file", path, err)
+			continue
+		}
+		messages := map[string]interface{}{}
+		for _, tr := range translations {
+			plural[tr.ID] = plural[tr.ID] || tr.IsPlural()
+			// frontends fall back on missing keys, not on empty strings
+			if tr.IsUntranslated() {
+				continue
+			}
+			if tr.IsPlural() {
+				messages[tr.ID] = tr.Plural
+			} else {
+				messages[tr.ID] = tr.Text
+			}
+		}
+		// maps are encoded with sorted keys, modules are stable between runs
+		module, err := json.MarshalIndent(messages, "", "  ")
+		if err != nil {
+			fail(fmt.Errorf("Unable to encode locale module %s %s, %v", projectName, path, err))
+		}
+		if err := writeOutputFile(filepath.Join(t.Dir, filepath.Base(path)), append(module, '\n')); err != nil {
+			fail(fmt.Errorf("Unable to write locale module %s %s, %v", projectName, path, err))
+		}
+	}
+	if err := writeOutputFile(filepath.Join(t.Dir, TYPESCRIPT_TYPES_FILE), messagesDts(projectName, plural)); err != nil {
+		fail(fmt.Errorf("Unable to write typescript types %s, %v", projectName, err))
+	}
+}
+
+// messagesDts declares keys of all locales, a key plural in any locale is plural.
+func messagesDts(projectName string, plural map[string]bool) []byte {
+	ids := make([]string, 0, len(plural))
+	for id := range plural {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	buf := &bytes.Buffer{}
+	fmt.Fprintf(buf, "// Code generated by i18n_gen from locales of %s. DO NOT EDIT.\n\n", projectName)
+	buf.WriteString("export type PluralForms = Partial<Record<\"zero\" | \"one\" | \"two\" | \"few\" | \"many\" | \"other\", string>>;\n\n")
+	buf.WriteString("export interface Messages {\n")
+	for _, id := range ids {
+		// json strings are valid typescript string literals, encoding/json escapes U+2028 and U+2029 too
+		key, _ := json.Marshal(id)
+		value := "string"
+		if plural[id] {
+			value = "PluralForms"
+		}
+		fmt.Fprintf(buf, "  %s?: %s;\n", key, value)
+	}
+	buf.WriteString("}\n\nexport type MessageKey = keyof Messages;\n")
+	return buf.Bytes()
+}
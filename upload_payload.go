@@ -0,0 +1,102 @@
+package i18n_gen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// UPLOAD_PAYLOAD_FILE is the name of an upload payload until it is renamed to the locale it is uploaded to.
+const UPLOAD_PAYLOAD_FILE = "payload.json"
+
+var (
+	// keysMu guards buffers of writeKeys which are reused by every key, upload part and project.
+	keysMu     sync.Mutex
+	keyBuf     bytes.Buffer
+	keyEncoder = newKeyEncoder(&keyBuf)
+	keysWriter = bufio.NewWriterSize(nil, 64<<10)
+)
+
+func newKeyEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("  ", "  ")
+	return enc
+}
+
+// writeKeys streams keys of sorted ids as a json array, byte to byte equal to json.MarshalIndent of them,
+// holding a single key in memory.
+func writeKeys(w io.Writer, ids []string, source func(id string) *Translation) error {
+	keysMu.Lock()
+	defer keysMu.Unlock()
+	keysWriter.Reset(w)
+	if len(ids) == 0 {
+		keysWriter.WriteString("[]")
+		return keysWriter.Flush()
+	}
+	keysWriter.WriteString("[\n")
+	for i, id := range ids {
+		keyBuf.Reset()
+		if err := keyEncoder.Encode(source(id)); err != nil {
+			return err
+		}
+		keysWriter.WriteString("  ")
+		keysWriter.Write(bytes.TrimSuffix(keyBuf.Bytes(), []byte("\n")))
+		if i < len(ids)-1 {
+			keysWriter.WriteString(",")
+		}
+		keysWriter.WriteString("\n")
+	}
+	keysWriter.WriteString("]")
+	return keysWriter.Flush()
+}
+
+// newUploadPart writes keys of sorted ids to a temporary folder of the part, removeUploadPart removes it.
+func newUploadPart(name string, ids []string, source func(id string) *Translation) (*UploadPart, error) {
+	dir, err := ioutil.TempDir("", "i18n_gen_upload")
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, UPLOAD_PAYLOAD_FILE)
+	f, err := os.Create(path)
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	err = writeKeys(f, ids, source)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return &UploadPart{Name: name, Path: path, Keys: len(ids)}, nil
+}
+
+// localePayload renames the payload to lang.json, phraseapp-go uploads a file by path and file name is kept.
+func (p *UploadPart) localePayload(lang string) (string, error) {
+	path := filepath.Join(filepath.Dir(p.Path), lang+".json")
+	if path != p.Path {
+		if err := os.Rename(p.Path, path); err != nil {
+			return "", err
+		}
+		p.Path = path
+	}
+	return path, nil
+}
+
+func removeUploadParts(locales map[string][]*UploadPart) {
+	for _, parts := range locales {
+		for _, part := range parts {
+			if err := os.RemoveAll(filepath.Dir(part.Path)); err != nil {
+				log.Println("WARNING! Unable to remove upload payload", part.Path, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package i18n_gen
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteKeysMatchesMarshalIndent(t *testing.T) {
+	translations := map[string]*Translation{
+		"Log in":       {ID: "Log in", Text: "Log in"},
+		"<b>Bold</b>":  {ID: "<b>Bold</b>", Text: "<b>Bold</b> & \"quoted\""},
+		"rides":        {ID: "rides", Plural: map[string]string{"one": "%d ride", "other": "%d rides"}},
+		"Unicode ‘’ ✓": {ID: "Unicode ‘’ ✓", Text: "Unicode ‘’ ✓\n\ttabbed"},
+	}
+	source := func(id string) *Translation { return translations[id] }
+	tests := []struct {
+		name string
+		ids  []string
+	}{
+		{"no keys", []string{}},
+		{"one key", []string{"Log in"}},
+		{"escaped html and quotes", []string{"<b>Bold</b>", "Log in"}},
+		{"plural and unicode", []string{"<b>Bold</b>", "Log in", "Unicode ‘’ ✓", "rides"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := make([]*Translation, 0, len(tt.ids))
+			for _, id := range tt.ids {
+				keys = append(keys, source(id))
+			}
+			want, err := json.MarshalIndent(keys, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := bytes.NewBuffer(nil)
+			if err := writeKeys(got, tt.ids, source); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got.Bytes(), want) {
+				t.Errorf("writeKeys wrote\n%s\nMarshalIndent wrote\n%s", got.Bytes(), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package i18n_gen
+
+import (
+	"log"
+	"time"
+)
+
+const (
+	USAGE_MONTH_FORMAT = "2006-01"
+	USAGE_WARN_RATIO   = 0.8
+)
+
+type (
+	// ApiUsage accumulates PhraseApp API calls and transferred bytes.
+	ApiUsage struct {
+		Calls         int64 `json:"calls"`
+		BytesSent     int64 `json:"bytes_sent"`
+		BytesReceived int64 `json:"bytes_received"`
+	}
+
+	// UsageHistory keeps totals per month, PhraseApp plan limits are monthly.
+	UsageHistory map[string]*ApiUsage
+)
+
+var apiCallLimit int64
+
+func (u *ApiUsage) Add(sent, received int64) {
+	u.Calls++
+	u.BytesSent += sent
+	u.BytesReceived += received
+}
+
+func (h *UsageHistory) Add(t time.Time, sent, received int64) *ApiUsage {
+	if *h == nil {
+		*h = UsageHistory{}
+	}
+	month := t.Format(USAGE_MONTH_FORMAT)
+	u, ok := (*h)[month]
+	if !ok {
+		u = &ApiUsage{}
+		(*h)[month] = u
+	}
+	u.Add(sent, received)
+	return u
+}
+
+func (c *i18nGenContext) OnApiCall(projectName string, sent, received int64) {
+	report.AddApiCall(projectName, sent, received)
+	monthly := runInfo.Usage.Add(time.Now(), sent, received)
+	if apiCallLimit <= 0 {
+		return
+	}
+	// runs may start past the thresholds, calls of the run don't hit them exactly then
+	if monthly.Calls >= apiCallLimit {
+		if !report.limitWarned {
+			report.limitWarned = true
+			log.Printf("WARNING! Monthly PhraseApp API call limit %d is reached.\n", apiCallLimit)
+		}
+		return
+	}
+	if monthly.Calls >= int64(float64(apiCallLimit)*USAGE_WARN_RATIO) && !report.usageWarned {
+		report.usageWarned = true
+		log.Printf("WARNING! %d of %d monthly PhraseApp API calls are used.\n", monthly.Calls, apiCallLimit)
+	}
+}
@@ -0,0 +1,102 @@
+package i18n_gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"log"
+	"sort"
+	"strings"
+)
+
+const (
+	// VARIANTS_DIRECTIVE above NewI18nString call declares variants of the key: //i18n:variants formal informal
+	VARIANTS_DIRECTIVE = "variants"
+	// VARIANT_SEPARATOR joins id of the key and name of the variant into id of the variant key: Log in#formal.
+	VARIANT_SEPARATOR = "#"
+	// VARIANT_TAG_PREFIX tags variant keys by variant name, e.g. variant:formal.
+	VARIANT_TAG_PREFIX = "variant:"
+)
+
+func variantId(id, variant string) string {
+	return id + VARIANT_SEPARATOR + variant
+}
+
+// extractVariants adds keys of variants declared by the variants directive, their source text is the one of the key.
+func extractVariants(v *FuncVisitor, fset *token.FileSet, file *ast.File, directives fileDirectives) {
+	ast.Inspect(file, func(node ast.Node) bool {
+		id, ok := i18nStringId(file, node)
+		if !ok {
+			return true
+		}
+		args, ok := directives.find(fset, node, VARIANTS_DIRECTIVE)
+		if !ok {
+			return true
+		}
+		variants := strings.FieldsFunc(args, func(r rune) bool { return r == ' ' || r == ',' })
+		if len(variants) == 0 {
+			v.AddDiagnostic(fset.Position(node.Pos()), fmt.Sprintf("variants of %s are not named", id))
+			return true
+		}
+		for _, variant := range variants {
+			if strings.Contains(variant, VARIANT_SEPARATOR) {
+				v.AddDiagnostic(fset.Position(node.Pos()), fmt.Sprintf("variant %s of %s should not contain %s", variant, id, VARIANT_SEPARATOR))
+				continue
+			}
+			v.AddVariant(id, variant, fset.Position(node.Pos()).String())
+		}
+		return true
+	})
+}
+
+// AddVariant adds the variant of id declared at pos.
+func (v *FuncVisitor) AddVariant(id, variant, pos string) {
+	v.Lock()
+	defer v.Unlock()
+	v.addDeclaredVariant(id, variant, pos)
+}
+
+func (v *FuncVisitor) addDeclaredVariant(id, variant string, positions ...string) {
+	if v.declaredVariants[id] == nil {
+		v.declaredVariants[id] = map[string][]string{}
+	}
+	v.declaredVariants[id][variant] = append(v.declaredVariants[id][variant], positions...)
+}
+
+// addVariantKeys adds variant keys of declared variants, ids of keys are the final ones after key mapping
+// and hashing, so VariantID of the mapped id matches the uploaded key. Variant keys are neither mapped nor hashed.
+func addVariantKeys(v *FuncVisitor) {
+	for id, variants := range v.declaredVariants {
+		for variant, positions := range variants {
+			key := variantId(id, variant)
+			for _, pos := range positions {
+				v.AddLocation(key, pos)
+			}
+			v.messages[key] = &Translation{ID: key, Text: sourceText(id)}
+			v.variants[key] = variant
+		}
+	}
+}
+
+// tagVariantKeys tags variant keys of the project by their variant name, so translators see them grouped.
+func tagVariantKeys(worker *PhraseappWorkerContext, projectName string) {
+	byVariant := map[string][]string{}
+	for id, variant := range v.variants {
+		if keyProject(id) == projectName {
+			byVariant[variant] = append(byVariant[variant], id)
+		}
+	}
+	variants := make([]string, 0, len(byVariant))
+	for variant := range byVariant {
+		variants = append(variants, variant)
+	}
+	sort.Strings(variants)
+	for _, variant := range variants {
+		ids := byVariant[variant]
+		sort.Strings(ids)
+		err := worker.TagKeys(&i18nGenContext{}, phraseappProjects[projectName], projectName, ids, VARIANT_TAG_PREFIX+variant)
+		if err != nil {
+			log.Println("WARNING! Unable to tag variant keys", projectName, variant, err)
+		}
+	}
+}
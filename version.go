@@ -0,0 +1,35 @@
+package i18n_gen
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Build info is set at link time, see Makefile.
+var (
+	Version   = "dev"
+	GitCommit = "unknown"
+	BuildDate = "unknown"
+)
+
+var (
+	supportedProviders = []string{"phraseapp"}
+	supportedFormats   = []string{"go_i18n"}
+)
+
+var versionCommand = &command{
+	name:        "version",
+	description: "print build information",
+	run:         runVersion,
+}
+
+func runVersion(fs *flag.FlagSet) {
+	fmt.Printf("i18n_gen %s\n", Version)
+	fmt.Printf("commit:    %s\n", GitCommit)
+	fmt.Printf("built:     %s\n", BuildDate)
+	fmt.Printf("go:        %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	fmt.Printf("providers: %s\n", strings.Join(supportedProviders, ", "))
+	fmt.Printf("formats:   %s\n", strings.Join(supportedFormats, ", "))
+}